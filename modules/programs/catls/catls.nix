@@ -11,30 +11,83 @@ Useful for code analysis and file content inspection.
 - ✅ Darwin
 
 ## Features
-- XML-formatted output
-- Pattern matching for file inclusion/exclusion
-- Content filtering with glob patterns
-- Binary file detection
-- File type detection
-- Recursive directory traversal
+- XML, JSON, JSON Lines, Markdown, CSV, HTML, and syntax-highlighted terminal output
+- Tar archive output with a JSON manifest
+- Custom output formats from Go templates or external formatter commands
+- Gitignore-style glob matching for file inclusion/exclusion, with ! negation
+- Content filtering with glob patterns or RE2 regular expressions, optionally inverted
+- Configurable binary file detection with optional hexdump previews
+- .gitignore support inside git repositories
+- Limiting output to files changed since a git ref or modified within a time range
+- Incremental snapshots that reuse or omit files unchanged since the last run
+- Snapshotting remote git repositories at a branch, tag, or commit
+- Scanning inside zip and tar archives
+- File type detection from names, extensions, shebangs, and modelines
+- Following local Go, Python, and TypeScript imports from entry files
+- Recursive directory traversal with an optional depth limit and concurrent file reading
+- Structure-only tree view
+- Path-only listings, newline or NUL separated, for xargs and fzf pipelines
+- Diffing two directory trees or JSON snapshots as a patch, XML, JSON, or Markdown
+- Per-directory sections with totals in Markdown and HTML output
+- Markdown frontmatter extraction for indexing docs and blog posts
 - Line number display
+- Adjustable or disabled truncation of long files
+- Approximate token counting
+- File sizes and SHA-256 digests for integrity checks
+- Statistics per language with the largest files
+- Code, comment, and blank line counts per language
+- Chunked output for context-limited models
+- Fitting output into a byte or token budget, prioritized by size, recency, or globs
+- gzip or zstd compressed output
+- Automatic paging through $PAGER in a terminal
 - Debug mode
 
 ## Implementation
 - **Language**: Go 1.24+
 - **Framework**: Cobra CLI
 - **Source**: ./main.go, ./cmd/root.go
-- **Dependencies**: github.com/spf13/cobra
+- **Dependencies**: github.com/spf13/cobra, github.com/alecthomas/chroma/v2, gopkg.in/yaml.v3, github.com/klauspost/compress
 - **Build**: pkgs.buildGoModule
 
 ## Usage
 ```bash
 catls                         # List current directory
 catls /path/to/dir           # List specific directory
+catls src/ pkg/ README.md    # Combine several directories and files
+catls https://github.com/org/repo@v1.0 -r # Shallow-clone and scan a tag
 catls -r                     # Recursive listing
+catls --max-depth 2          # Top two directory levels
+catls -r --tree              # Directory tree without contents
+catls -r -0 --globs '*.go' | xargs -0 wc -l # Feed matching paths to xargs
+catls diff old/ new/ > changes.patch # Unified diff of two trees
+catls diff -f json a.json b.json # Compare two JSON snapshots
+catls -r --stats-only        # Per-language totals and largest files
+catls -r --langs             # tokei-style code/comment/blank counts
+catls -r --changed-since main # Only files changed on this branch
+catls -r --changed-only       # Only files changed since the last run
+catls -r --newer-than 7d      # Only files modified in the last week
+catls -r --follow-imports . cmd/main.go # main.go and what it imports
+catls --archives dist.zip     # List the files inside an archive
 catls --globs "*.py"         # Include only Python files
+catls -r --globs "*.go,!*_test.go" # Go sources without their tests
 catls --pattern "*import*"   # Show only lines with imports
+catls --regex "^func " -i    # Case-insensitive RE2 content filter
+catls --regex "DEBUG" -v     # Drop debug lines from the dump
 catls -n                     # Show line numbers
+catls -r --hash -f jsonl      # Include size and sha256 per file
+catls -f term main.go        # Read a file with ANSI highlighting
+catls -r -f html --group-by-dir > snapshot.html # Sections per directory
+catls -r --frontmatter-only -f jsonl posts/ # Index post metadata
+catls -r --head 20           # Preview the first 20 lines of each file
+catls -r --no-truncate        # Never cut long files short
+catls --hex 64 assets/       # Hexdump the first 64 bytes of binaries
+catls --treat-as-text gen,log # Never treat .gen or .log files as binary
+catls -r --max-output '50K tokens' --priority-globs 'cmd/**' # Fit a context window
+catls -r -o out/snapshot.xml # Write output to a file
+catls -r -o snapshot.xml.zst # Write a zstd-compressed snapshot
+catls -r -f tar > files.tar  # Archive the selected files losslessly
+catls -f template --template-file out.tmpl # Render files through a Go template
+catls -f 'exec:./fmt.py'     # Stream files as JSON Lines to a formatter
 ```
 
 ## Common Use Cases
@@ -47,6 +100,10 @@ catls -n                     # Show line numbers
 Enabled via:
 - `myconfig.programs.catls.enable = true`
 - Or automatically with engineer feature
+
+Flag defaults can be set in `~/.config/catls/config.yaml` using long flag
+names as keys (e.g. `format: markdown`, `ignore-dir: [node_modules]`).
+Command-line flags always take precedence.
 */
 {
   delib,
@@ -60,7 +117,7 @@ Enabled via:
 
     src = ./.;
 
-    vendorHash = "sha256-m5mBubfbXXqXKsygF5j7cHEY+bXhAMcXUts5KBKoLzM=";
+    vendorHash = "sha256-t0R8XlNLf1Tx3dIqL5o8N1WhElQ9N/DraMjIue6jMBo=";
 
     meta = with pkgs.lib; {
       description = "Enhanced file listing utility with XML, Markdown, and JSON output";
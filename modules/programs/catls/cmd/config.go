@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath returns the location of the user config file,
+// $XDG_CONFIG_HOME/catls/config.yaml or ~/.config/catls/config.yaml.
+func defaultConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "catls", "config.yaml")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "catls", "config.yaml")
+}
+
+// applyUserConfig loads flag defaults from a YAML file whose keys are long
+// flag names, for example:
+//
+//	format: markdown
+//	line-numbers: true
+//	ignore-dir: [node_modules, dist]
+//
+// Flags given on the command line always win. A missing file is not an
+// error unless its path was given explicitly.
+func applyUserConfig(flags *pflag.FlagSet, path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	// Apply keys in a stable order so errors are deterministic.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := flags.Lookup(key)
+		if flag == nil || key == "config" {
+			return fmt.Errorf("config file %s: unknown option %q", path, key)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := setFlagFromConfig(flag, values[key]); err != nil {
+			return fmt.Errorf("config file %s: option %q: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+// setFlagFromConfig assigns a decoded YAML value to a flag. Lists replace
+// the flag's default rather than appending to it.
+func setFlagFromConfig(flag *pflag.Flag, value any) error {
+	items, isList := value.([]any)
+	if !isList {
+		return flag.Value.Set(fmt.Sprint(value))
+	}
+
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		strs := make([]string, len(items))
+		for i, item := range items {
+			strs[i] = fmt.Sprint(item)
+		}
+		return sliceValue.Replace(strs)
+	}
+	return fmt.Errorf("expected a single value, got a list")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <dirA|snapshot.json> <dirB|snapshot.json>",
+	Short: "Compare two directory trees or JSON snapshots",
+	Long: `diff compares two directory trees, or two snapshots written with
+--format json, and reports the files added, removed, and modified between
+them with unified diffs of changed text files.
+
+Directories are scanned recursively with the usual filters, such as --globs
+and --ignore-dir. Output is a plain unified diff that patch and git apply
+accept, or xml, json, or markdown with --format, written to standard output
+or to the --output file. Flags that shape the listing of file contents, such
+as --stats or --names-only, are rejected.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().IntP(
+		"unified",
+		"U",
+		3,
+		"Number of unchanged lines shown around each change",
+	)
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffUnsupportedFlags shape the file listing of the root command and have
+// no meaning for a diff.
+var diffUnsupportedFlags = []string{
+	"stats", "stats-only", "langs", "count-tokens", "hash", "tree",
+	"names-only", "null", "chunk-tokens", "chunk-bytes", "max-output",
+	"prioritize", "priority-globs", "frontmatter-only", "group-by-dir",
+	"template-file", "hex", "line-numbers", "head", "tail", "compress",
+	"cache", "changed-only",
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if err := loadUserConfig(cmd); err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	for _, name := range diffUnsupportedFlags {
+		if flags.Changed(name) {
+			return fmt.Errorf("--%s is not supported by diff", name)
+		}
+	}
+
+	cfg, err := buildConfig(cmd, nil)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed("recursive") {
+		cfg.Recursive = true
+	}
+
+	opts := catls.DiffOptions{Format: catls.DiffFormatPatch}
+	if flags.Changed("format") {
+		opts.Format = string(cfg.OutputFormat)
+	}
+	opts.Context, _ = flags.GetInt("unified")
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := catls.Diff(context.Background(), cfg, args[0], args[1], opts, out); err != nil {
+		return err
+	}
+	return out.Flush()
+}
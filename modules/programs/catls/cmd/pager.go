@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// defaultPager is used when neither $CATLS_PAGER nor $PAGER is set.
+const defaultPager = "less"
+
+// pager is a running pager process that output is piped through.
+type pager struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// pagerCommand returns the pager command line from $CATLS_PAGER or $PAGER,
+// or an empty string when paging is disabled with "cat" or an empty value.
+func pagerCommand() string {
+	command, ok := os.LookupEnv("CATLS_PAGER")
+	if !ok {
+		command, ok = os.LookupEnv("PAGER")
+	}
+	if !ok {
+		command = defaultPager
+	}
+	if command == "cat" {
+		return ""
+	}
+	return command
+}
+
+// startPager starts the user's pager like git does: only when stdout is a
+// terminal, with LESS=FRX by default so less colors output, keeps the screen
+// contents on exit, and exits immediately when everything fits on one
+// screen. It returns nil if output should not be paged.
+func startPager() *pager {
+	command := pagerCommand()
+	if command == "" || !isTerminal(os.Stdout) {
+		return nil
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+	return &pager{cmd: cmd, stdin: stdin}
+}
+
+// Write sends output to the pager.
+func (p *pager) Write(data []byte) (int, error) {
+	return p.stdin.Write(data)
+}
+
+// Close signals the end of output and waits for the user to quit the pager.
+func (p *pager) Close() error {
+	if err := p.stdin.Close(); err != nil {
+		return err
+	}
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("pager failed: %w", err)
+	}
+	return nil
+}
+
+// isPagerClosed reports whether err comes from writing to a pager the user
+// already quit, which is not a failure.
+func isPagerClosed(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed)
+}
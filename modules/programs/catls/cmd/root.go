@@ -6,16 +6,37 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/catls"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "catls [directory] [files...]",
+	Use:   "catls [directory|git-url[@ref]] [paths...]",
 	Short: "List files and their contents",
 	Long: `catls recursively lists files and displays their contents in XML format.
-It supports filtering by glob patterns, ignoring directories, and various output options.`,
+It supports filtering by glob patterns, ignoring directories, and various output options.
+
+Several directories and files can be given at once: directories are scanned,
+files inside a scanned directory select just those files, other files are
+included on their own, and arguments that do not exist are used as glob
+patterns. A first argument that does not exist is a glob over the working
+directory if it contains *, ?, or [, and an error otherwise.
+
+The directory may also be a git URL such as https://github.com/org/repo@v1.0,
+which is shallow-cloned at the given branch, tag, or commit, scanned, and
+removed afterwards.
+
+Globs follow gitignore rules: * and ? stay within one path segment, ** spans
+directories, a pattern without a slash matches a name at any depth, and one
+with a slash is anchored to the scanned directory. A trailing / matches only
+directories, and a leading ! negates a pattern; the last matching pattern
+wins, so --globs '*.go,!*_test.go' selects Go sources without their tests.
+
+Use catls diff to compare two trees or JSON snapshots.`,
+	// Arguments that are not subcommands are directories and paths to list
+	Args: cobra.ArbitraryArgs,
 	RunE: runCatls,
 }
 
@@ -31,8 +52,13 @@ func init() {
 }
 
 func setupFlags() {
-	flags := rootCmd.Flags()
+	flags := rootCmd.PersistentFlags()
 
+	flags.String(
+		"config",
+		"",
+		"Config file with flag defaults (default: ~/.config/catls/config.yaml)",
+	)
 	flags.BoolP(
 		"all",
 		"a",
@@ -45,6 +71,11 @@ func setupFlags() {
 		false,
 		"Recursively list files in subdirectories",
 	)
+	flags.Int(
+		"max-depth",
+		0,
+		"Descend at most N directory levels, 1 being the directory itself (implies --recursive)",
+	)
 	flags.StringSlice(
 		"ignore-dir",
 		defaultIgnoreDirs(),
@@ -53,24 +84,56 @@ func setupFlags() {
 	flags.StringSlice(
 		"globs",
 		nil,
-		"Only include files matching glob pattern (can be used multiple times)",
+		"Only include files matching glob pattern; !pattern excludes (can be used multiple times)",
 	)
 	flags.StringSlice(
 		"ignore-globs",
 		nil,
-		"Ignore files matching glob pattern (can be used multiple times)",
+		"Ignore files matching glob pattern; !pattern re-includes (can be used multiple times)",
 	)
 	flags.String(
 		"pattern",
 		"",
 		"Only show lines matching glob PATTERN",
 	)
+	flags.String(
+		"regex",
+		"",
+		"Only show lines matching RE2 regular expression REGEX",
+	)
+	flags.BoolP(
+		"ignore-case",
+		"i",
+		false,
+		"Match --pattern or --regex case-insensitively",
+	)
+	flags.BoolP(
+		"invert-match",
+		"v",
+		false,
+		"Show lines that do not match --pattern or --regex",
+	)
+	flags.Bool(
+		"multiline",
+		false,
+		"Match the content pattern against whole files so matches can span lines",
+	)
 	flags.BoolP(
 		"line-numbers",
 		"n",
 		false,
 		"Show line numbers",
 	)
+	flags.Int(
+		"head",
+		0,
+		"Show only the first N lines of each file",
+	)
+	flags.Int(
+		"tail",
+		0,
+		"Show only the last N lines of each file",
+	)
 	flags.Bool(
 		"debug",
 		false,
@@ -81,17 +144,210 @@ func setupFlags() {
 		false,
 		"Skip binary files in output",
 	)
+	flags.String(
+		"binary-detection",
+		"file",
+		"Binary detection strategy: file (file command, then bytes) or bytes",
+	)
+	flags.Int(
+		"binary-sample-size",
+		1024,
+		"Number of leading bytes inspected when detecting binary files by content",
+	)
+	flags.Float64(
+		"binary-null-threshold",
+		0,
+		"Fraction of NUL bytes in the sample above which a file is binary",
+	)
+	flags.StringSlice(
+		"treat-as-text",
+		nil,
+		"Always treat files with these extensions as text (e.g. txt,csv)",
+	)
+	flags.StringSlice(
+		"binary-extensions",
+		nil,
+		"Always treat files with these extensions as binary",
+	)
+	flags.Int(
+		"max-lines",
+		1000,
+		"Truncate unfiltered files longer than N lines",
+	)
+	flags.Int(
+		"truncate-to",
+		100,
+		"Number of lines kept from a file truncated by --max-lines",
+	)
+	flags.Bool(
+		"no-truncate",
+		false,
+		"Never truncate long files",
+	)
+	flags.Int(
+		"hex",
+		0,
+		"Show the first N bytes of binary files as a hexdump with their MIME type",
+	)
 	flags.StringP(
 		"format",
 		"f",
 		"xml",
-		"Output format: xml, json, markdown",
+		"Output format: xml, json, jsonl, markdown, csv, html, tar, term, template, exec:<cmd>",
+	)
+	flags.String(
+		"max-output",
+		"",
+		"Fit output into a budget such as 200K (bytes) or 50K tokens, truncating or listing files that do not fit",
+	)
+	flags.String(
+		"prioritize",
+		catls.PrioritizeSize,
+		"Order files are fitted into --max-output: size (smallest first) or recent (newest first)",
+	)
+	flags.StringSlice(
+		"priority-globs",
+		nil,
+		"Fit files matching these globs into --max-output first, in the order given",
+	)
+	flags.Bool(
+		"frontmatter-only",
+		false,
+		"Show only the YAML frontmatter of markdown files, as fields in json and xml output",
+	)
+	flags.Bool(
+		"group-by-dir",
+		false,
+		"Group markdown and html output into per-directory sections with totals",
+	)
+	flags.String(
+		"template-file",
+		"",
+		"Go text/template rendered per file with --format template",
+	)
+	flags.Bool(
+		"no-pager",
+		false,
+		"Do not pipe terminal output through $CATLS_PAGER, $PAGER, or less",
+	)
+	flags.String(
+		"color",
+		"auto",
+		"Syntax highlight term output: auto (when stdout is a terminal), always, never",
+	)
+	flags.StringP(
+		"output",
+		"o",
+		"",
+		"Write output to FILE instead of stdout, creating parent directories",
+	)
+	flags.String(
+		"compress",
+		"",
+		"Compress output with gzip or zstd (default from a .gz or .zst --output name)",
 	)
 	flags.String(
 		"relative-to",
 		"",
 		"Display paths relative to this directory (default: scan directory)",
 	)
+	flags.Bool(
+		"respect-gitignore",
+		true,
+		"Skip files ignored by .gitignore and .git/info/exclude inside git repositories",
+	)
+	flags.Bool(
+		"follow-imports",
+		false,
+		"Add the local files imported by Go, Python, and TypeScript/JavaScript sources, transitively",
+	)
+	flags.Bool(
+		"archives",
+		false,
+		"Scan inside zip, tar, and tar.gz archives, showing entries as archive.zip!/path",
+	)
+	flags.String(
+		"newer-than",
+		"",
+		"Only include files modified after a date (2024-01-01) or within an age (7d, 2w, 36h)",
+	)
+	flags.String(
+		"older-than",
+		"",
+		"Only include files modified before a date (2024-01-01) or longer ago than an age (7d, 2w, 36h)",
+	)
+	flags.String(
+		"changed-since",
+		"",
+		"Only include files changed relative to a git ref (HEAD for uncommitted changes)",
+	)
+	flags.IntP(
+		"jobs",
+		"j",
+		0,
+		"Number of files to read concurrently (0 means one per CPU)",
+	)
+	flags.Bool(
+		"stats",
+		false,
+		"Append statistics per language, the largest files, and totals to the output",
+	)
+	flags.Bool(
+		"stats-only",
+		false,
+		"Only output statistics, as JSON with --format json or jsonl",
+	)
+	flags.Bool(
+		"langs",
+		false,
+		"Only output code, comment, and blank line counts per language",
+	)
+	flags.Bool(
+		"count-tokens",
+		false,
+		"Report an approximate token count per file and in total",
+	)
+	flags.Bool(
+		"hash",
+		false,
+		"Report the size and SHA-256 digest of every file",
+	)
+	flags.Bool(
+		"cache",
+		false,
+		"Reuse unchanged files from the previous run over the same paths",
+	)
+	flags.Bool(
+		"changed-only",
+		false,
+		"Only output files added or modified since the previous run (implies --cache)",
+	)
+	flags.Int(
+		"chunk-tokens",
+		0,
+		"Split output into sequential chunks of at most N estimated tokens",
+	)
+	flags.Int(
+		"chunk-bytes",
+		0,
+		"Split output into sequential chunks of at most N bytes",
+	)
+	flags.Bool(
+		"tree",
+		false,
+		"Print the directory tree with line counts and sizes instead of file contents",
+	)
+	flags.Bool(
+		"names-only",
+		false,
+		"Print only the paths of matching files, one per line",
+	)
+	flags.BoolP(
+		"null",
+		"0",
+		false,
+		"Print only the paths of matching files, each terminated by NUL for xargs -0",
+	)
 }
 
 func defaultIgnoreDirs() []string {
@@ -112,14 +368,49 @@ func defaultIgnoreDirs() []string {
 }
 
 func runCatls(cmd *cobra.Command, args []string) error {
+	if err := loadUserConfig(cmd); err != nil {
+		return err
+	}
+
 	cfg, err := buildConfig(cmd, args)
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
-	app := catls.New(cfg)
-	return app.Run(ctx)
+	noPager, _ := cmd.Flags().GetBool("no-pager")
+	if noPager || cfg.Output != "" {
+		return catls.New(cfg).Run(ctx)
+	}
+
+	p := startPager()
+	if p == nil {
+		return catls.New(cfg).Run(ctx)
+	}
+
+	err = catls.NewWithWriter(cfg, p).Run(ctx)
+	if closeErr := p.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && isPagerClosed(err) {
+		return nil
+	}
+	return err
+}
+
+// loadUserConfig applies defaults from the user config file to any flags not
+// given on the command line.
+func loadUserConfig(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		return nil
+	}
+
+	return applyUserConfig(cmd.Flags(), path, explicit)
 }
 
 func buildConfig(cmd *cobra.Command, args []string) (*catls.Config, error) {
@@ -136,11 +427,63 @@ func buildConfig(cmd *cobra.Command, args []string) (*catls.Config, error) {
 
 	cfg.ShowAll, _ = flags.GetBool("all")
 	cfg.Recursive, _ = flags.GetBool("recursive")
+	cfg.MaxDepth, _ = flags.GetInt("max-depth")
 	cfg.Debug, _ = flags.GetBool("debug")
 	cfg.ShowLineNumbers, _ = flags.GetBool("line-numbers")
+	cfg.Head, _ = flags.GetInt("head")
+	cfg.Tail, _ = flags.GetInt("tail")
+	cfg.MaxLines, _ = flags.GetInt("max-lines")
+	cfg.TruncateTo, _ = flags.GetInt("truncate-to")
+	// A zero limit means the default in Config, so it is refused here
+	// rather than quietly ignored
+	if cfg.MaxLines <= 0 || cfg.TruncateTo <= 0 {
+		return nil, fmt.Errorf("--max-lines and --truncate-to must be positive (use --no-truncate to keep every line)")
+	}
+	cfg.NoTruncate, _ = flags.GetBool("no-truncate")
+	cfg.GroupByDir, _ = flags.GetBool("group-by-dir")
+	cfg.FrontmatterOnly, _ = flags.GetBool("frontmatter-only")
+	cfg.Prioritize, _ = flags.GetString("prioritize")
+	cfg.PriorityGlobs, _ = flags.GetStringSlice("priority-globs")
+	if budget, _ := flags.GetString("max-output"); budget != "" {
+		var err error
+		if cfg.MaxOutput, cfg.MaxOutputTokens, err = catls.ParseOutputBudget(budget); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Hex, _ = flags.GetInt("hex")
 	cfg.OmitBins, _ = flags.GetBool("omit-bins")
+	cfg.Binary.Strategy, _ = flags.GetString("binary-detection")
+	cfg.Binary.SampleSize, _ = flags.GetInt("binary-sample-size")
+	cfg.Binary.NullThreshold, _ = flags.GetFloat64("binary-null-threshold")
+	cfg.Binary.TextExtensions, _ = flags.GetStringSlice("treat-as-text")
+	cfg.Binary.BinaryExtensions, _ = flags.GetStringSlice("binary-extensions")
 	cfg.ContentPattern, _ = flags.GetString("pattern")
+	cfg.ContentRegex, _ = flags.GetString("regex")
+	cfg.IgnoreCase, _ = flags.GetBool("ignore-case")
+	cfg.Multiline, _ = flags.GetBool("multiline")
+	cfg.InvertMatch, _ = flags.GetBool("invert-match")
 	cfg.RelativeTo, _ = flags.GetString("relative-to")
+	cfg.TemplateFile, _ = flags.GetString("template-file")
+	cfg.Output, _ = flags.GetString("output")
+	color, _ := flags.GetString("color")
+	cfg.Compress, _ = flags.GetString("compress")
+	cfg.Tree, _ = flags.GetBool("tree")
+	cfg.NamesOnly, _ = flags.GetBool("names-only")
+	cfg.NullSeparated, _ = flags.GetBool("null")
+	cfg.GitIgnore, _ = flags.GetBool("respect-gitignore")
+	cfg.Archives, _ = flags.GetBool("archives")
+	cfg.FollowImports, _ = flags.GetBool("follow-imports")
+	cfg.ChangedSince, _ = flags.GetString("changed-since")
+	cfg.Jobs, _ = flags.GetInt("jobs")
+	cfg.CountTokens, _ = flags.GetBool("count-tokens")
+	cfg.Hash, _ = flags.GetBool("hash")
+	cfg.Cache, _ = flags.GetBool("cache")
+	cfg.ChangedOnly, _ = flags.GetBool("changed-only")
+	cfg.Stats, _ = flags.GetBool("stats")
+	cfg.StatsOnly, _ = flags.GetBool("stats-only")
+	cfg.Langs, _ = flags.GetBool("langs")
+	cfg.ChunkTokens, _ = flags.GetInt("chunk-tokens")
+	cfg.ChunkBytes, _ = flags.GetInt("chunk-bytes")
 	cfg.IgnoreDir, _ = flags.GetStringSlice("ignore-dir")
 	cfg.Globs, _ = flags.GetStringSlice("globs")
 	cfg.IgnoreGlobs, _ = flags.GetStringSlice("ignore-globs")
@@ -153,5 +496,29 @@ func buildConfig(cmd *cobra.Command, args []string) (*catls.Config, error) {
 			formatStr, strings.Join(catls.GetSupportedFormats(), ", "))
 	}
 
+	now := time.Now()
+	for name, bound := range map[string]*time.Time{"newer-than": &cfg.NewerThan, "older-than": &cfg.OlderThan} {
+		value, _ := flags.GetString(name)
+		if value == "" {
+			continue
+		}
+		t, err := catls.ParseTimeBound(value, now)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %w", name, err)
+		}
+		*bound = t
+	}
+
+	switch color {
+	case "always":
+		cfg.Color = true
+	case "never":
+		cfg.Color = false
+	case "auto":
+		cfg.Color = cfg.Output == "" && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	default:
+		return nil, fmt.Errorf("invalid --color value: %s (supported: auto, always, never)", color)
+	}
+
 	return cfg, nil
 }
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -18,10 +21,13 @@ func createTestFlags() *pflag.FlagSet {
 	flags.StringSlice("ignore-globs", nil, "Ignore files matching glob pattern")
 	flags.String("pattern", "", "Only show lines matching glob PATTERN")
 	flags.BoolP("line-numbers", "n", false, "Show line numbers")
+	flags.Int("max-lines", 1000, "Truncate unfiltered files longer than N lines")
+	flags.Int("truncate-to", 100, "Number of lines kept from a file truncated by --max-lines")
 	flags.Bool("debug", false, "Enable debug output")
 	flags.Bool("omit-bins", false, "Skip binary files in output")
 	flags.StringP("format", "f", "xml", "Output format: xml, json, markdown")
 	flags.String("relative-to", "", "Display paths relative to this directory")
+	flags.String("color", "auto", "Syntax highlight term output: auto, always, never")
 
 	return flags
 }
@@ -142,3 +148,152 @@ func TestBuildConfig_RelativeToWithOtherFlags(t *testing.T) {
 		t.Errorf("OutputFormat = %v, want json", cfg.OutputFormat)
 	}
 }
+
+func TestBuildConfig_RejectsZeroTruncation(t *testing.T) {
+	for _, name := range []string{"max-lines", "truncate-to"} {
+		cmd := &cobra.Command{
+			Use: "test",
+		}
+		cmd.Flags().AddFlagSet(createTestFlags())
+		if err := cmd.Flags().Set(name, "0"); err != nil {
+			t.Fatalf("failed to set flag %s: %v", name, err)
+		}
+
+		if _, err := buildConfig(cmd, nil); err == nil {
+			t.Errorf("buildConfig() with --%s 0 expected an error", name)
+		}
+	}
+}
+
+func TestApplyUserConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := `format: markdown
+line-numbers: true
+ignore-dir: [node_modules, dist]
+globs:
+  - "*.go"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cmd := &cobra.Command{
+		Use: "test",
+	}
+	cmd.Flags().AddFlagSet(createTestFlags())
+
+	// Command-line flags take precedence over the config file
+	if err := cmd.Flags().Set("format", "json"); err != nil {
+		t.Fatalf("failed to set flag format: %v", err)
+	}
+
+	if err := applyUserConfig(cmd.Flags(), configPath, true); err != nil {
+		t.Fatalf("applyUserConfig() unexpected error: %v", err)
+	}
+
+	cfg, err := buildConfig(cmd, nil)
+	if err != nil {
+		t.Fatalf("buildConfig() unexpected error: %v", err)
+	}
+
+	if string(cfg.OutputFormat) != "json" {
+		t.Errorf("OutputFormat = %v, want json", cfg.OutputFormat)
+	}
+	if !cfg.ShowLineNumbers {
+		t.Errorf("ShowLineNumbers = false, want true")
+	}
+	if want := []string{"node_modules", "dist"}; !reflect.DeepEqual(cfg.IgnoreDir, want) {
+		t.Errorf("IgnoreDir = %v, want %v", cfg.IgnoreDir, want)
+	}
+	if want := []string{"*.go"}; !reflect.DeepEqual(cfg.Globs, want) {
+		t.Errorf("Globs = %v, want %v", cfg.Globs, want)
+	}
+}
+
+func TestApplyUserConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing default file is ignored", func(t *testing.T) {
+		flags := createTestFlags()
+		if err := applyUserConfig(flags, filepath.Join(dir, "missing.yaml"), false); err != nil {
+			t.Errorf("applyUserConfig() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("missing explicit file is an error", func(t *testing.T) {
+		flags := createTestFlags()
+		if err := applyUserConfig(flags, filepath.Join(dir, "missing.yaml"), true); err == nil {
+			t.Errorf("applyUserConfig() expected error for missing explicit file")
+		}
+	})
+
+	t.Run("unknown option is an error", func(t *testing.T) {
+		configPath := filepath.Join(dir, "unknown.yaml")
+		if err := os.WriteFile(configPath, []byte("colour: red\n"), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		flags := createTestFlags()
+		if err := applyUserConfig(flags, configPath, false); err == nil {
+			t.Errorf("applyUserConfig() expected error for unknown option")
+		}
+	})
+}
+
+func TestBuildConfig_Color(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{value: "always", want: true},
+		{value: "never", want: false},
+		{value: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().AddFlagSet(createTestFlags())
+			if err := cmd.Flags().Set("color", tt.value); err != nil {
+				t.Fatalf("failed to set color: %v", err)
+			}
+
+			cfg, err := buildConfig(cmd, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("buildConfig() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildConfig() unexpected error: %v", err)
+			}
+			if cfg.Color != tt.want {
+				t.Errorf("Color = %v, want %v", cfg.Color, tt.want)
+			}
+		})
+	}
+}
+
+func TestPagerCommand(t *testing.T) {
+	t.Setenv("CATLS_PAGER", "most")
+	t.Setenv("PAGER", "more")
+	if got := pagerCommand(); got != "most" {
+		t.Errorf("pagerCommand() = %q, want $CATLS_PAGER", got)
+	}
+
+	os.Unsetenv("CATLS_PAGER")
+	if got := pagerCommand(); got != "more" {
+		t.Errorf("pagerCommand() = %q, want $PAGER", got)
+	}
+
+	t.Setenv("PAGER", "cat")
+	if got := pagerCommand(); got != "" {
+		t.Errorf("pagerCommand() = %q, want paging disabled", got)
+	}
+
+	os.Unsetenv("PAGER")
+	if got := pagerCommand(); got != defaultPager {
+		t.Errorf("pagerCommand() = %q, want %q", got, defaultPager)
+	}
+}
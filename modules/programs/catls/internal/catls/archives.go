@@ -0,0 +1,218 @@
+package catls
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// archiveSeparator joins an archive's path and the path of an entry inside
+// it, as in archive.zip!/inner/path.
+const archiveSeparator = "!/"
+
+// archiveKind returns the kind of archive at path judged by its name: "zip",
+// "tar", "tar.gz", or an empty string for other files.
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// expandArchives replaces every zip or tar archive among files with the
+// files inside it. Each archive is extracted to a temporary directory that
+// is removed when the run finishes and scanned recursively with the same
+// options as a directory; entries keep the archive's path as a prefix.
+// Archives nested inside archives are expanded too, and archives that cannot
+// be read are kept as ordinary files with a warning.
+func (a *App) expandArchives(ctx context.Context, files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+	var result []scanner.FileInfo
+	for _, file := range files {
+		kind := archiveKind(file.Path)
+		if kind == "" {
+			result = append(result, file)
+			continue
+		}
+
+		entries, err := a.scanArchive(ctx, file, kind)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "Warning: failed to read archive %s, listing it as a file: %v\n", file.RelPath, err)
+			result = append(result, file)
+			continue
+		}
+		if entries, err = a.expandArchives(ctx, entries); err != nil {
+			return nil, err
+		}
+		result = append(result, entries...)
+	}
+	return result, nil
+}
+
+// scanArchive extracts one archive and scans its contents.
+func (a *App) scanArchive(ctx context.Context, archive scanner.FileInfo, kind string) ([]scanner.FileInfo, error) {
+	if a.extractDir == "" {
+		dir, err := os.MkdirTemp("", "catls-archives-")
+		if err != nil {
+			return nil, err
+		}
+		a.extractDir = dir
+	}
+	dest, err := os.MkdirTemp(a.extractDir, "archive-")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := extractArchive(archive.Path, kind, dest); err != nil {
+		return nil, err
+	}
+
+	cfg := a.scannerConfig(dest)
+	cfg.Recursive = true
+	cfg.MaxDepth = 0
+	cfg.RelativeTo = dest
+	cfg.GitIgnore = false
+
+	entries, err := a.scanner.Scan(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].RelPath = archive.RelPath + archiveSeparator + filepath.ToSlash(entries[i].RelPath)
+		entries[i].Explicit = false
+	}
+	return entries, nil
+}
+
+// removeArchives deletes the files extracted from archives during the run.
+func (a *App) removeArchives() {
+	if a.extractDir != "" {
+		_ = os.RemoveAll(a.extractDir)
+		a.extractDir = ""
+	}
+}
+
+// extractArchive unpacks the regular files and directories of the archive
+// at src into dest. Links and other special entries are skipped.
+func extractArchive(src, kind, dest string) error {
+	if kind == "zip" {
+		return extractZip(src, dest)
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if kind == "tar.gz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return extractTar(r, dest)
+}
+
+// extractZip unpacks a zip archive into dest.
+func extractZip(src, dest string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		mode := entry.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(archiveEntryPath(dest, entry.Name), 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		r, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveEntry(archiveEntryPath(dest, entry.Name), r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTar unpacks a tar stream into dest.
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(archiveEntryPath(dest, header.Name), 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveEntry(archiveEntryPath(dest, header.Name), tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// archiveEntryPath maps an entry name to a path under dest. Names are
+// cleaned as if rooted, so entries cannot escape dest with ".." or absolute
+// paths.
+func archiveEntryPath(dest, name string) string {
+	return filepath.Join(dest, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// writeArchiveEntry writes the content of one archive entry to target.
+func writeArchiveEntry(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
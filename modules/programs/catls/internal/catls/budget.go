@@ -0,0 +1,261 @@
+package catls
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// Orders in which --max-output spends its budget on files.
+const (
+	PrioritizeSize   = "size"   // Smallest files first, to fit as many as possible
+	PrioritizeRecent = "recent" // Most recently modified files first
+)
+
+const (
+	// budgetMinLines is the fewest lines worth showing of a file cut down to
+	// fit the budget; below that it is only listed.
+	budgetMinLines = 10
+
+	// budgetNoticeSize approximates the truncation notice added to a file
+	// cut down to fit the budget.
+	budgetNoticeSize = 32
+)
+
+// budgetPattern matches an output budget such as 4096, 200K, 1.5M, 64KB, or
+// 50K tokens.
+var budgetPattern = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*([km]?)\s*(b|bytes|t|tok|tokens)?\s*$`)
+
+// ParseOutputBudget parses a --max-output value: a number with an optional K
+// (thousand) or M (million) suffix, in bytes unless followed by "tokens" (or
+// "t"). It returns the budget and whether it is counted in tokens.
+func ParseOutputBudget(value string) (int, bool, error) {
+	match := budgetPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false, fmt.Errorf("invalid output budget %q (expected e.g. 200K or 50K tokens)", value)
+	}
+
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid output budget %q: %w", value, err)
+	}
+	switch strings.ToLower(match[2]) {
+	case "k":
+		n *= 1e3
+	case "m":
+		n *= 1e6
+	}
+
+	tokens := match[3] != "" && !strings.EqualFold(match[3], "b") && !strings.EqualFold(match[3], "bytes")
+	return int(n), tokens, nil
+}
+
+// budgetReport records how files were cut down to fit --max-output.
+type budgetReport struct {
+	budget    int
+	tokens    bool
+	full      int
+	truncated []string
+	listed    []string
+	omitted   []string
+}
+
+// write describes the files that did not fit the budget in full.
+func (r budgetReport) write(w io.Writer) {
+	if len(r.truncated)+len(r.listed)+len(r.omitted) == 0 {
+		return
+	}
+
+	unit := "bytes"
+	if r.tokens {
+		unit = "tokens"
+	}
+	fmt.Fprintf(w, "Note: output limited to %d %s: %d files in full, %d truncated, %d listed only, %d omitted\n",
+		r.budget, unit, r.full, len(r.truncated), len(r.listed), len(r.omitted))
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"truncated", r.truncated},
+		{"listed only", r.listed},
+		{"omitted", r.omitted},
+	} {
+		for _, path := range group.paths {
+			fmt.Fprintf(w, "  %s: %s\n", group.label, path)
+		}
+	}
+}
+
+// fitBudget applies the --max-output budget, if any, to files and reports
+// on standard error what had to be cut.
+func (a *App) fitBudget(files []ProcessedFile) []ProcessedFile {
+	if a.cfg.MaxOutput == 0 {
+		return files
+	}
+	files, report := a.applyBudget(files)
+	report.write(os.Stderr)
+	return files
+}
+
+// applyBudget fits files into the --max-output budget. Files are considered
+// in priority order: those matching earlier --priority-globs first, then by
+// --prioritize. Each file is kept in full if it fits, otherwise cut down to
+// the lines that fit, otherwise listed without content, and otherwise
+// omitted. The files keep their original order in the result.
+func (a *App) applyBudget(files []ProcessedFile) ([]ProcessedFile, budgetReport) {
+	report := budgetReport{budget: a.cfg.MaxOutput, tokens: a.cfg.MaxOutputTokens}
+	weigh := func(file ProcessedFile) int {
+		return outputWeight(file, a.cfg.MaxOutputTokens)
+	}
+
+	order := a.budgetOrder(files)
+	keep := make([]bool, len(files))
+	remaining := a.cfg.MaxOutput
+	for _, i := range order {
+		file := files[i]
+		if weight := weigh(file); weight <= remaining {
+			keep[i] = true
+			remaining -= weight
+			report.full++
+			continue
+		}
+
+		if cut, ok := cutToFit(file, remaining, weigh); ok {
+			files[i] = a.recount(cut)
+			keep[i] = true
+			remaining -= weigh(cut) + budgetNoticeSize
+			report.truncated = append(report.truncated, file.Info.RelPath)
+			continue
+		}
+
+		if listed := listOnly(file); weigh(listed) <= remaining {
+			files[i] = a.recount(listed)
+			keep[i] = true
+			remaining -= weigh(listed)
+			report.listed = append(report.listed, file.Info.RelPath)
+			continue
+		}
+
+		report.omitted = append(report.omitted, file.Info.RelPath)
+	}
+
+	var result []ProcessedFile
+	for i, file := range files {
+		if keep[i] {
+			result = append(result, file)
+		}
+	}
+	sort.Strings(report.truncated)
+	sort.Strings(report.listed)
+	sort.Strings(report.omitted)
+	return result, report
+}
+
+// budgetOrder returns the indices of files in the order the budget is spent
+// on them.
+func (a *App) budgetOrder(files []ProcessedFile) []int {
+	rank := func(file ProcessedFile) int {
+		for i, pattern := range a.cfg.PriorityGlobs {
+			if scanner.MatchesGlobPattern(file.Info.RelPath, pattern) {
+				return i
+			}
+		}
+		return len(a.cfg.PriorityGlobs)
+	}
+
+	ranks := make([]int, len(files))
+	weights := make([]int, len(files))
+	modTimes := make([]int64, len(files))
+	for i, file := range files {
+		ranks[i] = rank(file)
+		weights[i] = outputWeight(file, a.cfg.MaxOutputTokens)
+		if a.cfg.Prioritize == PrioritizeRecent {
+			if info, err := os.Stat(file.Info.Path); err == nil {
+				modTimes[i] = info.ModTime().UnixNano()
+			}
+		}
+	}
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		i, j := order[x], order[y]
+		if ranks[i] != ranks[j] {
+			return ranks[i] < ranks[j]
+		}
+		if a.cfg.Prioritize == PrioritizeRecent {
+			return modTimes[i] > modTimes[j]
+		}
+		return weights[i] < weights[j]
+	})
+	return order
+}
+
+// cutToFit shortens a text file's displayed lines to the most that fit in
+// budget, keeping the start of the file (or the end, for --tail). It fails
+// if fewer than budgetMinLines would remain.
+func cutToFit(file ProcessedFile, budget int, weigh func(ProcessedFile) int) (ProcessedFile, bool) {
+	if file.Error != nil || file.Info.IsBinary || len(file.Lines) <= budgetMinLines {
+		return file, false
+	}
+
+	empty := file
+	empty.Lines = nil
+	available := budget - weigh(empty) - budgetNoticeSize
+	if available <= 0 {
+		return file, false
+	}
+
+	// Find the longest run of lines that fits
+	keep := sort.Search(len(file.Lines)+1, func(n int) bool {
+		cut := file
+		cut.Lines = budgetLines(file, n)
+		return weigh(cut)-weigh(empty) > available
+	}) - 1
+	if keep < budgetMinLines {
+		return file, false
+	}
+
+	cut := file
+	cut.Lines = budgetLines(file, keep)
+	cut.IsTruncated = true
+	cut.OmittedLines = file.OmittedLines + len(file.Lines) - keep
+	return cut, true
+}
+
+// budgetLines returns n of a file's displayed lines: the first n, or the
+// last n when the file already shows its end.
+func budgetLines(file ProcessedFile, n int) []FilteredLine {
+	if file.TruncatedAtStart {
+		return file.Lines[len(file.Lines)-n:]
+	}
+	return file.Lines[:n]
+}
+
+// listOnly reduces a file to its path and metadata, without content.
+func listOnly(file ProcessedFile) ProcessedFile {
+	listed := file
+	listed.Lines = nil
+	listed.BinaryPreview = nil
+	if omitted := file.OmittedLines + len(file.Lines); omitted > 0 && !file.Info.IsBinary {
+		listed.IsTruncated = true
+		listed.OmittedLines = omitted
+	}
+	return listed
+}
+
+// recount updates the token estimate of a file whose lines were cut.
+func (a *App) recount(file ProcessedFile) ProcessedFile {
+	if a.cfg.CountTokens {
+		file.Tokens = countFileTokens(file)
+	}
+	return file
+}
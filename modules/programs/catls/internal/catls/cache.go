@@ -0,0 +1,163 @@
+package catls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// snapshotCache remembers the processed form of every file from the previous
+// run over the same targets with the same processing options, keyed by path
+// and validated by modification time and size, so unchanged files are not
+// read again.
+type snapshotCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry // From the previous run
+	next    map[string]cacheEntry // Collected during this run
+}
+
+// cacheEntry is one cached file.
+type cacheEntry struct {
+	ModTime int64         `json:"mtime"`
+	Size    int64         `json:"size"`
+	File    ProcessedFile `json:"file"`
+}
+
+// cacheKeyOptions are the settings that change how a file is processed; a
+// change to any of them starts a fresh cache.
+type cacheKeyOptions struct {
+	Targets     []string
+	Content     string
+	InvertMatch bool
+	Multiline   bool
+	Head        int
+	Tail        int
+	MaxLines    int
+	TruncateTo  int
+	NoTruncate  bool
+	Frontmatter bool
+	Hex         int
+	CountTokens bool
+	Hash        bool
+	Binary      scanner.BinaryOptions
+}
+
+// cachePath returns the cache file for this run's targets and options, in
+// the user cache directory.
+func (a *App) cachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	targets := []string{absPath(a.cfg.Directory)}
+	for _, file := range a.cfg.Files {
+		targets = append(targets, absPath(file))
+	}
+	key, err := json.Marshal(cacheKeyOptions{
+		Targets:     targets,
+		Content:     a.cfg.ContentExpression(),
+		InvertMatch: a.cfg.InvertMatch,
+		Multiline:   a.cfg.Multiline,
+		Head:        a.cfg.Head,
+		Tail:        a.cfg.Tail,
+		MaxLines:    a.cfg.MaxLines,
+		TruncateTo:  a.cfg.TruncateTo,
+		NoTruncate:  a.cfg.NoTruncate,
+		Frontmatter: a.cfg.FrontmatterOnly,
+		Hex:         a.cfg.Hex,
+		CountTokens: a.cfg.CountTokens,
+		Hash:        a.cfg.Hash,
+		Binary:      a.cfg.Binary,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(key)
+	return filepath.Join(dir, "catls", hex.EncodeToString(sum[:16])+".json"), nil
+}
+
+// loadSnapshotCache reads the cache at path. A missing or unreadable cache
+// is treated as empty, so every file counts as changed.
+func loadSnapshotCache(path string) *snapshotCache {
+	cache := &snapshotCache{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+		next:    make(map[string]cacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		cache.entries = make(map[string]cacheEntry)
+	}
+	return cache
+}
+
+// lookup returns the cached processed form of file if info shows it is
+// unchanged since the previous run, carrying the entry over to the next cache.
+func (c *snapshotCache) lookup(file scanner.FileInfo, info os.FileInfo) (ProcessedFile, bool) {
+	key := absPath(file.Path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() {
+		return ProcessedFile{}, false
+	}
+
+	c.next[key] = entry
+	entry.File.Info = file
+	return entry.File, true
+}
+
+// store records the processed form of a file for the next run, validated by
+// info taken before the file was read. Files that could not be read are not
+// cached.
+func (c *snapshotCache) store(processed ProcessedFile, info os.FileInfo) {
+	if processed.Error != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next[absPath(processed.Info.Path)] = cacheEntry{
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		File:    processed,
+	}
+}
+
+// save replaces the cache file with the files seen during this run.
+func (c *snapshotCache) save() error {
+	data, err := json.Marshal(c.next)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a
+	// truncated cache behind
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return errors.Join(fmt.Errorf("failed to write cache: %w", err), os.Remove(tmp))
+	}
+	return nil
+}
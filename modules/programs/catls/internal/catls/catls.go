@@ -2,11 +2,16 @@
 package catls
 
 import (
+	"bufio"
+	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
 )
@@ -17,28 +22,92 @@ type Config struct {
 	Files           []string
 	ShowAll         bool
 	Recursive       bool
+	MaxDepth        int
+	NewerThan       time.Time
+	OlderThan       time.Time
 	Debug           bool
 	IgnoreDir       []string
 	Globs           []string
 	IgnoreGlobs     []string
 	ContentPattern  string
+	ContentRegex    string
+	IgnoreCase      bool
+	Multiline       bool
+	InvertMatch     bool
 	ShowLineNumbers bool
 	OmitBins        bool
+	Binary          scanner.BinaryOptions
 	OutputFormat    OutputFormat
+	TemplateFile    string
+	Color           bool
 	RelativeTo      string
+	Tree            bool
+	CountTokens     bool
+	Hash            bool
+	Cache           bool
+	ChangedOnly     bool
+	ChunkTokens     int
+	ChunkBytes      int
+	GitIgnore       bool
+	Archives        bool
+	FollowImports   bool
+	ChangedSince    string
+	Jobs            int
+	Compress        string
+	Hex             int
+	Stats           bool
+	StatsOnly       bool
+	Langs           bool
+	Output          string
+	Head            int
+	Tail            int
+	MaxLines        int
+	TruncateTo      int
+	NoTruncate      bool
+	GroupByDir      bool
+	FrontmatterOnly bool
+	MaxOutput       int      // Output budget for --max-output; 0 for none
+	MaxOutputTokens bool     // Whether MaxOutput counts tokens rather than bytes
+	Prioritize      string   // Order files are fitted into the budget
+	PriorityGlobs   []string // Files fitted into the budget first
+	NamesOnly       bool
+	NullSeparated   bool // Print names NUL-terminated; implies NamesOnly
 }
 
 // defaultIgnoreGlobs returns standard ignore patterns.
 func (c *Config) defaultIgnoreGlobs() []string {
 	return []string{
-		".git/*", ".svn/*", ".hg/*",
-		"__pycache__/*", ".pytest_cache/*", ".mypy_cache/*",
-		".tox/*", ".venv/*", ".coverage",
-		".DS_Store", ".idea/*", ".vscode/*",
+		".git/", ".svn/", ".hg/",
+		"__pycache__/", ".pytest_cache/", ".mypy_cache/",
+		".tox/", ".venv/", ".coverage",
+		".DS_Store", ".idea/", ".vscode/",
 		"*_templ.go", "LICENSE", "LICENSE.md", "LICENSE.txt",
 	}
 }
 
+// ContentExpression returns the regular expression used to filter file
+// content, built from either the glob --pattern or the RE2 --regex, or an
+// empty string when content is not filtered.
+func (c *Config) ContentExpression() string {
+	var expr string
+	switch {
+	case c.ContentRegex != "":
+		expr = c.ContentRegex
+	case c.ContentPattern != "":
+		expr = scanner.WildcardToRegex(c.ContentPattern)
+	default:
+		return ""
+	}
+
+	if c.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	if c.Multiline {
+		expr = "(?m)" + expr
+	}
+	return expr
+}
+
 // AllIgnoreGlobs combines default and user-specified ignore patterns.
 func (c *Config) AllIgnoreGlobs() []string {
 	return append(c.defaultIgnoreGlobs(), c.IgnoreGlobs...)
@@ -51,61 +120,185 @@ type App struct {
 	filter    *FileFilter
 	processor *FileProcessor
 	output    OutputFormatter
+	out       io.Writer
+	stats     *statsCollector
+	langs     *langsCollector
+	cache     *snapshotCache
+
+	extractDir string // Temporary directory holding extracted archives
+	cloned     bool   // Whether the directory is a temporary clone of a remote
 }
 
-// New creates a new catls application instance.
+// New creates a new catls application instance writing to standard output,
+// or to the file named by Config.Output.
 func New(cfg *Config) *App {
-	output, err := NewOutputFormatter(cfg.OutputFormat)
+	return NewWithWriter(cfg, os.Stdout)
+}
+
+// NewWithWriter creates a new catls application instance writing to out.
+func NewWithWriter(cfg *Config, out io.Writer) *App {
+	app := &App{
+		cfg:       cfg,
+		scanner:   scanner.NewWithBinaryOptions(cfg.Binary),
+		filter:    NewFileFilter(cfg),
+		processor: NewFileProcessor(cfg),
+	}
+	app.setOutput(out)
+	return app
+}
+
+// setOutput points the application and its formatter at out.
+func (a *App) setOutput(out io.Writer) {
+	output, err := NewOutputFormatter(a.cfg, out)
 	if err != nil {
 		// This should not happen if config validation is working correctly
 		panic(fmt.Sprintf("failed to create output formatter: %v", err))
 	}
 
-	return &App{
-		cfg:       cfg,
-		scanner:   scanner.New(),
-		filter:    NewFileFilter(cfg),
-		processor: NewFileProcessor(),
-		output:    output,
-	}
+	a.out = out
+	a.output = output
 }
 
-// Run executes the catls operation.
+// Run executes the catls operation. A remote git repository given as the
+// directory is cloned to a temporary directory for the duration of the run.
 func (a *App) Run(ctx context.Context) error {
+	if src, ok := ParseRemoteSource(a.cfg.Directory); ok {
+		if a.cfg.Debug {
+			fmt.Fprintf(os.Stderr, "Debug: Cloning %s at %q\n", src.URL, src.Ref)
+		}
+		dir, cleanup, err := cloneRemote(ctx, src)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		a.cfg.Directory = dir
+		a.cloned = true
+	}
+
+	// A missing first argument that looks like a glob selects from the
+	// working directory, as later arguments that do not exist do
+	if _, err := os.Stat(a.cfg.Directory); os.IsNotExist(err) && strings.ContainsAny(a.cfg.Directory, "*?[") {
+		a.cfg.Globs = append(a.cfg.Globs, a.cfg.Directory)
+		a.cfg.Directory = "."
+	}
+
 	if err := a.validateConfig(); err != nil {
 		return err
 	}
+	defer a.removeArchives()
 
-	if a.cfg.Debug {
-		fmt.Fprintf(os.Stderr, "Debug: Ignoring directories: %v\n", a.cfg.IgnoreDir)
+	switch {
+	case a.cfg.Output == "":
+		return a.writeCompressed(ctx, a.out)
+	case a.isChunked():
+		// Chunked output opens and compresses one file per chunk itself
+		return a.writeBuffered(ctx, a.out)
 	}
 
-	// Add files to globs if specified
-	a.addFilesToGlobs()
+	file, err := createOutputFile(a.cfg.Output)
+	if err != nil {
+		return err
+	}
 
-	// Scan for files
-	scanCfg := scanner.Config{
-		Directory:   a.cfg.Directory,
-		ShowAll:     a.cfg.ShowAll,
-		Recursive:   a.cfg.Recursive,
-		IgnoreDir:   a.cfg.IgnoreDir,
-		IgnoreGlobs: a.cfg.AllIgnoreGlobs(),
-		Debug:       a.cfg.Debug,
-		RelativeTo:  a.cfg.RelativeTo,
+	err = a.writeCompressed(ctx, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
 	}
+	return err
+}
 
-	files, err := a.scanner.Scan(ctx, scanCfg)
+// writeCompressed runs the scan and writes all output to out, compressed if
+// --compress is set.
+func (a *App) writeCompressed(ctx context.Context, out io.Writer) error {
+	compressed, err := a.compressWriter(out)
+	if err != nil {
+		return err
+	}
+
+	err = a.writeBuffered(ctx, compressed)
+	if closeErr := compressed.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write output: %w", closeErr)
+	}
+	return err
+}
+
+// writeBuffered runs the scan and writes all output to out through a buffer.
+func (a *App) writeBuffered(ctx context.Context, out io.Writer) error {
+	buffered := bufio.NewWriter(out)
+	a.setOutput(buffered)
+
+	err := a.run(ctx)
+	if flushErr := buffered.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to write output: %w", flushErr)
+	}
+	return err
+}
+
+// run scans, processes, and writes files to the configured output.
+func (a *App) run(ctx context.Context) error {
+	if a.cfg.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: Ignoring directories: %v\n", a.cfg.IgnoreDir)
+	}
+
+	// Scan every directory and explicit file argument
+	files, err := a.scanTargets(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to scan files: %w", err)
 	}
 
 	if len(files) == 0 {
-		fmt.Printf("No files found in directory: %s\n", a.cfg.Directory)
+		fmt.Fprintf(a.out, "No files found in directory: %s\n", a.cfg.Directory)
 		return nil
 	}
 
-	// Process and output files
-	return a.processAndOutput(ctx, files)
+	if a.cfg.Stats || a.cfg.StatsOnly {
+		a.stats = newStatsCollector()
+		if output, ok := a.output.(*JSONOutput); ok {
+			output.stats = a.stats
+		}
+	}
+	if a.cfg.Langs {
+		a.langs = newLangsCollector()
+	}
+	if a.cfg.Cache || a.cfg.ChangedOnly {
+		path, err := a.cachePath()
+		if err != nil {
+			return err
+		}
+		a.cache = loadSnapshotCache(path)
+	}
+
+	switch {
+	case a.cfg.NamesOnly || a.cfg.NullSeparated:
+		err = a.writeNames(ctx, files)
+	case a.cfg.StatsOnly || a.cfg.Langs:
+		err = a.processOrdered(ctx, files, func(ProcessedFile) error { return nil })
+	case a.cfg.Tree:
+		err = a.processAndWriteTree(ctx, files)
+	case a.isChunked():
+		err = a.processAndWriteChunks(ctx, files)
+	default:
+		// Process and output files
+		err = a.processAndOutput(ctx, files)
+	}
+	if err != nil {
+		return err
+	}
+
+	if a.langs != nil {
+		if err := a.writeLangs(); err != nil {
+			return err
+		}
+	}
+	if a.stats != nil {
+		if err := a.writeStats(); err != nil {
+			return err
+		}
+	}
+	if a.cache != nil {
+		return a.cache.save()
+	}
+	return nil
 }
 
 // validateConfig ensures the configuration is valid.
@@ -114,6 +307,99 @@ func (a *App) validateConfig() error {
 		return fmt.Errorf("directory '%s' does not exist", a.cfg.Directory)
 	}
 
+	if a.cfg.MaxDepth < 0 {
+		return fmt.Errorf("--max-depth must not be negative")
+	}
+	if a.cfg.Jobs < 0 {
+		return fmt.Errorf("--jobs must not be negative")
+	}
+	if !a.cfg.NewerThan.IsZero() && !a.cfg.OlderThan.IsZero() && !a.cfg.NewerThan.Before(a.cfg.OlderThan) {
+		return fmt.Errorf("--newer-than must be earlier than --older-than")
+	}
+
+	if a.cfg.ChunkTokens < 0 || a.cfg.ChunkBytes < 0 {
+		return fmt.Errorf("chunk size must not be negative")
+	}
+	if a.cfg.ContentPattern != "" && a.cfg.ContentRegex != "" {
+		return fmt.Errorf("--pattern and --regex cannot be used together")
+	}
+	if expr := a.cfg.ContentExpression(); expr != "" {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid content pattern: %w", err)
+		}
+	} else if a.cfg.InvertMatch {
+		return fmt.Errorf("--invert-match requires --pattern or --regex")
+	}
+
+	if a.cfg.Head < 0 || a.cfg.Tail < 0 {
+		return fmt.Errorf("--head and --tail must not be negative")
+	}
+	if a.cfg.Head > 0 && a.cfg.Tail > 0 {
+		return fmt.Errorf("--head and --tail cannot be used together")
+	}
+	if a.cfg.MaxLines < 0 || a.cfg.TruncateTo < 0 {
+		return fmt.Errorf("--max-lines and --truncate-to must not be negative")
+	}
+	if cmp.Or(a.cfg.TruncateTo, truncateToLines) > cmp.Or(a.cfg.MaxLines, maxDisplayLines) {
+		return fmt.Errorf("--truncate-to must not exceed --max-lines")
+	}
+	if a.cfg.Hex < 0 {
+		return fmt.Errorf("--hex must not be negative")
+	}
+
+	switch a.cfg.Binary.Strategy {
+	case "", scanner.BinaryDetectionFile, scanner.BinaryDetectionBytes:
+	default:
+		return fmt.Errorf("unsupported binary detection: %s (supported: %s, %s)",
+			a.cfg.Binary.Strategy, scanner.BinaryDetectionFile, scanner.BinaryDetectionBytes)
+	}
+	if a.cfg.Binary.SampleSize < 0 {
+		return fmt.Errorf("--binary-sample-size must not be negative")
+	}
+	if a.cfg.Binary.NullThreshold < 0 || a.cfg.Binary.NullThreshold > 1 {
+		return fmt.Errorf("--binary-null-threshold must be between 0 and 1")
+	}
+
+	if a.cfg.ChunkTokens > 0 && a.cfg.ChunkBytes > 0 {
+		return fmt.Errorf("--chunk-tokens and --chunk-bytes cannot be used together")
+	}
+
+	if a.cfg.OutputFormat == OutputFormatTemplate {
+		if a.cfg.TemplateFile == "" {
+			return fmt.Errorf("--format template requires --template-file")
+		}
+		if _, err := parseOutputTemplate(a.cfg.TemplateFile); err != nil {
+			return err
+		}
+	}
+
+	if a.cfg.MaxOutput < 0 {
+		return fmt.Errorf("--max-output must not be negative")
+	}
+	switch a.cfg.Prioritize {
+	case "", PrioritizeSize, PrioritizeRecent:
+	default:
+		return fmt.Errorf("unsupported --prioritize: %s (supported: %s, %s)", a.cfg.Prioritize, PrioritizeSize, PrioritizeRecent)
+	}
+
+	if a.cfg.GroupByDir && a.cfg.OutputFormat != OutputFormatMarkdown && a.cfg.OutputFormat != OutputFormatHTML {
+		return fmt.Errorf("--group-by-dir requires --format markdown or html")
+	}
+
+	if a.cfg.OutputFormat == OutputFormatTar && a.isChunked() && a.cfg.Output == "" {
+		return fmt.Errorf("chunked tar output requires --output")
+	}
+
+	// Compression defaults to the one implied by the output file extension
+	if a.cfg.Compress == "" && a.cfg.Output != "" {
+		a.cfg.Compress = compressionExtensions[filepath.Ext(a.cfg.Output)]
+	}
+	switch a.cfg.Compress {
+	case "", CompressGzip, CompressZstd:
+	default:
+		return fmt.Errorf("unsupported compression: %s (supported: %s, %s)", a.cfg.Compress, CompressGzip, CompressZstd)
+	}
+
 	// Normalize ignore directories
 	for i, dir := range a.cfg.IgnoreDir {
 		a.cfg.IgnoreDir[i] = strings.TrimSuffix(dir, "/")
@@ -122,48 +408,31 @@ func (a *App) validateConfig() error {
 	return nil
 }
 
-// addFilesToGlobs converts specific file arguments to glob patterns.
-func (a *App) addFilesToGlobs() {
-	for _, file := range a.cfg.Files {
-		if _, err := os.Stat(file); err == nil {
-			// File exists, use its basename as pattern
-			a.cfg.Globs = append(a.cfg.Globs, filepath.Base(file))
-		} else {
-			// File doesn't exist, treat as pattern
-			a.cfg.Globs = append(a.cfg.Globs, file)
+// processAndOutput handles file processing and output generation.
+func (a *App) processAndOutput(ctx context.Context, files []scanner.FileInfo) error {
+	// A budget needs every file before deciding which fit
+	if a.cfg.MaxOutput > 0 {
+		processed, err := a.processFiles(ctx, files)
+		if err != nil {
+			return err
 		}
+		return a.writeChunk(ctx, a.output, a.fitBudget(processed))
 	}
-}
 
-// processAndOutput handles file processing and output generation.
-func (a *App) processAndOutput(ctx context.Context, files []scanner.FileInfo) error {
 	// Write header
 	if err := a.output.WriteHeader(ctx); err != nil {
 		return fmt.Errorf("failed to write output header: %w", err)
 	}
 
-	for _, file := range files {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		// Apply file filtering
-		if !a.filter.ShouldIncludeFile(file, a.cfg) {
-			continue
-		}
-
-		// Create filter for this specific file processing
-		filter := NewFileFilter(a.cfg)
-		
-		// Process the file
-		processed := a.processor.ProcessFile(file, filter)
-
-		// Write processed file using the output formatter
+	// Process files concurrently and write them in order
+	err := a.processOrdered(ctx, files, func(processed ProcessedFile) error {
 		if err := a.output.WriteFile(ctx, processed, a.cfg); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", file.RelPath, err)
+			return fmt.Errorf("failed to write file %s: %w", processed.Info.RelPath, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Write footer
@@ -172,4 +441,30 @@ func (a *App) processAndOutput(ctx context.Context, files []scanner.FileInfo) er
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// processAndWriteTree processes the included files and prints them as a
+// directory tree instead of using the output formatter.
+func (a *App) processAndWriteTree(ctx context.Context, files []scanner.FileInfo) error {
+	processed, err := a.processFiles(ctx, files)
+	if err != nil {
+		return err
+	}
+
+	return a.writeTree(ctx, processed)
+}
+
+// processFiles filters and processes all files up front, for output modes
+// that need to see every file before writing anything.
+func (a *App) processFiles(ctx context.Context, files []scanner.FileInfo) ([]ProcessedFile, error) {
+	var processed []ProcessedFile
+	err := a.processOrdered(ctx, files, func(file ProcessedFile) error {
+		processed = append(processed, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return processed, nil
+}
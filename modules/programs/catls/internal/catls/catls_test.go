@@ -1,13 +1,29 @@
 package catls
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestRelativeToIntegration(t *testing.T) {
@@ -84,26 +100,13 @@ func TestRelativeToIntegration(t *testing.T) {
 				OutputFormat: "xml",
 			}
 
-			// Capture output
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			// Run the application
-			app := New(cfg)
+			var buf bytes.Buffer
+			app := NewWithWriter(cfg, &buf)
 			err := app.Run(context.Background())
-
-			// Restore stdout
-			w.Close()
-			os.Stdout = oldStdout
-
 			if err != nil {
 				t.Fatalf("Run() unexpected error: %v", err)
 			}
 
-			// Read output
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
 			output := buf.String()
 
 			// Verify the path in output contains expected prefix
@@ -146,23 +149,13 @@ func TestRelativeToWithNestedDirectories(t *testing.T) {
 			OutputFormat: "xml",
 		}
 
-		// Capture output
-		oldStdout := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
-
-		app := New(cfg)
+		var buf bytes.Buffer
+		app := NewWithWriter(cfg, &buf)
 		err := app.Run(context.Background())
-
-		w.Close()
-		os.Stdout = oldStdout
-
 		if err != nil {
 			t.Fatalf("Run() unexpected error: %v", err)
 		}
 
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
 		output := buf.String()
 
 		// Should contain paths relative to project root
@@ -191,23 +184,13 @@ func TestRelativeToWithNestedDirectories(t *testing.T) {
 			OutputFormat: "xml",
 		}
 
-		// Capture output
-		oldStdout := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
-
-		app := New(cfg)
+		var buf bytes.Buffer
+		app := NewWithWriter(cfg, &buf)
 		err := app.Run(context.Background())
-
-		w.Close()
-		os.Stdout = oldStdout
-
 		if err != nil {
 			t.Fatalf("Run() unexpected error: %v", err)
 		}
 
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
 		output := buf.String()
 
 		// Paths should be relative to tmpDir
@@ -233,7 +216,7 @@ func TestRelativeToWithDifferentOutputFormats(t *testing.T) {
 		t.Fatalf("failed to write test file: %v", err)
 	}
 
-	formats := []OutputFormat{"xml", "json", "markdown"}
+	formats := []OutputFormat{"xml", "json", "jsonl", "markdown", "csv", "html"}
 
 	for _, format := range formats {
 		t.Run(string(format), func(t *testing.T) {
@@ -244,23 +227,13 @@ func TestRelativeToWithDifferentOutputFormats(t *testing.T) {
 				OutputFormat: format,
 			}
 
-			// Capture output
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			app := New(cfg)
+			var buf bytes.Buffer
+			app := NewWithWriter(cfg, &buf)
 			err := app.Run(context.Background())
-
-			w.Close()
-			os.Stdout = oldStdout
-
 			if err != nil {
 				t.Fatalf("Run() unexpected error: %v", err)
 			}
 
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
 			output := buf.String()
 
 			// Should contain test.txt in output
@@ -278,11 +251,2314 @@ func TestRelativeToWithDifferentOutputFormats(t *testing.T) {
 				if !strings.Contains(output, "\"path\"") {
 					t.Errorf("json output should contain path field")
 				}
+			case "jsonl":
+				if !strings.HasPrefix(output, `{"path":"test.txt"`) || strings.Count(output, "\n") != 1 {
+					t.Errorf("jsonl output should contain one compact object per file")
+				}
 			case "markdown":
 				if !strings.Contains(output, "##") || !strings.Contains(output, "```") {
 					t.Errorf("markdown output should contain markdown formatting")
 				}
+			case "csv":
+				if !strings.HasPrefix(output, "path,type,binary,total_lines,size,truncated\n") {
+					t.Errorf("csv output should start with the column header row")
+				}
+				if !strings.Contains(output, "test.txt,,false,1,12,false\n") {
+					t.Errorf("csv output should contain a row for test.txt")
+				}
+			case "html":
+				if !strings.Contains(output, "<details open") || !strings.Contains(output, `href="#file-1"`) {
+					t.Errorf("html output should contain file sections and an index")
+				}
+			}
+		})
+	}
+}
+
+func TestTreeOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"src/main.go":      "package main\n\nfunc main() {}\n",
+		"src/lib/utils.go": "package lib\n",
+		"README.md":        "# Project\n",
+	}
+
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file %s: %v", fullPath, err)
+		}
+	}
+
+	cfg := &Config{
+		Directory:    tmpDir,
+		Recursive:    true,
+		Tree:         true,
+		OutputFormat: "xml",
+	}
+
+	var buf bytes.Buffer
+	app := NewWithWriter(cfg, &buf)
+	err := app.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	output := buf.String()
+
+	want := tmpDir + `
+├── README.md (1 lines, 10 B)
+└── src/
+    ├── lib/
+    │   └── utils.go (1 lines, 12 B)
+    └── main.go (3 lines, 29 B)
+`
+	if output != want {
+		t.Errorf("tree output mismatch\ngot:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.size); got != tt.want {
+			t.Errorf("formatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 2},
+		{"x := 12345", 5},
+		{"func main() {}", 4},
+		{"internationalization", 5},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestChunkFiles(t *testing.T) {
+	newFile := func(path string, lineLen int) ProcessedFile {
+		return ProcessedFile{
+			Info:  scanner.FileInfo{RelPath: path},
+			Lines: []FilteredLine{{LineNumber: 1, Content: strings.Repeat("x", lineLen)}},
+		}
+	}
+
+	// Each file weighs chunkFileOverhead + len(path) + lineLen + 1 bytes.
+	files := []ProcessedFile{
+		newFile("a", 34),  // 100 bytes
+		newFile("b", 34),  // 100 bytes
+		newFile("c", 434), // 500 bytes, larger than the budget on its own
+		newFile("d", 34),  // 100 bytes
+	}
+
+	app := New(&Config{OutputFormat: "xml", ChunkBytes: 250})
+	chunks := app.chunkFiles(files)
+
+	var got [][]string
+	for _, chunk := range chunks {
+		var paths []string
+		for _, file := range chunk {
+			paths = append(paths, file.Info.RelPath)
+		}
+		got = append(got, paths)
+	}
+
+	want := [][]string{{"a", "b"}, {"c"}, {"d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chunkFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestXMLOutputWriter(t *testing.T) {
+	var buf bytes.Buffer
+	output := NewXMLOutput(&buf)
+	ctx := context.Background()
+
+	file := ProcessedFile{
+		Info:       scanner.FileInfo{RelPath: "main.go"},
+		FileType:   "go",
+		Lines:      []FilteredLine{{LineNumber: 1, Content: "package main"}},
+		TotalLines: 1,
+	}
+
+	if err := output.WriteHeader(ctx); err != nil {
+		t.Fatalf("WriteHeader() unexpected error: %v", err)
+	}
+	if err := output.WriteFile(ctx, file, &Config{}); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := output.WriteFooter(ctx); err != nil {
+		t.Fatalf("WriteFooter() unexpected error: %v", err)
+	}
+
+	want := `<files>
+<file path="main.go" size="0" type="go">
+<content><![CDATA[
+package main
+]]></content>
+</file>
+</files>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("XML output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(strings.Repeat("x", 100)), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	t.Run("creates parent directories", func(t *testing.T) {
+		outPath := filepath.Join(tmpDir, "out", "nested", "snapshot.xml")
+		cfg := &Config{
+			Directory:    srcDir,
+			OutputFormat: "xml",
+			Output:       outPath,
+		}
+
+		var stdout bytes.Buffer
+		if err := NewWithWriter(cfg, &stdout).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		if stdout.Len() != 0 {
+			t.Errorf("nothing should be written to the default writer, got:\n%s", stdout.String())
+		}
+
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(data), `<file path="a.txt" `) {
+			t.Errorf("output file should contain a.txt\noutput:\n%s", data)
+		}
+	})
+
+	t.Run("writes one numbered file per chunk", func(t *testing.T) {
+		outPath := filepath.Join(tmpDir, "chunks", "snapshot.md")
+		cfg := &Config{
+			Directory:    srcDir,
+			OutputFormat: "markdown",
+			Output:       outPath,
+			ChunkBytes:   200,
+		}
+
+		if err := New(cfg).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		for i, name := range []string{"a.txt", "b.txt"} {
+			path := filepath.Join(tmpDir, "chunks", fmt.Sprintf("snapshot-%03d.md", i+1))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read chunk file: %v", err)
+			}
+			if !strings.Contains(string(data), "## "+name) {
+				t.Errorf("chunk %s should contain %s\noutput:\n%s", path, name, data)
+			}
+		}
+	})
+
+	t.Run("leaves out its own output files", func(t *testing.T) {
+		t.Chdir(srcDir)
+		if err := os.MkdirAll("sub", 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join("sub", "out-001.xml"), []byte("old chunk"), 0644); err != nil {
+			t.Fatalf("failed to write old chunk: %v", err)
+		}
+		t.Cleanup(func() { _ = os.RemoveAll(filepath.Join(srcDir, "sub")) })
+
+		cfg := &Config{
+			Directory:    ".",
+			Recursive:    true,
+			OutputFormat: "xml",
+			Output:       filepath.Join("sub", "out.xml"),
+		}
+		if err := New(cfg).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(cfg.Output)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		for _, name := range []string{"out.xml", "out-001.xml"} {
+			if strings.Contains(string(data), name) {
+				t.Errorf("output should not list %s\noutput:\n%s", name, data)
+			}
+		}
+		if !strings.Contains(string(data), `<file path="a.txt" `) {
+			t.Errorf("output file should contain a.txt\noutput:\n%s", data)
+		}
+	})
+
+	t.Run("compresses by output extension", func(t *testing.T) {
+		for _, name := range []string{"snapshot.xml.gz", "snapshot.xml.zst"} {
+			outPath := filepath.Join(tmpDir, "compressed", name)
+			cfg := &Config{
+				Directory:    srcDir,
+				OutputFormat: "xml",
+				Output:       outPath,
+			}
+			if err := New(cfg).Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			file, err := os.Open(outPath)
+			if err != nil {
+				t.Fatalf("failed to open output file: %v", err)
+			}
+			defer file.Close()
+
+			var reader io.Reader
+			if cfg.Compress == CompressGzip {
+				gz, err := gzip.NewReader(file)
+				if err != nil {
+					t.Fatalf("%s is not gzip compressed: %v", name, err)
+				}
+				if !strings.Contains(gz.Comment, `"compression":"gzip"`) {
+					t.Errorf("gzip header comment = %q, want a manifest", gz.Comment)
+				}
+				reader = gz
+			} else {
+				zr, err := zstd.NewReader(file)
+				if err != nil {
+					t.Fatalf("%s is not zstd compressed: %v", name, err)
+				}
+				defer zr.Close()
+				reader = zr
+			}
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to decompress %s: %v", name, err)
+			}
+			if !strings.Contains(string(data), `<file path="a.txt" `) {
+				t.Errorf("%s should contain a.txt\noutput:\n%s", name, data)
+			}
+		}
+	})
+}
+
+func TestChunkOutputPath(t *testing.T) {
+	tests := map[string]string{
+		"out.xml":        "out-002.xml",
+		"dir/out.xml.gz": "dir/out-002.xml.gz",
+		"out.zst":        "out-002.zst",
+	}
+	for path, want := range tests {
+		if got := chunkOutputPath(path, 2); got != want {
+			t.Errorf("chunkOutputPath(%q, 2) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMultipleTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for path, content := range map[string]string{
+		"src/main.go":      "package main",
+		"src/util.go":      "package main",
+		"pkg/lib/lib.go":   "package lib",
+		"docs/README.md":   "# Docs",
+		"docs/CHANGES.md":  "# Changes",
+		"other/skipped.go": "package other",
+	} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		directory string
+		files     []string
+		want      []string
+	}{
+		{
+			name:      "directories and an explicit file share a common root",
+			directory: "src",
+			files:     []string{"pkg", "docs/README.md"},
+			want:      []string{"docs/README.md", "pkg/lib/lib.go", "src/main.go", "src/util.go"},
+		},
+		{
+			name:      "file inside the directory selects it",
+			directory: "src",
+			files:     []string{"src/util.go"},
+			want:      []string{"util.go"},
+		},
+		{
+			name:      "missing paths are glob patterns",
+			directory: "src",
+			files:     []string{"main.*"},
+			want:      []string{"main.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Directory:    filepath.Join(tmpDir, tt.directory),
+				Recursive:    true,
+				OutputFormat: "xml",
+			}
+			for _, file := range tt.files {
+				if strings.Contains(file, "*") {
+					cfg.Files = append(cfg.Files, file)
+				} else {
+					cfg.Files = append(cfg.Files, filepath.Join(tmpDir, file))
+				}
+			}
+
+			app := New(cfg)
+			files, err := app.scanTargets(context.Background())
+			if err != nil {
+				t.Fatalf("scanTargets() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				if app.filter.ShouldIncludeFile(file, cfg) {
+					got = append(got, file.RelPath)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanTargets() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestNestedFileWithoutRecursion(t *testing.T) {
+	tmpDir := t.TempDir()
+	for path, content := range map[string]string{
+		"top.go":     "package top",
+		"sub/foo.go": "package sub",
+		"sub/bar.go": "package sub",
+	} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(tmpDir)
+
+	for _, file := range []string{"sub/foo.go", filepath.Join(tmpDir, "sub", "foo.go")} {
+		cfg := &Config{
+			Directory:    ".",
+			Files:        []string{file},
+			OutputFormat: "xml",
+		}
+
+		app := New(cfg)
+		files, err := app.scanTargets(context.Background())
+		if err != nil {
+			t.Fatalf("scanTargets() unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, file := range files {
+			got = append(got, file.RelPath)
+		}
+		if want := []string{"sub/foo.go"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("scanTargets() with %s = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestCommonRoot(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"src", "pkg"}, "."},
+		{[]string{"a/b", "a/c/d"}, "a"},
+		{[]string{"/x/y/z", "/x/y/w"}, "/x/y"},
+		{[]string{"/x", "/y"}, "/"},
+		{[]string{".", "../docs"}, ".."},
+		{[]string{"sub/a", "../work/sub/b"}, "sub"},
+	}
+
+	work := filepath.Join(t.TempDir(), "work")
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	t.Chdir(work)
+
+	for _, tt := range tests {
+		if got := commonRoot(tt.paths); got != tt.want {
+			t.Errorf("commonRoot(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestRootsOutsideWorkingDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	for path, content := range map[string]string{
+		"work/main.go":  "package main",
+		"docs/post.md":  "# Post",
+		"src/lib.go":    "package lib",
+		"src/sub/io.go": "package sub",
+	} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(filepath.Join(tmpDir, "work"))
+
+	tests := []struct {
+		name       string
+		directory  string
+		files      []string
+		relativeTo string
+		want       []string
+	}{
+		{
+			name:      "common root above the working directory",
+			directory: ".",
+			files:     []string{"../docs"},
+			want:      []string{"docs/post.md", "work/main.go"},
+		},
+		{
+			name:       "absolute --relative-to with relative targets",
+			directory:  "../src",
+			files:      []string{"../docs"},
+			relativeTo: filepath.Join(tmpDir, "src"),
+			want:       []string{"../docs/post.md", "lib.go", "sub/io.go"},
+		},
+		{
+			name:       "relative --relative-to with an absolute target",
+			directory:  filepath.Join(tmpDir, "src"),
+			relativeTo: "..",
+			want:       []string{"src/lib.go", "src/sub/io.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Directory:    tt.directory,
+				Files:        tt.files,
+				RelativeTo:   tt.relativeTo,
+				Recursive:    true,
+				OutputFormat: "xml",
+			}
+
+			files, err := New(cfg).scanTargets(context.Background())
+			if err != nil {
+				t.Fatalf("scanTargets() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, file.RelPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobFirstArgument(t *testing.T) {
+	tmpDir := t.TempDir()
+	for path, content := range map[string]string{
+		"src/main.go":   "package main",
+		"src/README.md": "# Src",
+		"top.go":        "package top",
+	} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(tmpDir)
+
+	var buf bytes.Buffer
+	cfg := &Config{Directory: "src/*.go", Recursive: true, NamesOnly: true, OutputFormat: OutputFormatXML}
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "src/main.go\n"; got != want {
+		t.Errorf("Run() with a glob first argument = %q, want %q", got, want)
+	}
+
+	// Without glob characters a missing directory is still an error
+	cfg = &Config{Directory: "srcc", Recursive: true, NamesOnly: true, OutputFormat: OutputFormatXML}
+	err := NewWithWriter(cfg, &buf).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Run() with a missing directory error = %v, want one saying it does not exist", err)
+	}
+}
+
+func TestProcessFileHeadTail(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "lines.txt")
+
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		wantFirst    int
+		wantCount    int
+		wantLeading  string
+		wantTrailing string
+	}{
+		{
+			name:         "head",
+			cfg:          Config{Head: 3},
+			wantFirst:    1,
+			wantCount:    3,
+			wantTrailing: "... (7 more lines)",
+		},
+		{
+			name:        "tail",
+			cfg:         Config{Tail: 4},
+			wantFirst:   7,
+			wantCount:   4,
+			wantLeading: "... (6 earlier lines)",
+		},
+		{
+			name:      "head larger than file",
+			cfg:       Config{Head: 20},
+			wantFirst: 1,
+			wantCount: 10,
+		},
+		{
+			name:        "tail of filtered lines",
+			cfg:         Config{Tail: 2, ContentRegex: `[13579]$`},
+			wantFirst:   7,
+			wantCount:   2,
+			wantLeading: "... (3 earlier lines)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewFileProcessor(&tt.cfg)
+			result := processor.ProcessFile(scanner.FileInfo{Path: path, RelPath: "lines.txt"}, NewFileFilter(&tt.cfg))
+
+			if len(result.Lines) != tt.wantCount {
+				t.Fatalf("got %d lines, want %d", len(result.Lines), tt.wantCount)
+			}
+			if result.Lines[0].LineNumber != tt.wantFirst {
+				t.Errorf("first line number = %d, want %d", result.Lines[0].LineNumber, tt.wantFirst)
+			}
+			for i := 1; i < len(result.Lines); i++ {
+				if result.Lines[i].LineNumber <= result.Lines[i-1].LineNumber {
+					t.Errorf("lines out of order: %d after %d", result.Lines[i].LineNumber, result.Lines[i-1].LineNumber)
+				}
+			}
+			if got := result.LeadingNotice(); got != tt.wantLeading {
+				t.Errorf("LeadingNotice() = %q, want %q", got, tt.wantLeading)
+			}
+			if got := result.TrailingNotice(); got != tt.wantTrailing {
+				t.Errorf("TrailingNotice() = %q, want %q", got, tt.wantTrailing)
+			}
+		})
+	}
+}
+
+func TestFilterContentPatterns(t *testing.T) {
+	lines := []string{
+		"func Alpha() {",
+		"\treturn",
+		"}",
+		"// TODO: fix",
+		"func beta() {}",
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []int
+	}{
+		{"glob pattern", Config{ContentPattern: "func *"}, []int{1, 5}},
+		{"regex", Config{ContentRegex: `^func [a-z]`}, []int{5}},
+		{"regex ignore case", Config{ContentRegex: `^func [a-z]`, IgnoreCase: true}, []int{1, 5}},
+		{"glob ignore case", Config{ContentPattern: "*todo*", IgnoreCase: true}, []int{4}},
+		{"multiline span", Config{ContentRegex: `Alpha\(\) \{\n\s+return`, Multiline: true}, []int{1, 2}},
+		{"multiline anchors", Config{ContentRegex: `^\}$`, Multiline: true}, []int{3}},
+		{"inverted glob", Config{ContentPattern: "*//*", InvertMatch: true}, []int{1, 2, 3, 5}},
+		{"inverted regex", Config{ContentRegex: `^func`, InvertMatch: true}, []int{2, 3, 4}},
+		{"inverted multiline", Config{ContentRegex: `\{\n\s+return\n\}`, Multiline: true, InvertMatch: true}, []int{4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, line := range NewFileFilter(&tt.cfg).FilterContent(lines) {
+				got = append(got, line.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterContent() line numbers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldIncludeFileGlobs(t *testing.T) {
+	paths := []string{"main.go", "main_test.go", "pkg/util.go", "docs/readme.md", ".git/config", "sub/.git/HEAD"}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"no globs", Config{}, []string{"main.go", "main_test.go", "pkg/util.go", "docs/readme.md"}},
+		{"negated include", Config{Globs: []string{"*.go", "!*_test.go"}}, []string{"main.go", "pkg/util.go"}},
+		{"only negations", Config{Globs: []string{"!*.go"}}, []string{"docs/readme.md"}},
+		{"anchored include", Config{Globs: []string{"pkg/*.go"}}, []string{"pkg/util.go"}},
+		{"re-included ignore", Config{IgnoreGlobs: []string{"*.go", "!main.go"}}, []string{"main.go", "docs/readme.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFileFilter(&tt.cfg)
+			var got []string
+			for _, path := range paths {
+				if filter.ShouldIncludeFile(scanner.FileInfo{Path: path, RelPath: path}, &tt.cfg) {
+					got = append(got, path)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShouldIncludeFile() selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	write := func(path, content string) {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	write("stable.go", "package main")
+	write("edited.go", "package main")
+	write("sub/committed.go", "package sub")
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "base")
+
+	write("sub/committed.go", "package sub\n\nfunc New() {}")
+	git("commit", "-q", "-am", "change sub")
+	write("edited.go", "package main\n\nfunc main() {}")
+	write("untracked.go", "package main")
+
+	tests := []struct {
+		ref  string
+		want []string
+	}{
+		{ref: "HEAD", want: []string{"edited.go", "untracked.go"}},
+		{ref: "base", want: []string{"edited.go", "sub/committed.go", "untracked.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			cfg := &Config{
+				Directory:    root,
+				Recursive:    true,
+				OutputFormat: "xml",
+				ChangedSince: tt.ref,
+			}
+
+			files, err := New(cfg).scanTargets(context.Background())
+			if err != nil {
+				t.Fatalf("scanTargets() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, file.RelPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	cfg := &Config{Directory: root, OutputFormat: "xml", ChangedSince: "no-such-ref"}
+	if _, err := New(cfg).scanTargets(context.Background()); err == nil {
+		t.Error("scanTargets() with an unknown ref should fail")
+	}
+}
+
+func TestProcessOrderedKeepsInputOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	var files []scanner.FileInfo
+	for i := range 50 {
+		name := fmt.Sprintf("file%02d.txt", i)
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("line\n", i+1)), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		files = append(files, scanner.FileInfo{Path: path, RelPath: name})
+	}
+
+	cfg := &Config{
+		Directory:    tmpDir,
+		OutputFormat: "xml",
+		IgnoreGlobs:  []string{"file13.txt"},
+		Jobs:         4,
+	}
+	processed, err := New(cfg).processFiles(context.Background(), files)
+	if err != nil {
+		t.Fatalf("processFiles() unexpected error: %v", err)
+	}
+
+	if len(processed) != len(files)-1 {
+		t.Fatalf("processFiles() returned %d files, want %d", len(processed), len(files)-1)
+	}
+	want := 0
+	for _, file := range processed {
+		if want == 13 {
+			want++
+		}
+		if name := fmt.Sprintf("file%02d.txt", want); file.Info.RelPath != name || file.TotalLines != want+1 {
+			t.Fatalf("processFiles() got %s with %d lines, want %s with %d lines", file.Info.RelPath, file.TotalLines, name, want+1)
+		}
+		want++
+	}
+}
+
+func TestProcessFileStreaming(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	longLine := strings.Repeat("x", 256*1024)
+	longPath := filepath.Join(tmpDir, "long.txt")
+	if err := os.WriteFile(longPath, []byte("short\r\n"+longLine+"\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cfg := &Config{}
+	result := NewFileProcessor(cfg).ProcessFile(scanner.FileInfo{Path: longPath, RelPath: "long.txt"}, NewFileFilter(cfg))
+	if result.Error != nil {
+		t.Fatalf("ProcessFile() unexpected error: %v", result.Error)
+	}
+	if result.TotalLines != 2 || result.Lines[0].Content != "short" || result.Lines[1].Content != longLine {
+		t.Errorf("ProcessFile() did not read long lines intact: %d lines", result.TotalLines)
+	}
+
+	bigPath := filepath.Join(tmpDir, "big.txt")
+	if err := os.WriteFile(bigPath, []byte(strings.Repeat("line\n", 5000)), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	result = NewFileProcessor(cfg).ProcessFile(scanner.FileInfo{Path: bigPath, RelPath: "big.txt"}, NewFileFilter(cfg))
+	if result.TotalLines != 5000 || len(result.Lines) != truncateToLines || result.OmittedLines != 5000-truncateToLines {
+		t.Errorf("ProcessFile() = %d total, %d shown, %d omitted; want 5000, %d, %d",
+			result.TotalLines, len(result.Lines), result.OmittedLines, truncateToLines, 5000-truncateToLines)
+	}
+}
+
+func TestJSONOutputStreaming(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{CountTokens: true}
+
+	for _, count := range []int{0, 1, 3} {
+		var buf bytes.Buffer
+		output := NewJSONOutput(&buf)
+		if err := output.WriteHeader(ctx); err != nil {
+			t.Fatalf("WriteHeader() unexpected error: %v", err)
+		}
+		for i := range count {
+			file := ProcessedFile{
+				Info:   scanner.FileInfo{RelPath: fmt.Sprintf("file%d.go", i)},
+				Lines:  []FilteredLine{{LineNumber: 1, Content: "package main"}},
+				Tokens: 2,
+			}
+			if err := output.WriteFile(ctx, file, cfg); err != nil {
+				t.Fatalf("WriteFile() unexpected error: %v", err)
+			}
+		}
+		if err := output.WriteFooter(ctx); err != nil {
+			t.Fatalf("WriteFooter() unexpected error: %v", err)
+		}
+
+		var doc struct {
+			Files       []JSONFile `json:"files"`
+			TotalTokens int        `json:"totalTokens"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("output with %d files is not valid JSON: %v\n%s", count, err, buf.String())
+		}
+		if len(doc.Files) != count || doc.TotalTokens != 2*count {
+			t.Errorf("got %d files and %d tokens, want %d and %d", len(doc.Files), doc.TotalTokens, count, 2*count)
+		}
+	}
+}
+
+func TestTarOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"lib/util.go": "package lib\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := &Config{
+		Directory:    tmpDir,
+		Recursive:    true,
+		OutputFormat: "tar",
+		Head:         1,
+	}
+	var buf bytes.Buffer
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	archived := make(map[string]string)
+	reader := tar.NewReader(&buf)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid tar stream: %v", err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", header.Name, err)
+		}
+		archived[header.Name] = string(data)
+	}
+
+	for path, content := range files {
+		if archived[path] != content {
+			t.Errorf("archived %s = %q, want the full original %q", path, archived[path], content)
+		}
+	}
+
+	var manifest struct {
+		Files []TarManifestFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(archived[tarManifestName]), &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	want := []TarManifestFile{
+		{Path: "lib/util.go", Type: "go", Size: 12, TotalLines: 1},
+		{Path: "main.go", Type: "go", Size: 29, TotalLines: 3},
+	}
+	if !reflect.DeepEqual(manifest.Files, want) {
+		t.Errorf("manifest files = %+v, want %+v", manifest.Files, want)
+	}
+
+	for relPath, want := range map[string]string{
+		"a/b.go":     "a/b.go",
+		"../up.go":   "up.go",
+		"/abs/x.go":  "abs/x.go",
+		"./a/../b.c": "b.c",
+	} {
+		if got := tarEntryName(relPath); got != want {
+			t.Errorf("tarEntryName(%q) = %q, want %q", relPath, got, want)
+		}
+	}
+}
+
+func TestBinaryHexPreview(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "image.png")
+	data := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 64)...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	info := scanner.FileInfo{Path: path, RelPath: "image.png", IsBinary: true, Size: int64(len(data))}
+
+	cfg := &Config{Hex: 16}
+	result := NewFileProcessor(cfg).ProcessFile(info, NewFileFilter(cfg))
+	if result.MIMEType != "image/png" {
+		t.Errorf("MIMEType = %q, want image/png", result.MIMEType)
+	}
+	if len(result.BinaryPreview) != 16 {
+		t.Errorf("BinaryPreview has %d bytes, want 16", len(result.BinaryPreview))
+	}
+
+	var buf bytes.Buffer
+	if err := NewXMLOutput(&buf).WriteFile(context.Background(), result, cfg); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	for _, want := range []string{"<mimeType>image/png</mimeType>", "89 50 4e 47 0d 0a 1a 0a", "|.PNG........IHDR|"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("XML output should contain %q\noutput:\n%s", want, buf.String())
+		}
+	}
+
+	cfg = &Config{}
+	result = NewFileProcessor(cfg).ProcessFile(info, NewFileFilter(cfg))
+	if result.HexDump() != "" || result.BinaryNotice() != "Binary file - contents not displayed" {
+		t.Errorf("binary files should not be previewed without --hex")
+	}
+}
+
+func TestContentTypeDetector(t *testing.T) {
+	tmpDir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"main.go", "package main", "go"},
+		{"deploy", "#!/usr/bin/env python3\nprint('hi')", "python"},
+		{"run", "#!/bin/sh\necho hi", "bash"},
+		{"serve", "#!/usr/bin/env -S deno run --allow-net\n", "typescript"},
+		{"Dockerfile.prod", "FROM alpine", "dockerfile"},
+		{"GNUmakefile", "all:\n", "makefile"},
+		{"rules.mk", "all:\n", "makefile"},
+		{"Rakefile", "# vim: set ft=ruby :\ntask :default", "ruby"},
+		{"script", "# -*- mode: python; coding: utf-8 -*-\n", "python"},
+		{"config", "# -*- shell-script -*-\n", "bash"},
+		{"page", "<!DOCTYPE html>\n<html></html>", "html"},
+		{"notes", "just some text", ""},
+	}
+
+	detector := &ContentTypeDetector{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", tt.name, err)
+			}
+			if got := detector.DetectType(path); got != tt.want {
+				t.Errorf("DetectType(%s) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	for path, content := range map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"util.go":   "package main\n",
+		"run.py":    "print('hi')\nprint('bye')\n",
+		"image.bin": "\x00\x01\x02",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := &Config{Directory: tmpDir, OutputFormat: "json", StatsOnly: true}
+	var buf bytes.Buffer
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Stats Stats `json:"stats"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("stats output is not a single JSON document: %v\n%s", err, buf.String())
+	}
+
+	stats := doc.Stats
+	if stats.Files != 4 || stats.BinaryFiles != 1 || stats.TotalLines != 6 || stats.TotalSize != 70 {
+		t.Errorf("totals = %+v, want 4 files, 1 binary, 6 lines, 70 bytes", stats)
+	}
+	wantLanguages := []LanguageStats{
+		{Language: "go", Files: 2, Lines: 4, Size: 42},
+		{Language: "python", Files: 1, Lines: 2, Size: 25},
+		{Language: "binary", Files: 1, Lines: 0, Size: 3},
+	}
+	if !reflect.DeepEqual(stats.Languages, wantLanguages) {
+		t.Errorf("languages = %+v, want %+v", stats.Languages, wantLanguages)
+	}
+	if len(stats.Largest) != 4 || stats.Largest[0].Path != "main.go" {
+		t.Errorf("largest = %+v, want main.go first", stats.Largest)
+	}
+
+	cfg = &Config{Directory: tmpDir, OutputFormat: "markdown", Stats: true}
+	buf.Reset()
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	for _, want := range []string{"## main.go", "Language", "Binary files: 1", "Largest files:"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output should contain %q\noutput:\n%s", want, buf.String())
+		}
+	}
+
+	// With the listing, the statistics join the same JSON document
+	cfg = &Config{Directory: tmpDir, OutputFormat: "json", Stats: true}
+	buf.Reset()
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	var listing struct {
+		Files []JSONFile `json:"files"`
+		Stats Stats      `json:"stats"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &listing); err != nil {
+		t.Fatalf("--stats output is not a single JSON document: %v\n%s", err, buf.String())
+	}
+	if len(listing.Files) != 4 || listing.Stats.Files != 4 {
+		t.Errorf("document has %d files and stats for %d, want 4 and 4", len(listing.Files), listing.Stats.Files)
+	}
+}
+
+func TestLineCounter(t *testing.T) {
+	source := []string{
+		"// Package main does things.",
+		"package main",
+		"",
+		"/* a block",
+		"   comment */",
+		"func main() { /* inline",
+		"still comment */ }",
+		"x := 1 /* closed */",
+		"",
+	}
+
+	counter := &lineCounter{syntax: languageComments["go"]}
+	for _, line := range source {
+		counter.countLine(line)
+	}
+	want := LanguageLines{Lines: 9, Code: 3, Comments: 4, Blanks: 2}
+	if counter.counts != want {
+		t.Errorf("go counts = %+v, want %+v", counter.counts, want)
+	}
+
+	counter = &lineCounter{syntax: languageComments["python"]}
+	for _, line := range []string{"#!/usr/bin/env python3", "# comment", "", "print('# not a comment')"} {
+		counter.countLine(line)
+	}
+	want = LanguageLines{Lines: 4, Code: 1, Comments: 2, Blanks: 1}
+	if counter.counts != want {
+		t.Errorf("python counts = %+v, want %+v", counter.counts, want)
+	}
+}
+
+func TestTermOutput(t *testing.T) {
+	file := ProcessedFile{
+		Info:       scanner.FileInfo{RelPath: "main.go"},
+		FileType:   "go",
+		Lines:      []FilteredLine{{LineNumber: 1, Content: "package main"}},
+		TotalLines: 1,
+	}
+
+	var plain bytes.Buffer
+	if err := NewTermOutput(&plain).WriteFile(context.Background(), file, &Config{ShowLineNumbers: true}); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if want := "==> main.go <== (go, 1 lines)\n   1| package main\n"; plain.String() != want {
+		t.Errorf("plain output = %q, want %q", plain.String(), want)
+	}
+
+	var colored bytes.Buffer
+	if err := NewTermOutput(&colored).WriteFile(context.Background(), file, &Config{Color: true}); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if !strings.Contains(colored.String(), "\x1b[") || !strings.Contains(colored.String(), "package") {
+		t.Errorf("colored output should contain ANSI escapes and the code, got %q", colored.String())
+	}
+}
+
+func TestProcessFileDecodesUTF16(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wide.txt")
+	data := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune("héllo\n😀 wörld\n")) {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cfg := &Config{}
+	result := NewFileProcessor(cfg).ProcessFile(scanner.FileInfo{Path: path, RelPath: "wide.txt"}, NewFileFilter(cfg))
+	var got []string
+	for _, line := range result.Lines {
+		got = append(got, line.Content)
+	}
+	if want := []string{"héllo", "😀 wörld"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ProcessFile() lines = %q, want %q", got, want)
+	}
+}
+
+func TestTemplateOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.tmpl")
+	tmpl := `{{define "header"}}<docs>
+{{end}}{{define "footer"}}</docs> {{.Files}} files
+{{end}}<doc name="{{xml .Info.RelPath}}" type={{json .FileType}}>
+{{content . | indent "  "}}</doc>
+`
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	cfg := &Config{OutputFormat: OutputFormatTemplate, TemplateFile: path, ShowLineNumbers: true}
+	var buf bytes.Buffer
+	output, err := NewOutputFormatter(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewOutputFormatter() unexpected error: %v", err)
+	}
+
+	file := ProcessedFile{
+		Info:     scanner.FileInfo{RelPath: "a&b.go"},
+		FileType: "go",
+		Lines:    []FilteredLine{{LineNumber: 1, Content: "package main"}},
+	}
+	if err := output.WriteHeader(context.Background()); err != nil {
+		t.Fatalf("WriteHeader() unexpected error: %v", err)
+	}
+	if err := output.WriteFile(context.Background(), file, cfg); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := output.WriteFooter(context.Background()); err != nil {
+		t.Fatalf("WriteFooter() unexpected error: %v", err)
+	}
+
+	want := "<docs>\n<doc name=\"a&amp;b.go\" type=\"go\">\n     1| package main\n</doc>\n</docs> 1 files\n"
+	if buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestExecOutput(t *testing.T) {
+	cfg := &Config{OutputFormat: "exec:cat"}
+	if !cfg.OutputFormat.IsValid() {
+		t.Fatalf("%s should be a valid format", cfg.OutputFormat)
+	}
+
+	var buf bytes.Buffer
+	output, err := NewOutputFormatter(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewOutputFormatter() unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	file := ProcessedFile{
+		Info:       scanner.FileInfo{RelPath: "main.go"},
+		Lines:      []FilteredLine{{LineNumber: 1, Content: "package main"}},
+		TotalLines: 1,
+	}
+	if err := output.WriteHeader(ctx); err != nil {
+		t.Fatalf("WriteHeader() unexpected error: %v", err)
+	}
+	if err := output.WriteFile(ctx, file, cfg); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := output.WriteFooter(ctx); err != nil {
+		t.Fatalf("WriteFooter() unexpected error: %v", err)
+	}
+
+	var messages []ExecMessage
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var msg ExecMessage
+		if err := decoder.Decode(&msg); err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+	if messages[0].Type != "header" || messages[0].Version != ExecProtocolVersion {
+		t.Errorf("header = %+v", messages[0])
+	}
+	if messages[1].Type != "file" || messages[1].File == nil || messages[1].File.Path != "main.go" {
+		t.Errorf("file = %+v", messages[1])
+	}
+	if messages[2].Type != "footer" || messages[2].Files != 1 {
+		t.Errorf("footer = %+v", messages[2])
+	}
+
+	// The formatter may exit before or after the header is written
+	failing, _ := NewOutputFormatter(&Config{OutputFormat: "exec:exit 3"}, io.Discard)
+	err = failing.WriteHeader(ctx)
+	if err == nil {
+		err = failing.WriteFooter(ctx)
+	}
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("expected the formatter's exit status as an error, got %v", err)
+	}
+}
+
+func TestParseRemoteSource(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   RemoteSource
+		remote bool
+	}{
+		{arg: "src/", remote: false},
+		{arg: "https://github.com/org/repo", want: RemoteSource{URL: "https://github.com/org/repo"}, remote: true},
+		{arg: "https://github.com/org/repo@v1.2", want: RemoteSource{URL: "https://github.com/org/repo", Ref: "v1.2"}, remote: true},
+		{arg: "git@github.com:org/repo.git", want: RemoteSource{URL: "git@github.com:org/repo.git"}, remote: true},
+		{arg: "git@github.com:org/repo@main", want: RemoteSource{URL: "git@github.com:org/repo", Ref: "main"}, remote: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseRemoteSource(tt.arg)
+		if ok != tt.remote || got != tt.want {
+			t.Errorf("ParseRemoteSource(%q) = %+v, %v, want %+v, %v", tt.arg, got, ok, tt.want, tt.remote)
+		}
+	}
+}
+
+func TestRunRemoteSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo, "old.go"), []byte("package old"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "v1")
+	git("mv", "old.go", "new.go")
+	git("commit", "-q", "-m", "rename")
+
+	for ref, want := range map[string]string{"": "new.go", "v1": "old.go"} {
+		source := "file://" + repo
+		if ref != "" {
+			source += "@" + ref
+		}
+
+		var buf bytes.Buffer
+		cfg := &Config{Directory: source, OutputFormat: OutputFormatXML}
+		if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run(%s) unexpected error: %v", source, err)
+		}
+		if !strings.Contains(buf.String(), `<file path="`+want+`" `) {
+			t.Errorf("Run(%s) output should contain %s, got:\n%s", source, want, buf.String())
+		}
+		if _, err := os.Stat(cfg.Directory); !os.IsNotExist(err) {
+			t.Errorf("clone directory %s was not removed", cfg.Directory)
+		}
+	}
+}
+
+func TestArchives(t *testing.T) {
+	root := t.TempDir()
+
+	var inner bytes.Buffer
+	tw := tar.NewWriter(&inner)
+	for name, content := range map[string]string{"pkg/lib.go": "package lib", "../escape.txt": "outside"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+
+	zipFile, err := os.Create(filepath.Join(root, "bundle.zip"))
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(zipFile)
+	for name, content := range map[string]string{"docs/readme.md": "# Docs", "nested.tar": inner.String()} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	zipFile.Close()
+
+	var buf bytes.Buffer
+	cfg := &Config{Directory: root, Archives: true, OutputFormat: OutputFormatXML}
+	app := NewWithWriter(cfg, &buf)
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		`<file path="bundle.zip!/docs/readme.md" `,
+		`<file path="bundle.zip!/nested.tar!/pkg/lib.go" `,
+		`<file path="bundle.zip!/nested.tar!/escape.txt" `,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output should contain %s, got:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), `<file path="bundle.zip" `) {
+		t.Error("the archive itself should be replaced by its entries")
+	}
+	if app.extractDir != "" {
+		t.Error("extracted archives should be removed after the run")
+	}
+
+	// A damaged archive is listed as a file instead of ending the scan
+	data, err := os.ReadFile(filepath.Join(root, "bundle.zip"))
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "broken.zip"), data[:len(data)/2], 0644); err != nil {
+		t.Fatalf("failed to write truncated zip: %v", err)
+	}
+	buf.Reset()
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() with a truncated zip unexpected error: %v", err)
+	}
+	for _, want := range []string{`<file path="broken.zip" `, `<file path="bundle.zip!/docs/readme.md" `} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output should contain %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	const digest = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	var buf bytes.Buffer
+	cfg := &Config{Directory: root, Hash: true, OutputFormat: OutputFormatJSONL}
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	var file JSONFile
+	if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if file.SHA256 != digest || file.Size != 6 {
+		t.Errorf("sha256, size = %s, %d, want %s, 6", file.SHA256, file.Size, digest)
+	}
+
+	buf.Reset()
+	cfg = &Config{Directory: root, Hash: true, OutputFormat: OutputFormatXML}
+	if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if want := ` sha256="` + digest + `">`; !strings.Contains(buf.String(), `<file path="hello.txt" size="6"`) || !strings.Contains(buf.String(), want) {
+		t.Errorf("xml output should contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		countTokens bool
+		hash        bool
+		want        []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"path", "type", "binary", "total_lines", "size", "truncated"},
+		},
+		{
+			name:        "with --count-tokens",
+			countTokens: true,
+			want:        []string{"path", "type", "binary", "total_lines", "size", "truncated", "tokens"},
+		},
+		{
+			name: "with --hash",
+			hash: true,
+			want: []string{"path", "type", "binary", "total_lines", "size", "truncated", "sha256"},
+		},
+		{
+			name:        "with both",
+			countTokens: true,
+			hash:        true,
+			want:        []string{"path", "type", "binary", "total_lines", "size", "truncated", "tokens", "sha256"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &Config{Directory: root, CountTokens: tt.countTokens, Hash: tt.hash, OutputFormat: OutputFormatCSV}
+			if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("failed to parse csv output: %v", err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("csv output has %d records, want a header and one row", len(records))
+			}
+			if !reflect.DeepEqual(records[0], tt.want) {
+				t.Errorf("csv header = %v, want %v", records[0], tt.want)
+			}
+			row := make(map[string]string)
+			for i, column := range records[0] {
+				row[column] = records[1][i]
+			}
+			if row["path"] != "hello.txt" || row["size"] != "6" {
+				t.Errorf("csv row = %v, want hello.txt of size 6", records[1])
+			}
+			if tt.countTokens && row["tokens"] == "" {
+				t.Errorf("csv row = %v, want a token count", records[1])
+			}
+			if tt.hash && row["sha256"] != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+				t.Errorf("csv row = %v, want the sha256 of hello.txt", records[1])
+			}
+		})
+	}
+}
+
+func TestChangedOnly(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write("a.txt", "alpha")
+	write("b.txt", "beta")
+
+	run := func(cfg *Config) string {
+		var buf bytes.Buffer
+		cfg.Directory = root
+		cfg.OutputFormat = OutputFormatXML
+		if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	full := run(&Config{Cache: true})
+	if cached := run(&Config{Cache: true}); cached != full {
+		t.Errorf("cached output = %q, want %q", cached, full)
+	}
+
+	if got := run(&Config{ChangedOnly: true}); strings.Contains(got, "<file ") {
+		t.Errorf("unchanged files should be omitted, got:\n%s", got)
+	}
+
+	write("b.txt", "beta, edited")
+	got := run(&Config{ChangedOnly: true})
+	if !strings.Contains(got, `<file path="b.txt" `) || strings.Contains(got, `<file path="a.txt" `) {
+		t.Errorf("only the edited file should be output, got:\n%s", got)
+	}
+	if !strings.Contains(got, "beta, edited") {
+		t.Errorf("edited file should show its new content, got:\n%s", got)
+	}
+}
+
+func TestFollowImports(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/m\n",
+		"main.go":            "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/m/lib\"\n)\n",
+		"helpers.go":         "package main\n",
+		"lib/lib.go":         "package lib\n",
+		"lib/lib_test.go":    "package lib\n",
+		"unused/unused.go":   "package unused\n",
+		"web/app.ts":         "import { util } from './util';\nimport '../shared/setup.js';\nconst x = require(\"lodash\");\n",
+		"web/util.ts":        "export const util = 1;\n",
+		"shared/setup.ts":    "export {};\n",
+		"shared/unused.ts":   "export {};\n",
+		"py/app.py":          "import os\nfrom .models import (\n    User,\n    Group as G,\n)\nfrom pkg import helpers\n",
+		"py/models.py":       "",
+		"py/pkg/__init__.py": "",
+		"py/pkg/helpers.py":  "",
+		"py/unused.py":       "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := &Config{
+		Directory:     root,
+		Files:         []string{filepath.Join(root, "main.go"), filepath.Join(root, "web/app.ts"), filepath.Join(root, "py/app.py")},
+		Recursive:     true,
+		FollowImports: true,
+		OutputFormat:  OutputFormatXML,
+	}
+	app := NewWithWriter(cfg, io.Discard)
+	if err := app.validateConfig(); err != nil {
+		t.Fatalf("validateConfig() unexpected error: %v", err)
+	}
+	found, err := app.scanTargets(context.Background())
+	if err != nil {
+		t.Fatalf("scanTargets() unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, file := range found {
+		got = append(got, filepath.ToSlash(file.RelPath))
+	}
+	want := []string{
+		"helpers.go", "lib/lib.go", "main.go",
+		"py/app.py", "py/models.py", "py/pkg/__init__.py", "py/pkg/helpers.py",
+		"shared/setup.ts", "web/app.ts", "web/util.ts",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanTargets() = %v, want %v", got, want)
+	}
+}
+
+func TestFollowImportsNamesOnly(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"go.mod":     "module example.com/m\n",
+		"main.go":    "package main\n\nimport \"example.com/m/lib\"\n",
+		"lib/lib.go": "package lib\n",
+	} {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(root)
+
+	// Followed files are listed in the same form as the named ones
+	for _, directory := range []string{".", root} {
+		var buf bytes.Buffer
+		cfg := &Config{
+			Directory:     directory,
+			Files:         []string{"main.go"},
+			FollowImports: true,
+			NamesOnly:     true,
+			NullSeparated: true,
+			OutputFormat:  OutputFormatXML,
+		}
+		if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		want := "lib/lib.go\x00main.go\x00"
+		if directory == root {
+			want = filepath.Join(root, "lib", "lib.go") + "\x00" + filepath.Join(root, "main.go") + "\x00"
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("Run() in %s = %q, want %q", directory, got, want)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "7d", want: time.Date(2024, 3, 8, 12, 0, 0, 0, time.Local)},
+		{value: "2w", want: time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)},
+		{value: "36h", want: time.Date(2024, 3, 14, 0, 0, 0, 0, time.Local)},
+		{value: "2024-01-01", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-01-01T10:00:00Z", want: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTimeBound(%q) expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTimeBound(%q) unexpected error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTruncationFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("line\n", 50)), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	file := scanner.FileInfo{Path: path, RelPath: "big.txt"}
+
+	tests := []struct {
+		name      string
+		cfg       *Config
+		wantLines int
+	}{
+		{"under the default limit", &Config{}, 50},
+		{"max lines", &Config{MaxLines: 20, TruncateTo: 5}, 5},
+		{"at max lines", &Config{MaxLines: 50, TruncateTo: 5}, 50},
+		{"no truncate", &Config{MaxLines: 20, TruncateTo: 5, NoTruncate: true}, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewFileProcessor(tt.cfg).ProcessFile(file, NewFileFilter(tt.cfg))
+			if len(result.Lines) != tt.wantLines || result.OmittedLines != 50-tt.wantLines {
+				t.Errorf("ProcessFile() = %d shown, %d omitted; want %d, %d",
+					len(result.Lines), result.OmittedLines, tt.wantLines, 50-tt.wantLines)
+			}
+		})
+	}
+
+	truncated := NewFileProcessor(&Config{MaxLines: 20, TruncateTo: 5}).ProcessFile(file, NewFileFilter(&Config{}))
+	if got := newJSONFile(truncated).Notice; got != "... (45 more lines)" {
+		t.Errorf("JSON truncation notice = %q, want the notice shown by other formats", got)
+	}
+
+	app := NewWithWriter(&Config{Directory: ".", MaxLines: 10, TruncateTo: 20, OutputFormat: OutputFormatXML}, io.Discard)
+	if err := app.validateConfig(); err == nil {
+		t.Error("validateConfig() expected an error when --truncate-to exceeds --max-lines")
+	}
+}
+
+func TestXMLOutputIsWellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	output := NewXMLOutput(&buf)
+	ctx := context.Background()
+	cfg := &Config{}
+
+	file := ProcessedFile{
+		Info:         scanner.FileInfo{RelPath: `a "quoted" & <odd>.txt`, Size: 42},
+		FileType:     "text",
+		Lines:        []FilteredLine{{LineNumber: 1, Content: "if a < b && c]]>d {\x01}"}},
+		TotalLines:   3,
+		IsTruncated:  true,
+		OmittedLines: 2,
+	}
+	if err := output.WriteHeader(ctx); err != nil {
+		t.Fatalf("WriteHeader() unexpected error: %v", err)
+	}
+	if err := output.WriteFile(ctx, file, cfg); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := output.WriteFooter(ctx); err != nil {
+		t.Fatalf("WriteFooter() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Files []struct {
+			Path         string `xml:"path,attr"`
+			Size         int64  `xml:"size,attr"`
+			Type         string `xml:"type,attr"`
+			Truncated    bool   `xml:"truncated,attr"`
+			OmittedLines int    `xml:"omittedLines,attr"`
+			Content      string `xml:"content"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not well-formed XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(doc.Files))
+	}
+
+	got := doc.Files[0]
+	if got.Path != file.Info.RelPath || got.Size != 42 || got.Type != "text" || !got.Truncated || got.OmittedLines != 2 {
+		t.Errorf("file attributes = %+v", got)
+	}
+	if want := "\nif a < b && c]]>d {�}\n... (2 more lines)\n"; got.Content != want {
+		t.Errorf("content = %q, want %q", got.Content, want)
+	}
+}
+
+func TestGroupByDir(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"top.txt":      "top\n",
+		"a/one.txt":    "one\n",
+		"a/b/deep.txt": "deep\n",
+		"a/two.txt":    "two\nlines\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(format OutputFormat) string {
+		var buf bytes.Buffer
+		cfg := &Config{Directory: root, Recursive: true, GroupByDir: true, OutputFormat: format}
+		if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	// Each directory's files come together, before its subdirectories
+	markdown := run(OutputFormatMarkdown)
+	order := []string{
+		"## ./", "### top.txt",
+		"## a/\n\n*2 files · 3 lines · 14 B*", "### a/one.txt", "### a/two.txt",
+		"## a/b/", "### a/b/deep.txt",
+	}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(markdown, want)
+		if i <= last {
+			t.Fatalf("markdown output missing %q in order, got:\n%s", want, markdown)
+		}
+		last = i
+	}
+
+	page := run(OutputFormatHTML)
+	for _, want := range []string{
+		`<section id="dir-2">`,
+		`<h2>a/<span class="meta">2 files &middot; 3 lines &middot; 14 B</span></h2>`,
+		`<li><a href="#dir-2"><strong>a/</strong></a><ul>`,
+		`<strong>Files (4)</strong>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html output missing %q", want)
+		}
+	}
+
+	app := NewWithWriter(&Config{Directory: root, GroupByDir: true, OutputFormat: OutputFormatXML}, io.Discard)
+	if err := app.validateConfig(); err == nil {
+		t.Error("validateConfig() expected an error for --group-by-dir with xml output")
+	}
+}
+
+func TestFrontmatterOnly(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"post.md":  "---\ntitle: Hello\ntags: [go, cli]\ndraft: false\n---\n# Hello\n\nBody text.\n",
+		"plain.md": "# No frontmatter\n",
+		"bad.md":   "---\ntitle: [unclosed\n---\n",
+		"main.go":  "package main\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(format OutputFormat) string {
+		var buf bytes.Buffer
+		cfg := &Config{Directory: root, FrontmatterOnly: true, OutputFormat: format}
+		if err := NewWithWriter(cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	var doc struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(run(OutputFormatJSON)), &doc); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	byPath := make(map[string]JSONFile)
+	for _, file := range doc.Files {
+		byPath[file.Path] = file
+	}
+
+	if _, ok := byPath["main.go"]; ok {
+		t.Error("non-markdown files should be skipped")
+	}
+	post := byPath["post.md"]
+	want := map[string]any{"title": "Hello", "tags": []any{"go", "cli"}, "draft": false}
+	if !reflect.DeepEqual(post.Frontmatter, want) {
+		t.Errorf("post.md frontmatter = %v, want %v", post.Frontmatter, want)
+	}
+	if len(post.Lines) != 0 {
+		t.Errorf("post.md should have no content lines in JSON, got %v", post.Lines)
+	}
+	if plain := byPath["plain.md"]; plain.Frontmatter != nil || len(plain.Lines) != 0 {
+		t.Errorf("plain.md should have no frontmatter, got %+v", plain)
+	}
+	if bad := byPath["bad.md"]; bad.Error == nil || !strings.Contains(*bad.Error, "invalid frontmatter") {
+		t.Errorf("bad.md should report invalid frontmatter, got %+v", bad)
+	}
+
+	xmlOut := run(OutputFormatXML)
+	if !strings.Contains(xmlOut, `<frontmatter draft="false" tags="[&#34;go&#34;,&#34;cli&#34;]" title="Hello"/>`) {
+		t.Errorf("XML output missing frontmatter attributes, got:\n%s", xmlOut)
+	}
+	if strings.Contains(xmlOut, "Body text.") {
+		t.Errorf("XML output should omit the document body, got:\n%s", xmlOut)
+	}
+
+	markdown := run(OutputFormatMarkdown)
+	if !strings.Contains(markdown, "```yaml name=\"post.md\"\ntitle: Hello\n") || strings.Contains(markdown, "Body text.") {
+		t.Errorf("markdown output should show just the frontmatter block, got:\n%s", markdown)
+	}
+}
+
+func TestNamesOnly(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":    "hello\n",
+		"sub/b.go": "world\n",
+		"sub/c.go": "hello world\n",
+		"skip.log": "world\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(cfg Config) string {
+		var buf bytes.Buffer
+		cfg.Directory, cfg.Recursive, cfg.OutputFormat = root, true, OutputFormatXML
+		cfg.IgnoreGlobs = []string{"*.log"}
+		if err := NewWithWriter(&cfg, &buf).Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "newline separated",
+			cfg:  Config{NamesOnly: true},
+			want: filepath.Join(root, "a.txt") + "\n" + filepath.Join(root, "sub/b.go") + "\n" + filepath.Join(root, "sub/c.go") + "\n",
+		},
+		{
+			name: "NUL terminated",
+			cfg:  Config{NullSeparated: true, Globs: []string{"*.go"}},
+			want: filepath.Join(root, "sub/b.go") + "\x00" + filepath.Join(root, "sub/c.go") + "\x00",
+		},
+		{
+			name: "content filter lists matching files",
+			cfg:  Config{NamesOnly: true, ContentPattern: "*hello*", RelativeTo: root},
+			want: "a.txt\nsub/c.go\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.cfg); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutputBudget(t *testing.T) {
+	tests := []struct {
+		value      string
+		want       int
+		wantTokens bool
+		wantErr    bool
+	}{
+		{"4096", 4096, false, false},
+		{"200K", 200000, false, false},
+		{"1.5M", 1500000, false, false},
+		{"64KB", 64000, false, false},
+		{"50K tokens", 50000, true, false},
+		{"8kt", 8000, true, false},
+		{"lots", 0, false, true},
+		{"-5K", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, tokens, err := ParseOutputBudget(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutputBudget(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want || tokens != tt.wantTokens {
+				t.Errorf("ParseOutputBudget(%q) = %d, %v; want %d, %v", tt.value, got, tokens, tt.want, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestApplyBudget(t *testing.T) {
+	lines := func(n int) []FilteredLine {
+		result := make([]FilteredLine, n)
+		for i := range result {
+			result[i] = FilteredLine{LineNumber: i + 1, Content: strings.Repeat("x", 19)}
+		}
+		return result
+	}
+	file := func(path string, n int) ProcessedFile {
+		return ProcessedFile{Info: scanner.FileInfo{Path: path, RelPath: path}, Lines: lines(n), TotalLines: n}
+	}
+
+	// Each line weighs 20 bytes and each file about 70 more
+	files := []ProcessedFile{file("big.txt", 100), file("docs/readme.md", 20), file("small.txt", 5)}
+
+	app := New(&Config{MaxOutput: 1000, OutputFormat: OutputFormatXML})
+	got, report := app.applyBudget(slices.Clone(files))
+	if report.full != 2 || !reflect.DeepEqual(report.truncated, []string{"big.txt"}) {
+		t.Fatalf("applyBudget() report = %+v, want small files in full and big.txt truncated", report)
+	}
+	if len(got) != 3 || got[0].Info.RelPath != "big.txt" {
+		t.Fatalf("applyBudget() should keep the original order, got %d files", len(got))
+	}
+	if big := got[0]; !big.IsTruncated || len(big.Lines)+big.OmittedLines != 100 || len(big.Lines) < budgetMinLines {
+		t.Errorf("big.txt = %d lines shown, %d omitted; want a truncated file", len(big.Lines), big.OmittedLines)
+	}
+
+	// Prioritized files get the budget first, even when larger
+	app = New(&Config{MaxOutput: 750, PriorityGlobs: []string{"docs/"}, OutputFormat: OutputFormatXML})
+	got, report = app.applyBudget(slices.Clone(files))
+	if report.full != 2 || !reflect.DeepEqual(report.listed, []string{"big.txt"}) {
+		t.Errorf("applyBudget() with priority report = %+v, want big.txt listed only", report)
+	}
+	if big := got[0]; len(big.Lines) != 0 || big.OmittedLines != 100 {
+		t.Errorf("listed big.txt = %d lines shown, %d omitted; want none shown", len(big.Lines), big.OmittedLines)
+	}
+
+	app = New(&Config{MaxOutput: 100, OutputFormat: OutputFormatXML})
+	got, report = app.applyBudget(slices.Clone(files))
+	if len(got) != 1 || len(report.omitted) != 2 {
+		t.Errorf("applyBudget() with a tiny budget kept %d files, report %+v; want one kept and two omitted", len(got), report)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	after := []string{"a", "b", "C", "d", "e", "f", "g", "h", "i", "j", "k"}
+
+	want := `--- a/x
++++ b/x
+@@ -2,3 +2,3 @@
+ b
+-c
++C
+ d
+@@ -10,1 +10,2 @@
+ j
++k
+`
+	if got := unifiedDiff("a/x", "b/x", before, after, 1); got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant:\n%s", got, want)
+	}
+
+	// Replaying the edit script must rebuild both versions
+	for _, tt := range [][2][]string{
+		{{"x", "a", "b", "c", "a", "b", "b", "a"}, {"c", "b", "a", "b", "a", "c"}},
+		{nil, {"new", "lines"}},
+		{{"old", "lines"}, nil},
+		{{"same"}, {"same"}},
+	} {
+		var gotOld, gotNew []string
+		for _, op := range diffLines(tt[0], tt[1]) {
+			if op.kind != '+' {
+				gotOld = append(gotOld, op.text)
+			}
+			if op.kind != '-' {
+				gotNew = append(gotNew, op.text)
+			}
+		}
+		if !slices.Equal(gotOld, tt[0]) || !slices.Equal(gotNew, tt[1]) {
+			t.Errorf("diffLines(%q, %q) rebuilt %q, %q", tt[0], tt[1], gotOld, gotNew)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	write := func(root string, files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create directory for %s: %v", name, err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+	}
+
+	left, right := t.TempDir(), t.TempDir()
+	write(left, map[string]string{"same.txt": "same\n", "edit.txt": "one\ntwo\n", "sub/gone.txt": "gone\n"})
+	write(right, map[string]string{"same.txt": "same\n", "edit.txt": "one\n2\n", "sub/new.txt": "new\n"})
+
+	diff := func(left, right, format string) string {
+		var buf bytes.Buffer
+		cfg := &Config{Recursive: true, OutputFormat: OutputFormatXML}
+		if err := Diff(context.Background(), cfg, left, right, DiffOptions{Format: format, Context: 3}, &buf); err != nil {
+			t.Fatalf("Diff() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	var report struct {
+		Files    []FileDiff `json:"files"`
+		Added    int        `json:"added"`
+		Removed  int        `json:"removed"`
+		Modified int        `json:"modified"`
+	}
+	if err := json.Unmarshal([]byte(diff(left, right, "json")), &report); err != nil {
+		t.Fatalf("invalid JSON diff: %v", err)
+	}
+	var statuses []string
+	for _, file := range report.Files {
+		statuses = append(statuses, file.Path+" "+file.Status)
+	}
+	if want := []string{"edit.txt modified", "sub/gone.txt removed", "sub/new.txt added"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Diff() files = %v, want %v", statuses, want)
+	}
+	if report.Added != 1 || report.Removed != 1 || report.Modified != 1 {
+		t.Errorf("Diff() counts = %d/%d/%d, want 1/1/1", report.Added, report.Removed, report.Modified)
+	}
+
+	if patch := diff(left, right, DiffFormatPatch); !strings.Contains(patch, "--- a/edit.txt\n+++ b/edit.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n") {
+		t.Errorf("patch output missing the edit hunk, got:\n%s", patch)
+	}
+
+	// A JSON snapshot compares like the directory it was taken of
+	snapshot := filepath.Join(t.TempDir(), "left.json")
+	var buf bytes.Buffer
+	if err := NewWithWriter(&Config{Directory: left, Recursive: true, OutputFormat: OutputFormatJSON}, &buf).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if err := os.WriteFile(snapshot, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	if got, want := diff(snapshot, right, DiffFormatPatch), diff(left, right, DiffFormatPatch); got != want {
+		t.Errorf("snapshot diff =\n%s\nwant:\n%s", got, want)
+	}
+	if got := diff(snapshot, left, DiffFormatPatch); got != "" {
+		t.Errorf("snapshot should match its own directory, got:\n%s", got)
+	}
+
+	// --output sends the diff to a file instead
+	outPath := filepath.Join(t.TempDir(), "out", "changes.patch")
+	var stdout bytes.Buffer
+	cfg := &Config{Recursive: true, OutputFormat: OutputFormatXML, Output: outPath}
+	if err := Diff(context.Background(), cfg, left, right, DiffOptions{Format: DiffFormatPatch, Context: 3}, &stdout); err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nothing should be written to out with --output, got:\n%s", stdout.String())
+	}
+	if data, err := os.ReadFile(outPath); err != nil || string(data) != diff(left, right, DiffFormatPatch) {
+		t.Errorf("output file = %q (%v), want the patch", data, err)
+	}
+}
+
+func TestDiffPatchApplies(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not available")
+	}
+
+	files := func(root string) map[string]string {
+		got := map[string]string{}
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			rel, _ := filepath.Rel(root, path)
+			got[filepath.ToSlash(rel)] = string(data)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", root, err)
+		}
+		return got
+	}
+	write := func(root string, contents map[string]string) {
+		for name, content := range contents {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create directory for %s: %v", name, err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+	}
+
+	left, right := t.TempDir(), t.TempDir()
+	write(left, map[string]string{
+		"gains.txt":   "one\ntwo",
+		"loses.txt":   "one\ntwo\n",
+		"edits.txt":   "one\ntwo",
+		"keeps.txt":   "one\ntwo\nthree",
+		"removed.txt": "gone",
+	})
+	write(right, map[string]string{
+		"gains.txt": "one\ntwo\n",
+		"loses.txt": "one\ntwo",
+		"edits.txt": "one\n2",
+		"keeps.txt": "ONE\ntwo\nthree",
+		"added.txt": "new",
+	})
+
+	var patch bytes.Buffer
+	cfg := &Config{Recursive: true, OutputFormat: OutputFormatXML}
+	if err := Diff(context.Background(), cfg, left, right, DiffOptions{Format: DiffFormatPatch, Context: 3}, &patch); err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if !strings.Contains(patch.String(), "\n"+noNewlineMarker+"\n") {
+		t.Errorf("patch should mark missing final newlines, got:\n%s", patch.String())
+	}
+
+	cmd := exec.Command(git, "apply", "-")
+	cmd.Dir = left
+	cmd.Stdin = &patch
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed: %v\n%s", err, out)
+	}
+	if got, want := files(left), files(right); !reflect.DeepEqual(got, want) {
+		t.Errorf("patched tree = %q, want %q", got, want)
+	}
+}
+
+func TestDiffBinariesAndUnreadableFiles(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	for root, files := range map[string]map[string]string{
+		left:  {"same.bin": "\x00\x01same", "edit.bin": "\x00\x01aaaa", "text.txt": "text\n"},
+		right: {"same.bin": "\x00\x01same", "edit.bin": "\x00\x01bbbb", "text.txt": "text\n"},
+	} {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+	}
+
+	diff := func(left, right string) string {
+		var buf bytes.Buffer
+		cfg := &Config{Recursive: true, OutputFormat: OutputFormatXML, Binary: scanner.BinaryOptions{Strategy: scanner.BinaryDetectionBytes}}
+		if err := Diff(context.Background(), cfg, left, right, DiffOptions{Format: DiffFormatPatch}, &buf); err != nil {
+			t.Fatalf("Diff() unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	// Binaries of the same size are told apart by their content
+	if got, want := diff(left, right), "Binary files a/edit.bin and b/edit.bin differ\n"; got != want {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+
+	// A snapshot without --hash cannot show a binary is unchanged
+	var snapshot bytes.Buffer
+	cfg := &Config{Directory: left, Recursive: true, OutputFormat: OutputFormatJSON, Binary: scanner.BinaryOptions{Strategy: scanner.BinaryDetectionBytes}}
+	if err := NewWithWriter(cfg, &snapshot).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	snapshotPath := filepath.Join(t.TempDir(), "left.json")
+	if err := os.WriteFile(snapshotPath, snapshot.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	if got := diff(snapshotPath, left); !strings.Contains(got, "same.bin differ") {
+		t.Errorf("binary in a snapshot without digests should be reported, got:\n%s", got)
+	}
+
+	// An unreadable file is skipped rather than failing the diff
+	if os.Geteuid() == 0 {
+		return
+	}
+	if err := os.Chmod(filepath.Join(right, "text.txt"), 0); err != nil {
+		t.Fatalf("failed to make text.txt unreadable: %v", err)
+	}
+	if got := diff(left, right); !strings.Contains(got, "--- a/text.txt\n+++ /dev/null\n") {
+		t.Errorf("unreadable text.txt should be left out of the right side, got:\n%s", got)
+	}
+}
@@ -0,0 +1,86 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// filterChanged keeps only the files that differ from Config.ChangedSince in
+// their git repository, including uncommitted and untracked files.
+func (a *App) filterChanged(ctx context.Context, files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+	rootOf := make(map[string]string)
+	changedIn := make(map[string]map[string]bool)
+
+	var result []scanner.FileInfo
+	for _, file := range files {
+		dir := filepath.Dir(absPath(file.Path))
+		root, ok := rootOf[dir]
+		if !ok {
+			root = scanner.FindGitRoot(dir)
+			rootOf[dir] = root
+		}
+		if root == "" {
+			return nil, fmt.Errorf("--changed-since: %s is not inside a git repository", file.Path)
+		}
+
+		changed, ok := changedIn[root]
+		if !ok {
+			var err error
+			changed, err = changedFiles(ctx, root, a.cfg.ChangedSince)
+			if err != nil {
+				return nil, err
+			}
+			changedIn[root] = changed
+		}
+
+		if changed[absPath(file.Path)] {
+			result = append(result, file)
+		}
+	}
+
+	return result, nil
+}
+
+// changedFiles returns the absolute paths of files in the repository at root
+// whose working tree content differs from ref, plus untracked files that are
+// not ignored.
+func changedFiles(ctx context.Context, root, ref string) (map[string]bool, error) {
+	diff, err := gitOutput(ctx, root, "diff", "--name-only", "-z", ref, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", ref, err)
+	}
+	untracked, err := gitOutput(ctx, root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(diff+untracked, "\x00") {
+		if name != "" {
+			changed[filepath.Join(root, filepath.FromSlash(name))] = true
+		}
+	}
+	return changed, nil
+}
+
+// gitOutput runs a git command in dir and returns its standard output.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
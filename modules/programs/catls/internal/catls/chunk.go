@@ -0,0 +1,161 @@
+package catls
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// chunkFileOverhead approximates the per-file markup (path, type, wrapper
+// elements) a formatter adds around a file's content.
+const chunkFileOverhead = 64
+
+// isChunked reports whether output is split by a token or byte budget.
+func (a *App) isChunked() bool {
+	return a.cfg.ChunkTokens > 0 || a.cfg.ChunkBytes > 0
+}
+
+// processAndWriteChunks processes all files and writes them as a sequence of
+// complete, independently consumable documents, each within the configured
+// token or byte budget. With --output each chunk goes to its own numbered
+// file; otherwise chunks are written to the output one after another.
+func (a *App) processAndWriteChunks(ctx context.Context, files []scanner.FileInfo) error {
+	processed, err := a.processFiles(ctx, files)
+	if err != nil {
+		return err
+	}
+
+	chunks := a.chunkFiles(a.fitBudget(processed))
+	for i, chunk := range chunks {
+		if a.cfg.Output != "" {
+			path := a.cfg.Output
+			if len(chunks) > 1 {
+				path = chunkOutputPath(path, i+1)
+			}
+			if err := a.writeChunkFile(ctx, path, chunk); err != nil {
+				return fmt.Errorf("failed to write chunk %d: %w", i+1, err)
+			}
+			continue
+		}
+
+		if len(chunks) > 1 {
+			if i > 0 {
+				fmt.Fprintln(a.out)
+			}
+			fmt.Fprintf(a.out, "--- catls chunk %d of %d ---\n", i+1, len(chunks))
+		}
+
+		output, err := NewOutputFormatter(a.cfg, a.out)
+		if err != nil {
+			return err
+		}
+		if err := a.writeChunk(ctx, output, chunk); err != nil {
+			return fmt.Errorf("failed to write chunk %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// writeChunkFile writes one chunk as a complete document to its own file.
+func (a *App) writeChunkFile(ctx context.Context, path string, files []ProcessedFile) error {
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+
+	compressed, err := a.compressWriter(file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	buffered := bufio.NewWriter(compressed)
+	output, err := NewOutputFormatter(a.cfg, buffered)
+	if err == nil {
+		err = a.writeChunk(ctx, output, files)
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if closeErr := compressed.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeChunk writes one chunk as a complete document with its own header and
+// footer.
+func (a *App) writeChunk(ctx context.Context, output OutputFormatter, files []ProcessedFile) error {
+	if err := output.WriteHeader(ctx); err != nil {
+		return fmt.Errorf("failed to write output header: %w", err)
+	}
+
+	for _, file := range files {
+		if err := output.WriteFile(ctx, file, a.cfg); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", file.Info.RelPath, err)
+		}
+	}
+
+	if err := output.WriteFooter(ctx); err != nil {
+		return fmt.Errorf("failed to write output footer: %w", err)
+	}
+
+	return nil
+}
+
+// chunkFiles groups files into sequential chunks whose estimated size stays
+// within the budget. Files are never split across chunks; a file that is
+// larger than the budget on its own gets a chunk to itself.
+func (a *App) chunkFiles(files []ProcessedFile) [][]ProcessedFile {
+	budget := a.cfg.ChunkBytes
+	if a.cfg.ChunkTokens > 0 {
+		budget = a.cfg.ChunkTokens
+	}
+
+	var chunks [][]ProcessedFile
+	var current []ProcessedFile
+	used := 0
+	for _, file := range files {
+		weight := a.chunkWeight(file)
+		if len(current) > 0 && used+weight > budget {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		current = append(current, file)
+		used += weight
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// chunkWeight estimates how much of the chunk budget a file will consume,
+// in tokens or bytes depending on which budget is configured.
+func (a *App) chunkWeight(file ProcessedFile) int {
+	return outputWeight(file, a.cfg.ChunkTokens > 0)
+}
+
+// outputWeight estimates how much output a file produces, in tokens or
+// bytes.
+func outputWeight(file ProcessedFile, tokens bool) int {
+	if tokens {
+		return chunkFileOverhead/charsPerToken + EstimateTokens(file.Info.RelPath) + countFileTokens(file)
+	}
+
+	size := chunkFileOverhead + len(file.Info.RelPath)
+	if file.Error != nil {
+		size += len(file.Error.Error())
+	}
+	for _, line := range file.Lines {
+		size += len(line.Content) + 1
+	}
+	return size + len(file.HexDump())
+}
@@ -0,0 +1,204 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sniffLen is how much of a file is read for content-based type detection.
+const sniffLen = 512
+
+// interpreterTypes maps shebang interpreters to file types.
+var interpreterTypes = map[string]string{
+	"sh":      "bash",
+	"bash":    "bash",
+	"zsh":     "bash",
+	"dash":    "bash",
+	"ksh":     "bash",
+	"ash":     "bash",
+	"python":  "python",
+	"pypy":    "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"make":    "makefile",
+}
+
+// modeTypes maps vim filetypes and emacs major modes that differ from file
+// extensions to file types.
+var modeTypes = map[string]string{
+	"shell-script": "bash",
+	"zsh":          "bash",
+	"make":         "makefile",
+	"c++":          "cpp",
+	"js":           "javascript",
+	"python3":      "python",
+}
+
+var (
+	// vimModeline matches "vim: set ft=python:" and similar.
+	vimModeline = regexp.MustCompile(`\b(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([\w+-]+)`)
+	// emacsModeline matches "-*- mode: python -*-" and "-*- python -*-".
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+)
+
+// ContentTypeDetector detects file types from well-known file names and
+// extensions, and for files that have neither, from their first bytes: a
+// shebang line, an editor modeline, or MIME magic.
+type ContentTypeDetector struct {
+	extensions ExtensionTypeDetector
+}
+
+// DetectType implements TypeDetector.
+func (d *ContentTypeDetector) DetectType(filePath string) string {
+	if fileType := detectTypeByName(filepath.Base(filePath)); fileType != "" {
+		return fileType
+	}
+	if fileType := d.extensions.DetectType(filePath); fileType != "" {
+		return fileType
+	}
+
+	head, err := readHead(filePath)
+	if err != nil {
+		return ""
+	}
+	return detectTypeByContent(head)
+}
+
+// detectTypeByName recognizes files whose name rather than extension gives
+// their type, such as Dockerfile.dev or GNUmakefile.
+func detectTypeByName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == "dockerfile" || lower == "containerfile" ||
+		strings.HasPrefix(lower, "dockerfile.") || strings.HasPrefix(lower, "containerfile."):
+		return "dockerfile"
+	case lower == "makefile" || lower == "gnumakefile" || lower == "bsdmakefile" ||
+		strings.HasPrefix(lower, "makefile."):
+		return "makefile"
+	case lower == ".bashrc" || lower == ".bash_profile" || lower == ".zshrc" || lower == ".profile":
+		return "bash"
+	default:
+		return ""
+	}
+}
+
+// detectTypeByContent detects a file type from the first bytes of a file.
+func detectTypeByContent(head []byte) string {
+	firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+	if fileType := detectShebang(string(firstLine)); fileType != "" {
+		return fileType
+	}
+
+	lines := bufio.NewScanner(bytes.NewReader(head))
+	for i := 0; i < 5 && lines.Scan(); i++ {
+		if fileType := detectModeline(lines.Text()); fileType != "" {
+			return fileType
+		}
+	}
+
+	mime, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	switch mime {
+	case "text/html":
+		return "html"
+	case "text/xml":
+		return "xml"
+	default:
+		return ""
+	}
+}
+
+// detectShebang returns the file type of a "#!" line's interpreter, looking
+// through env and its options. Version suffixes like python3.12 are ignored.
+func detectShebang(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+
+	return interpreterTypes[strings.TrimRight(interpreter, "0123456789.")]
+}
+
+// detectModeline returns the file type named by a vim or emacs modeline.
+func detectModeline(line string) string {
+	var mode string
+	if match := vimModeline.FindStringSubmatch(line); match != nil {
+		mode = match[1]
+	} else if match := emacsModeline.FindStringSubmatch(line); match != nil {
+		mode = emacsMode(match[1])
+	}
+
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		return ""
+	}
+	if fileType, ok := modeTypes[mode]; ok {
+		return fileType
+	}
+	if fileType, ok := extensionTypes[mode]; ok {
+		return fileType
+	}
+	for _, fileType := range extensionTypes {
+		if fileType == mode {
+			return fileType
+		}
+	}
+	return ""
+}
+
+// emacsMode extracts the major mode from the settings between "-*-" markers,
+// which are either a bare mode name or "key: value" pairs separated by ";".
+func emacsMode(settings string) string {
+	if !strings.Contains(settings, ":") {
+		return strings.TrimSuffix(settings, "-mode")
+	}
+
+	for _, setting := range strings.Split(settings, ";") {
+		key, value, _ := strings.Cut(setting, ":")
+		if strings.EqualFold(strings.TrimSpace(key), "mode") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// readHead returns up to sniffLen bytes from the start of a file.
+func readHead(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", filePath, closeErr)
+		}
+	}()
+
+	return io.ReadAll(io.LimitReader(file, sniffLen))
+}
@@ -0,0 +1,544 @@
+package catls
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Statuses of a file in catls diff.
+const (
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffModified = "modified"
+)
+
+// DiffFormatPatch is the catls diff output format of a plain unified diff,
+// which patch and git apply accept.
+const DiffFormatPatch = "patch"
+
+// noNewlineMarker follows the last line of a file without a final newline
+// in a unified diff, so patch and git apply restore it as it was.
+const noNewlineMarker = `\ No newline at end of file`
+
+// diffMaxEdits bounds the work spent aligning two versions of a file. Files
+// that differ by more lines than this are shown as replaced outright.
+const diffMaxEdits = 2000
+
+// DiffOptions configures catls diff.
+type DiffOptions struct {
+	Format  string // patch, xml, json, or markdown
+	Context int    // Unchanged lines shown around each change
+}
+
+// FileDiff is one file that differs between the two sides of catls diff.
+type FileDiff struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Binary bool   `json:"binary,omitempty"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// diffEntry is one file on a side of catls diff.
+type diffEntry struct {
+	lines     []string
+	binary    bool
+	sum       string // SHA-256 of a binary file, when known
+	size      int64  // Size of a binary file, when known
+	noNewline bool   // Whether the last line has no newline after it
+}
+
+// sameBinary reports whether two binary files are known to be the same.
+// Files whose digests were not recorded, as in snapshots taken without
+// --hash, cannot be told apart and are reported as differing.
+func sameBinary(a, b diffEntry) bool {
+	if !a.binary || !b.binary || a.sum == "" || b.sum == "" {
+		return false
+	}
+	if a.size != 0 && b.size != 0 && a.size != b.size {
+		return false
+	}
+	return a.sum == b.sum
+}
+
+// patchLines returns the lines compared for the entry, with the no newline
+// marker attached to a last line that lacks one so that the line differs
+// from the same text with a newline and prints the marker after it.
+func (e diffEntry) patchLines() []string {
+	if !e.noNewline || len(e.lines) == 0 {
+		return e.lines
+	}
+	lines := slices.Clone(e.lines)
+	lines[len(lines)-1] += "\n" + noNewlineMarker
+	return lines
+}
+
+// Diff compares two directory trees, or two snapshots written with
+// --format json, and writes the files added, removed, and modified between
+// left and right to out, or to the file named by cfg.Output, with unified
+// diffs of text files. Directories are scanned with the filters in cfg.
+func Diff(ctx context.Context, cfg *Config, left, right string, opts DiffOptions, out io.Writer) error {
+	switch OutputFormat(opts.Format) {
+	case DiffFormatPatch, OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown:
+	default:
+		return fmt.Errorf("unsupported diff format: %s (supported: %s, %s, %s, %s)",
+			opts.Format, DiffFormatPatch, OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown)
+	}
+	if opts.Context < 0 {
+		return fmt.Errorf("--context must not be negative")
+	}
+
+	before, err := loadDiffSide(ctx, cfg, left)
+	if err != nil {
+		return err
+	}
+	after, err := loadDiffSide(ctx, cfg, right)
+	if err != nil {
+		return err
+	}
+
+	diffs := compareTrees(before, after, opts.Context)
+	if cfg.Output == "" {
+		return writeDiff(out, opts.Format, diffs)
+	}
+
+	file, err := createOutputFile(cfg.Output)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(file)
+	err = writeDiff(buffered, opts.Format, diffs)
+	if flushErr := buffered.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to write output: %w", flushErr)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	return err
+}
+
+// loadDiffSide reads one side of a diff: a JSON snapshot file or a
+// directory to scan.
+func loadDiffSide(ctx context.Context, cfg *Config, path string) (map[string]diffEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadDiffSnapshot(path)
+	}
+
+	// Every line is needed to compare files, and the output is produced here
+	// rather than by a formatter; the output file stays set so the scan
+	// leaves it out
+	side := *cfg
+	side.Directory = path
+	side.Files = nil
+	side.Head, side.Tail = 0, 0
+	side.NoTruncate = true
+	side.Hash = false
+	side.CountTokens = false
+	side.Cache, side.ChangedOnly = false, false
+	side.MaxOutput = 0
+	side.OutputFormat = OutputFormatJSON
+
+	app := NewWithWriter(&side, io.Discard)
+	if err := app.validateConfig(); err != nil {
+		return nil, err
+	}
+	defer app.removeArchives()
+
+	files, err := app.scanTargets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", path, err)
+	}
+	processed, err := app.processFiles(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files that cannot be read are left out of the comparison
+	entries := make(map[string]diffEntry, len(processed))
+	for _, file := range processed {
+		entry, err := newDiffEntry(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", file.Info.Path, err)
+			continue
+		}
+		entries[file.Info.RelPath] = entry
+	}
+	return entries, nil
+}
+
+// newDiffEntry returns the diff entry of a file read from a directory.
+func newDiffEntry(file ProcessedFile) (diffEntry, error) {
+	if file.Error != nil {
+		return diffEntry{}, file.Error
+	}
+
+	entry := diffEntry{binary: file.Info.IsBinary}
+	var err error
+	if entry.binary {
+		entry.size = file.Info.Size
+		entry.sum, err = hashFile(file.Info.Path)
+		return entry, err
+	}
+
+	entry.lines = make([]string, len(file.Lines))
+	for i, line := range file.Lines {
+		entry.lines[i] = line.Content
+	}
+	entry.noNewline, err = lacksFinalNewline(file.Info.Path)
+	return entry, err
+}
+
+// lacksFinalNewline reports whether a non-empty file does not end in a
+// newline.
+func lacksFinalNewline(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// loadDiffSnapshot reads the files of a snapshot written with --format json.
+// Snapshots hold only the lines they displayed, so files truncated when the
+// snapshot was taken are compared as truncated, and every file is taken to
+// end in a newline.
+func loadDiffSnapshot(path string) (map[string]diffEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s is not a catls JSON snapshot: %w", path, err)
+	}
+
+	entries := make(map[string]diffEntry, len(snapshot.Files))
+	for _, file := range snapshot.Files {
+		if file.Error != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s, unreadable in %s: %s\n", file.Path, path, *file.Error)
+			continue
+		}
+		entry := diffEntry{binary: file.Binary, sum: file.SHA256, size: file.Size}
+		for _, line := range file.Lines {
+			entry.lines = append(entry.lines, line.Content)
+		}
+		entries[file.Path] = entry
+	}
+	return entries, nil
+}
+
+// compareTrees lists the files that differ between two sides, in path order.
+func compareTrees(before, after map[string]diffEntry, contextLines int) []FileDiff {
+	paths := make(map[string]bool)
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+
+	var diffs []FileDiff
+	for path := range paths {
+		old, inBefore := before[path]
+		cur, inAfter := after[path]
+
+		diff := FileDiff{Path: path, Binary: old.binary || cur.binary}
+		switch {
+		case !inBefore:
+			diff.Status = DiffAdded
+		case !inAfter:
+			diff.Status = DiffRemoved
+		case diff.Binary:
+			if sameBinary(old, cur) {
+				continue
+			}
+			diff.Status = DiffModified
+		default:
+			if slices.Equal(old.lines, cur.lines) && old.noNewline == cur.noNewline {
+				continue
+			}
+			diff.Status = DiffModified
+		}
+
+		oldName, newName := "a/"+path, "b/"+path
+		if !inBefore {
+			oldName = "/dev/null"
+		}
+		if !inAfter {
+			newName = "/dev/null"
+		}
+		if diff.Binary {
+			diff.Diff = fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName)
+		} else {
+			diff.Diff = unifiedDiff(oldName, newName, old.patchLines(), cur.patchLines(), contextLines)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+// writeDiff writes the differing files in the given format.
+func writeDiff(out io.Writer, format string, diffs []FileDiff) error {
+	counts := map[string]int{}
+	for _, diff := range diffs {
+		counts[diff.Status]++
+	}
+
+	switch OutputFormat(format) {
+	case OutputFormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Files    []FileDiff `json:"files"`
+			Added    int        `json:"added"`
+			Removed  int        `json:"removed"`
+			Modified int        `json:"modified"`
+		}{
+			Files:    append([]FileDiff{}, diffs...),
+			Added:    counts[DiffAdded],
+			Removed:  counts[DiffRemoved],
+			Modified: counts[DiffModified],
+		})
+
+	case OutputFormatXML:
+		fmt.Fprintf(out, "<diff added=\"%d\" removed=\"%d\" modified=\"%d\">\n",
+			counts[DiffAdded], counts[DiffRemoved], counts[DiffModified])
+		for _, diff := range diffs {
+			fmt.Fprintf(out, "<file path=\"%s\" status=\"%s\"", xmlEscape(diff.Path), diff.Status)
+			if diff.Binary {
+				fmt.Fprint(out, " binary=\"true\"")
+			}
+			fmt.Fprintf(out, ">\n<![CDATA[%s]]>\n</file>\n", xmlCDATA(diff.Diff))
+		}
+		fmt.Fprintln(out, "</diff>")
+
+	case OutputFormatMarkdown:
+		fmt.Fprintf(out, "# Diff\n\n%d added, %d removed, %d modified\n",
+			counts[DiffAdded], counts[DiffRemoved], counts[DiffModified])
+		for _, diff := range diffs {
+			fmt.Fprintf(out, "\n## %s (%s)\n\n```diff\n%s```\n", diff.Path, diff.Status, diff.Diff)
+		}
+
+	default:
+		for _, diff := range diffs {
+			fmt.Fprint(out, diff.Diff)
+		}
+	}
+	return nil
+}
+
+// diffOp is one line of an edit script: kept (' '), removed ('-'), or added
+// ('+'), with its position in the old and new versions before the edit.
+type diffOp struct {
+	kind byte
+	text string
+	old  int
+	new  int
+}
+
+// unifiedDiff returns the unified diff between two versions of a file, with
+// contextLines unchanged lines around each change.
+func unifiedDiff(oldName, newName string, a, b []string, contextLines int) string {
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are close
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		end := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*contextLines {
+				break
+			}
+		}
+
+		from := max(first-contextLines, start)
+		to := min(end+contextLines, len(ops))
+		hunk := ops[from:to]
+
+		oldLen, newLen := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		oldStart, newStart := hunk[0].old, hunk[0].new
+		if oldLen > 0 {
+			oldStart++
+		}
+		if newLen > 0 {
+			newStart++
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range hunk {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+
+	return sb.String()
+}
+
+// diffLines computes a shortest edit script from a to b with Myers'
+// algorithm, after setting aside the common prefix and suffix. Versions
+// that differ by more than diffMaxEdits lines are treated as replaced.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := range prefix {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], old: i, new: i})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	middle := myersDiff(midA, midB)
+	if middle == nil {
+		for i, line := range midA {
+			middle = append(middle, diffOp{kind: '-', text: line, old: i, new: 0})
+		}
+		for i, line := range midB {
+			middle = append(middle, diffOp{kind: '+', text: line, old: len(midA), new: i})
+		}
+	}
+	for _, op := range middle {
+		op.old += prefix
+		op.new += prefix
+		ops = append(ops, op)
+	}
+
+	for i := range suffix {
+		oldIndex, newIndex := len(a)-suffix+i, len(b)-suffix+i
+		ops = append(ops, diffOp{kind: ' ', text: a[oldIndex], old: oldIndex, new: newIndex})
+	}
+	return ops
+}
+
+// myersDiff returns the edit script from a to b, or nil if it needs more
+// than diffMaxEdits edits.
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return []diffOp{}
+	}
+
+	limit := min(n+m, diffMaxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		// Round d only reads diagonals -d-1 to d+1 of the previous round
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return myersBacktrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// myersBacktrack walks the saved rounds of myersDiff back from the end of
+// both versions to recover the edit script. Round d saved diagonals -d-1 to
+// d+1.
+func myersBacktrack(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', text: a[x], old: x, new: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{kind: '+', text: b[prevY], old: prevX, new: prevY})
+			} else {
+				ops = append(ops, diffOp{kind: '-', text: a[prevX], old: prevX, new: prevY})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,76 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// newTextReader returns a reader of r's content as UTF-8, decoding UTF-16
+// text that starts with a byte order mark. Other content is passed through.
+func newTextReader(r io.Reader) io.Reader {
+	reader := bufio.NewReader(r)
+	bom, _ := reader.Peek(2)
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.Equal(bom, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.Equal(bom, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return reader
+	}
+
+	_, _ = reader.Discard(2)
+	return &utf16Reader{r: reader, order: order}
+}
+
+// utf16Reader decodes a UTF-16 byte stream into UTF-8.
+type utf16Reader struct {
+	r       io.Reader
+	order   binary.ByteOrder
+	decoded []byte // UTF-8 output not yet returned
+	odd     []byte // Trailing byte of an incomplete code unit
+	high    rune   // Pending high surrogate awaiting its pair
+	err     error
+}
+
+// Read implements io.Reader.
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.decoded) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+
+		raw := make([]byte, 4096)
+		n, err := u.r.Read(raw)
+		data := append(u.odd, raw[:n]...)
+		u.odd = nil
+		if len(data)%2 == 1 {
+			u.odd = data[len(data)-1:]
+			data = data[:len(data)-1]
+		}
+
+		for i := 0; i < len(data); i += 2 {
+			r := rune(u.order.Uint16(data[i:]))
+			switch {
+			case u.high != 0:
+				r = utf16.DecodeRune(u.high, r)
+				u.high = 0
+			case r >= 0xD800 && r < 0xDC00:
+				u.high = r
+				continue
+			}
+			u.decoded = utf8.AppendRune(u.decoded, r)
+		}
+		u.err = err
+	}
+
+	n := copy(p, u.decoded)
+	u.decoded = u.decoded[n:]
+	return n, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
 )
@@ -11,6 +13,8 @@ import (
 // FileFilter handles file and content filtering.
 type FileFilter struct {
 	contentPattern *regexp.Regexp
+	multiline      bool
+	invert         bool
 }
 
 // FilteredLine represents a line with its original line number.
@@ -21,12 +25,14 @@ type FilteredLine struct {
 
 // NewFileFilter creates a new file filter.
 func NewFileFilter(cfg *Config) *FileFilter {
-	filter := &FileFilter{}
+	filter := &FileFilter{
+		multiline: cfg.Multiline,
+		invert:    cfg.InvertMatch,
+	}
 	
 	// Compile content pattern if provided
-	if cfg.ContentPattern != "" {
-		regexPattern := scanner.WildcardToRegex(cfg.ContentPattern)
-		if compiled, err := regexp.Compile(regexPattern); err == nil {
+	if expr := cfg.ContentExpression(); expr != "" {
+		if compiled, err := regexp.Compile(expr); err == nil {
 			filter.contentPattern = compiled
 		}
 	}
@@ -44,55 +50,92 @@ func (f *FileFilter) ShouldIncludeFile(file scanner.FileInfo, cfg *Config) bool
 		return false
 	}
 
-	// Check ignore patterns first
-	allIgnoreGlobs := cfg.AllIgnoreGlobs()
-	for _, pattern := range allIgnoreGlobs {
-		if scanner.MatchesGlobPattern(file.RelPath, pattern) {
-			if cfg.Debug {
-				fmt.Fprintf(os.Stderr, "Debug: Ignoring file: %s\n", file.RelPath)
-			}
-			return false
+	// Only markdown files have frontmatter to show
+	if cfg.FrontmatterOnly && !isMarkdownFile(file.RelPath) {
+		if cfg.Debug {
+			fmt.Fprintf(os.Stderr, "Debug: Skipping non-markdown file: %s\n", file.RelPath)
 		}
+		return false
 	}
 
-	// Check include patterns
-	if len(cfg.Globs) == 0 {
-		return true // Include everything if no specific patterns
+	// Check ignore patterns first; a later "!pattern" re-includes a file
+	if scanner.SelectedByGlobs(file.RelPath, cfg.AllIgnoreGlobs(), false) {
+		if cfg.Debug {
+			fmt.Fprintf(os.Stderr, "Debug: Ignoring file: %s\n", file.RelPath)
+		}
+		return false
 	}
 
-	for _, pattern := range cfg.Globs {
-		if scanner.MatchesGlobPattern(file.RelPath, pattern) {
-			return true
-		}
+	// Check include patterns; explicitly named files are always included
+	if len(cfg.Globs) == 0 || file.Explicit {
+		return true // Include everything if no specific patterns
 	}
 
-	return false
+	// With only negated patterns, everything else is included
+	return scanner.SelectedByGlobs(file.RelPath, cfg.Globs, !scanner.HasPositiveGlob(cfg.Globs))
 }
 
 // FilterContent filters file content based on pattern.
 func (f *FileFilter) FilterContent(lines []string) []FilteredLine {
 	var result []FilteredLine
 
-	if f.contentPattern == nil {
-		// No pattern - return all lines
-		for i, line := range lines {
+	if f.contentPattern != nil && f.multiline {
+		return f.filterMultiline(lines)
+	}
+
+	for i, line := range lines {
+		if f.MatchLine(line) {
 			result = append(result, FilteredLine{
 				LineNumber: i + 1,
 				Content:    line,
 			})
 		}
-		return result
 	}
 
-	// Filter lines matching pattern
+	return result
+}
+
+// MatchLine reports whether a single line passes the content filter: every
+// line passes without a pattern, otherwise lines matching the pattern (or not
+// matching, when inverted) do. Multiline patterns need the whole file and go
+// through FilterContent instead.
+func (f *FileFilter) MatchLine(line string) bool {
+	return f.contentPattern == nil || f.contentPattern.MatchString(line) != f.invert
+}
+
+// filterMultiline matches the pattern against the whole file so matches can
+// span lines, and keeps every line that overlaps a match (or every line that
+// does not, when inverted).
+func (f *FileFilter) filterMultiline(lines []string) []FilteredLine {
+	// Byte offset at which each line starts in the joined content
+	starts := make([]int, len(lines))
+	offset := 0
 	for i, line := range lines {
-		if f.contentPattern.MatchString(line) {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+
+	keep := make([]bool, len(lines))
+	content := strings.Join(lines, "\n")
+	for _, match := range f.contentPattern.FindAllStringIndex(content, -1) {
+		first := sort.SearchInts(starts, match[0]+1) - 1
+		last := first
+		if match[1] > match[0] {
+			last = sort.SearchInts(starts, match[1]) - 1
+		}
+		for i := first; i <= last && i < len(lines); i++ {
+			keep[i] = true
+		}
+	}
+
+	var result []FilteredLine
+	for i, line := range lines {
+		if keep[i] != f.invert {
 			result = append(result, FilteredLine{
 				LineNumber: i + 1,
 				Content:    line,
 			})
 		}
 	}
-
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,319 @@
+package catls
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// scriptExtensions are the TypeScript and JavaScript extensions tried, in
+// order, when resolving an extensionless relative import.
+var scriptExtensions = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"}
+
+var (
+	scriptImportPattern  = regexp.MustCompile(`(?:import|export)\s[^'"]*?from\s*['"]([^'"]+)['"]`)
+	scriptSideEffect     = regexp.MustCompile(`(?m)^\s*import\s*['"]([^'"]+)['"]`)
+	scriptRequirePattern = regexp.MustCompile(`(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+	pythonImportPattern  = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([^\n#]+)`)
+	pythonFromPattern    = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\.*[\w.]*)[ \t]+import[ \t]+(\([^)]*\)|[^\n#]*)`)
+	goModulePattern      = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+)
+
+// followImports extends files with the local files their Go, Python, and
+// TypeScript/JavaScript sources import, transitively. Imports that resolve
+// outside the file system, such as the standard library or installed
+// packages, are ignored.
+func (a *App) followImports(files []scanner.FileInfo) []scanner.FileInfo {
+	base := a.cfg.Directory
+	if info, err := os.Stat(base); err == nil && !info.IsDir() {
+		base = filepath.Dir(base)
+	}
+	cfg := a.scannerConfig(base)
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		seen[absPath(file.Path)] = true
+	}
+
+	result := append([]scanner.FileInfo{}, files...)
+	queue := append([]scanner.FileInfo{}, files...)
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if file.IsBinary {
+			continue
+		}
+
+		deps, err := localImports(absPath(file.Path))
+		if err != nil {
+			if a.cfg.Debug {
+				fmt.Fprintf(os.Stderr, "Debug: Cannot follow imports of %s: %v\n", file.RelPath, err)
+			}
+			continue
+		}
+
+		for _, dep := range deps {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+
+			// Imports resolve to absolute paths; followed files take the
+			// form of the directory argument, like the files listed from it
+			info, err := a.scanner.ScanFile(pathLike(dep, base), cfg)
+			if err != nil {
+				continue
+			}
+			result = append(result, info)
+			queue = append(queue, info)
+		}
+	}
+
+	return result
+}
+
+// localImports returns the absolute paths of the local files imported by the
+// source file at path, judged by its extension.
+func localImports(path string) ([]string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".go":
+		return goImports(path)
+	case ext == ".py":
+		return pythonImports(path)
+	case slices.Contains(scriptExtensions, ext):
+		return scriptImports(path)
+	}
+	return nil, nil
+}
+
+// goImports returns the other files of a Go file's package plus the files of
+// every package it imports from its own module.
+func goImports(path string) ([]string, error) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := goPackageFiles(filepath.Dir(path), strings.HasSuffix(path, "_test.go"))
+
+	root, module := goModule(filepath.Dir(path))
+	if module == "" {
+		return deps, nil
+	}
+	for _, spec := range parsed.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		rest, ok := strings.CutPrefix(importPath, module)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			continue
+		}
+		deps = append(deps, goPackageFiles(filepath.Join(root, filepath.FromSlash(rest)), false)...)
+	}
+	return deps, nil
+}
+
+// goPackageFiles lists the Go source files in dir, including tests only when
+// tests is set.
+func goPackageFiles(dir string, tests bool) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") && !tests {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// goModule finds the go.mod governing dir and returns its directory and
+// module path, or empty strings outside a module.
+func goModule(dir string) (string, string) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			if match := goModulePattern.FindSubmatch(data); match != nil {
+				return dir, string(match[1])
+			}
+			return dir, ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// pythonImports resolves a Python file's import and from-import statements
+// to module files. Relative imports are resolved from the file's package;
+// absolute ones from the file's directory and its parents up to the
+// repository root.
+func pythonImports(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := string(data)
+	dir := filepath.Dir(path)
+
+	var modules []string
+	for _, match := range pythonImportPattern.FindAllStringSubmatch(source, -1) {
+		modules = append(modules, splitPythonNames(match[1])...)
+	}
+	for _, match := range pythonFromPattern.FindAllStringSubmatch(source, -1) {
+		module := match[1]
+		modules = append(modules, module)
+		// Imported names may be submodules of the package
+		for _, name := range splitPythonNames(match[2]) {
+			if strings.HasSuffix(module, ".") {
+				modules = append(modules, module+name)
+			} else {
+				modules = append(modules, module+"."+name)
+			}
+		}
+	}
+
+	roots := pythonRoots(dir)
+	var deps []string
+	for _, module := range modules {
+		if dep := resolvePythonModule(module, dir, roots); dep != "" {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// splitPythonNames splits a comma-separated import list, dropping aliases,
+// parentheses, and star imports.
+func splitPythonNames(list string) []string {
+	list = strings.Trim(strings.TrimSpace(list), "()")
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || fields[0] == "*" {
+			continue
+		}
+		names = append(names, fields[0])
+	}
+	return names
+}
+
+// pythonRoots returns the directories absolute imports may be resolved
+// from: dir and its parents up to the enclosing git repository root, or
+// just dir outside a repository.
+func pythonRoots(dir string) []string {
+	top := scanner.FindGitRoot(dir)
+	if top == "" {
+		return []string{dir}
+	}
+
+	var roots []string
+	for {
+		roots = append(roots, dir)
+		parent := filepath.Dir(dir)
+		if dir == top || parent == dir {
+			return roots
+		}
+		dir = parent
+	}
+}
+
+// resolvePythonModule maps a dotted module name to its .py or package
+// __init__.py file, or returns an empty string if it is not local.
+func resolvePythonModule(module, dir string, roots []string) string {
+	name := strings.TrimLeft(module, ".")
+	if level := len(module) - len(name); level > 0 {
+		for range level - 1 {
+			dir = filepath.Dir(dir)
+		}
+		roots = []string{dir}
+	}
+
+	rel := filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))
+	for _, root := range roots {
+		candidates := []string{filepath.Join(root, rel+".py"), filepath.Join(root, rel, "__init__.py")}
+		if name == "" {
+			candidates = []string{filepath.Join(root, "__init__.py")}
+		}
+		for _, candidate := range candidates {
+			if isRegularFile(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// scriptImports resolves the relative import, export-from, require, and
+// dynamic import specifiers of a TypeScript or JavaScript file.
+func scriptImports(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := string(data)
+
+	var deps []string
+	for _, pattern := range []*regexp.Regexp{scriptImportPattern, scriptSideEffect, scriptRequirePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(source, -1) {
+			spec := match[1]
+			if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+				continue
+			}
+			if dep := resolveScriptImport(filepath.Join(filepath.Dir(path), filepath.FromSlash(spec))); dep != "" {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// resolveScriptImport finds the file a relative specifier refers to: the
+// path itself, the path with a script extension, an index file inside it, or
+// for a .js specifier the TypeScript source it is compiled from.
+func resolveScriptImport(target string) string {
+	candidates := []string{target}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, target+ext)
+	}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, filepath.Join(target, "index"+ext))
+	}
+	if stem, ok := strings.CutSuffix(target, ".js"); ok {
+		candidates = append(candidates, stem+".ts", stem+".tsx")
+	}
+
+	for _, candidate := range candidates {
+		if isRegularFile(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// isRegularFile reports whether path exists and is a regular file.
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
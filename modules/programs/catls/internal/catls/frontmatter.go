@@ -0,0 +1,131 @@
+package catls
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isMarkdownFile reports whether a file is markdown judged by its extension,
+// the files --frontmatter-only reads.
+func isMarkdownFile(filePath string) bool {
+	return (&ExtensionTypeDetector{}).DetectType(filePath) == "markdown"
+}
+
+// readFrontmatter reads the YAML frontmatter block that opens a markdown
+// file between "---" lines. It returns the block's lines, numbered as in the
+// file, and its parsed fields; a file without frontmatter has neither. Only
+// the frontmatter itself is read, however long the document is.
+func readFrontmatter(filePath string) ([]FilteredLine, map[string]any, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(newTextReader(file))
+	readLine := func() (string, bool, error) {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			err = nil
+			if line == "" {
+				return "", false, nil
+			}
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), err == nil, err
+	}
+
+	// A UTF-8 byte order mark may precede the opening delimiter
+	if first, ok, err := readLine(); !ok || strings.TrimSpace(strings.TrimPrefix(first, "\ufeff")) != "---" {
+		return nil, nil, err
+	}
+
+	var lines []FilteredLine
+	var source []string
+	for {
+		line, ok, err := readLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("unterminated frontmatter")
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "---" || trimmed == "..." {
+			break
+		}
+		lines = append(lines, FilteredLine{LineNumber: len(lines) + 2, Content: line})
+		source = append(source, line)
+	}
+
+	fields := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(strings.Join(source, "\n")), &fields); err != nil {
+		return nil, nil, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	return lines, fields, nil
+}
+
+// frontmatterAttrs renders frontmatter fields as XML attributes in key order.
+// Keys are made into valid XML names, and values other than scalars are
+// written as JSON.
+func frontmatterAttrs(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		name := xmlName(key)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fmt.Fprintf(&sb, " %s=\"%s\"", name, xmlEscape(frontmatterValue(fields[key])))
+	}
+	return sb.String()
+}
+
+// frontmatterValue formats a frontmatter value as text.
+func frontmatterValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// xmlName turns a frontmatter key into a valid XML attribute name by
+// replacing disallowed characters with underscores.
+func xmlName(key string) string {
+	name := []rune(key)
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r) && r != '-' && r != '.') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 {
+		return "_"
+	}
+	return string(name)
+}
@@ -0,0 +1,213 @@
+package catls
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// commentSyntax describes how comments are written in a language.
+type commentSyntax struct {
+	line       []string // Prefixes that start a comment running to end of line
+	blockStart string   // Opening delimiter of a block comment
+	blockEnd   string   // Closing delimiter of a block comment
+}
+
+var (
+	cStyleComments   = commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashComments     = commentSyntax{line: []string{"#"}}
+	markupComments   = commentSyntax{blockStart: "<!--", blockEnd: "-->"}
+	languageComments = map[string]commentSyntax{
+		"go":         cStyleComments,
+		"templ":      cStyleComments,
+		"c":          cStyleComments,
+		"cpp":        cStyleComments,
+		"java":       cStyleComments,
+		"rust":       cStyleComments,
+		"javascript": cStyleComments,
+		"typescript": cStyleComments,
+		"scss":       cStyleComments,
+		"sass":       cStyleComments,
+		"css":        {blockStart: "/*", blockEnd: "*/"},
+		"php":        {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+		"bash":       hashComments,
+		"python":     hashComments,
+		"ruby":       hashComments,
+		"perl":       hashComments,
+		"yaml":       hashComments,
+		"toml":       hashComments,
+		"dockerfile": hashComments,
+		"makefile":   hashComments,
+		"nix":        {line: []string{"#"}, blockStart: "/*", blockEnd: "*/"},
+		"sql":        {line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+		"html":       markupComments,
+		"xml":        markupComments,
+		"markdown":   markupComments,
+	}
+)
+
+// LanguageLines holds a tokei-style line breakdown for one language.
+type LanguageLines struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Lines    int    `json:"lines"`
+	Code     int    `json:"code"`
+	Comments int    `json:"comments"`
+	Blanks   int    `json:"blanks"`
+}
+
+// add accumulates another breakdown into l.
+func (l *LanguageLines) add(other LanguageLines) {
+	l.Files += other.Files
+	l.Lines += other.Lines
+	l.Code += other.Code
+	l.Comments += other.Comments
+	l.Blanks += other.Blanks
+}
+
+// lineCounter classifies the lines of one file as code, comments, or blanks.
+type lineCounter struct {
+	syntax  commentSyntax
+	counts  LanguageLines
+	inBlock bool
+}
+
+// countLine classifies a single line. Lines holding both code and a comment
+// count as code.
+func (c *lineCounter) countLine(line string) {
+	c.counts.Lines++
+	trimmed := strings.TrimSpace(line)
+
+	hasBlocks := c.syntax.blockStart != ""
+	switch {
+	case trimmed == "":
+		c.counts.Blanks++
+	case c.inBlock || hasBlocks && strings.HasPrefix(trimmed, c.syntax.blockStart):
+		c.counts.Comments++
+		c.inBlock = c.blockOpenAfter(trimmed, c.inBlock)
+	case c.isLineComment(trimmed):
+		c.counts.Comments++
+	default:
+		c.counts.Code++
+		if hasBlocks {
+			c.inBlock = c.blockOpenAfter(trimmed, false)
+		}
+	}
+}
+
+// blockOpenAfter reports whether a block comment is still open at the end
+// of line, given whether one was open at its start.
+func (c *lineCounter) blockOpenAfter(line string, open bool) bool {
+	for {
+		delim := c.syntax.blockStart
+		if open {
+			delim = c.syntax.blockEnd
+		}
+		i := strings.Index(line, delim)
+		if i < 0 {
+			return open
+		}
+		line = line[i+len(delim):]
+		open = !open
+	}
+}
+
+// isLineComment reports whether a trimmed line starts with a line comment.
+func (c *lineCounter) isLineComment(trimmed string) bool {
+	for _, prefix := range c.syntax.line {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// langsCollector accumulates per-language line breakdowns across files.
+type langsCollector struct {
+	mu        sync.Mutex
+	languages map[string]*LanguageLines
+}
+
+// newLangsCollector creates an empty language breakdown collector.
+func newLangsCollector() *langsCollector {
+	return &langsCollector{
+		languages: make(map[string]*LanguageLines),
+	}
+}
+
+// countFile reads a text file and adds its line breakdown under its file
+// type. Binary files and files that cannot be read are skipped.
+func (c *langsCollector) countFile(file ProcessedFile, processor *FileProcessor) {
+	if file.Info.IsBinary || file.Error != nil {
+		return
+	}
+
+	language := file.FileType
+	if language == "" {
+		language = "other"
+	}
+
+	counter := &lineCounter{syntax: languageComments[language]}
+	if err := processor.forEachFileLine(file.Info.Path, counter.countLine); err != nil {
+		return
+	}
+	counter.counts.Files = 1
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lang, ok := c.languages[language]
+	if !ok {
+		lang = &LanguageLines{Language: language}
+		c.languages[language] = lang
+	}
+	lang.add(counter.counts)
+}
+
+// result returns the breakdown ordered by code lines, and the grand total.
+func (c *langsCollector) result() ([]LanguageLines, LanguageLines) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := LanguageLines{Language: "Total"}
+	languages := make([]LanguageLines, 0, len(c.languages))
+	for _, lang := range c.languages {
+		languages = append(languages, *lang)
+		total.add(*lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Code != languages[j].Code {
+			return languages[i].Code > languages[j].Code
+		}
+		return languages[i].Language < languages[j].Language
+	})
+
+	return languages, total
+}
+
+// writeLangs writes the language breakdown, as JSON for the JSON output
+// formats and as a table otherwise.
+func (a *App) writeLangs() error {
+	languages, total := a.langs.result()
+
+	if a.cfg.OutputFormat == OutputFormatJSON || a.cfg.OutputFormat == OutputFormatJSONL {
+		encoder := json.NewEncoder(a.out)
+		if a.cfg.OutputFormat == OutputFormatJSON {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(struct {
+			Languages []LanguageLines `json:"languages"`
+			Total     LanguageLines   `json:"total"`
+		}{languages, total})
+	}
+
+	table := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "Language\tFiles\tLines\tCode\tComments\tBlanks\t")
+	for _, lang := range append(languages, total) {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\t\n",
+			lang.Language, lang.Files, lang.Lines, lang.Code, lang.Comments, lang.Blanks)
+	}
+	return table.Flush()
+}
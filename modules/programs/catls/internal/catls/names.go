@@ -0,0 +1,56 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// writeNames prints the path of every file that passes the filters instead
+// of its contents, one per line or NUL-terminated with -0. With a content
+// filter only files with a matching line are listed, like grep -l; files are
+// read only when a filter needs their content.
+func (a *App) writeNames(ctx context.Context, files []scanner.FileInfo) error {
+	terminator := "\n"
+	if a.cfg.NullSeparated {
+		terminator = "\x00"
+	}
+
+	contentFiltered := a.cfg.ContentExpression() != ""
+	if !contentFiltered && !a.cfg.ChangedOnly {
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !a.filter.ShouldIncludeFile(file, a.cfg) {
+				continue
+			}
+			if _, err := fmt.Fprint(a.out, a.displayName(file), terminator); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return a.processOrdered(ctx, files, func(processed ProcessedFile) error {
+		if contentFiltered && len(processed.Lines) == 0 {
+			return nil
+		}
+		_, err := fmt.Fprint(a.out, a.displayName(processed.Info), terminator)
+		return err
+	})
+}
+
+// displayName returns the path printed for a file by --names-only: a path
+// usable from the current directory, so the list can be passed straight to
+// other commands. Paths are shown relative to --relative-to when it is given,
+// and files inside archives or remote clones, which have no lasting path of
+// their own, keep their display path.
+func (a *App) displayName(file scanner.FileInfo) string {
+	if a.cfg.RelativeTo != "" || a.cloned || strings.Contains(file.RelPath, archiveSeparator) {
+		return file.RelPath
+	}
+	return file.Path
+}
@@ -4,14 +4,21 @@ import (
 	"context"
 	"fmt"
 	"html"
+	"io"
+	"strings"
 )
 
 // XMLOutput handles XML output formatting.
-type XMLOutput struct{}
+type XMLOutput struct {
+	out    io.Writer
+	tokens tokenTally
+}
 
-// NewXMLOutput creates a new XML output formatter.
-func NewXMLOutput() *XMLOutput {
-	return &XMLOutput{}
+// NewXMLOutput creates a new XML output formatter writing to out.
+func NewXMLOutput(out io.Writer) *XMLOutput {
+	return &XMLOutput{
+		out: out,
+	}
 }
 
 // WriteHeader writes the opening XML structure.
@@ -22,7 +29,7 @@ func (o *XMLOutput) WriteHeader(ctx context.Context) error {
 	default:
 	}
 
-	fmt.Println("<files>")
+	fmt.Fprintln(o.out, "<files>")
 	return nil
 }
 
@@ -34,6 +41,7 @@ func (o *XMLOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Conf
 	default:
 	}
 
+	o.tokens.add(file, cfg)
 	return o.writeProcessedFile(file, cfg)
 }
 
@@ -45,28 +53,54 @@ func (o *XMLOutput) WriteFooter(ctx context.Context) error {
 	default:
 	}
 
-	fmt.Println("</files>")
+	if o.tokens.enabled {
+		fmt.Fprintf(o.out, "<totalTokens>%d</totalTokens>\n", o.tokens.total)
+	}
+	fmt.Fprintln(o.out, "</files>")
 	return nil
 }
 
-// writeProcessedFile writes a processed file to XML format.
+// writeProcessedFile writes a processed file to XML format. Metadata goes
+// in attributes of the file element, and content in a CDATA section so it
+// needs no escaping.
 func (o *XMLOutput) writeProcessedFile(file ProcessedFile, cfg *Config) error {
-	safePath := html.EscapeString(file.Info.RelPath)
-	fmt.Printf("<file path=\"%s\">\n", safePath)
+	fmt.Fprintf(o.out, "<file path=\"%s\" size=\"%d\"", xmlEscape(file.Info.RelPath), file.Info.Size)
+	if file.FileType != "" && !file.Info.IsBinary {
+		fmt.Fprintf(o.out, " type=\"%s\"", xmlEscape(file.FileType))
+	}
+	if file.IsTruncated {
+		fmt.Fprintf(o.out, " truncated=\"true\" omittedLines=\"%d\"", file.OmittedLines)
+	}
+	if file.SHA256 != "" {
+		fmt.Fprintf(o.out, " sha256=\"%s\"", file.SHA256)
+	}
+	fmt.Fprintln(o.out, ">")
 
 	if file.Error != nil {
-		safeError := html.EscapeString(file.Error.Error())
-		fmt.Printf("<error>%s</error>\n", safeError)
-		fmt.Println("</file>")
+		fmt.Fprintf(o.out, "<error>%s</error>\n", xmlEscape(file.Error.Error()))
+		fmt.Fprintln(o.out, "</file>")
 		return nil
 	}
 
 	if file.Info.IsBinary {
-		fmt.Println("<binary>true</binary>")
-		fmt.Println("<content>[Binary file - contents not displayed]</content>")
+		fmt.Fprintln(o.out, "<binary>true</binary>")
+		if file.MIMEType == "" {
+			fmt.Fprintf(o.out, "<content>[%s]</content>\n", file.BinaryNotice())
+		} else {
+			fmt.Fprintf(o.out, "<mimeType>%s</mimeType>\n", xmlEscape(file.MIMEType))
+			fmt.Fprintf(o.out, "<hexdump>\n%s</hexdump>\n", xmlEscape(file.HexDump()))
+		}
 	} else {
-		if file.FileType != "" {
-			fmt.Printf("<type>%s</type>\n", html.EscapeString(file.FileType))
+		if cfg.CountTokens {
+			fmt.Fprintf(o.out, "<tokens>%d</tokens>\n", file.Tokens)
+		}
+
+		if cfg.FrontmatterOnly {
+			if file.Frontmatter != nil {
+				fmt.Fprintf(o.out, "<frontmatter%s/>\n", frontmatterAttrs(file.Frontmatter))
+			}
+			fmt.Fprintln(o.out, "</file>")
+			return nil
 		}
 
 		if err := o.writeContent(file, cfg); err != nil {
@@ -74,29 +108,56 @@ func (o *XMLOutput) writeProcessedFile(file ProcessedFile, cfg *Config) error {
 		}
 	}
 
-	fmt.Println("</file>")
+	fmt.Fprintln(o.out, "</file>")
 	return nil
 }
 
-// writeContent writes the content section of a file.
+// writeContent writes the content section of a file as CDATA.
 func (o *XMLOutput) writeContent(file ProcessedFile, cfg *Config) error {
-	fmt.Println("<content>")
+	fmt.Fprintln(o.out, "<content><![CDATA[")
+
+	if notice := file.LeadingNotice(); notice != "" {
+		fmt.Fprintln(o.out, notice)
+	}
 
 	for _, line := range file.Lines {
 		if cfg.ShowLineNumbers {
-			fmt.Printf("%4d| %s\n", line.LineNumber, line.Content)
+			fmt.Fprintf(o.out, "%4d| %s\n", line.LineNumber, xmlCDATA(line.Content))
 		} else {
-			fmt.Println(line.Content)
+			fmt.Fprintln(o.out, xmlCDATA(line.Content))
 		}
 	}
 
-	if file.IsTruncated {
-		remainingLines := file.TotalLines - len(file.Lines)
-		if remainingLines > 0 {
-			fmt.Printf("... (%d more lines)\n", remainingLines)
-		}
+	if notice := file.TrailingNotice(); notice != "" {
+		fmt.Fprintln(o.out, notice)
 	}
 
-	fmt.Println("</content>")
+	fmt.Fprintln(o.out, "]]></content>")
 	return nil
-}
\ No newline at end of file
+}
+
+// xmlEscape escapes s for use in XML text or a quoted attribute.
+func xmlEscape(s string) string {
+	return html.EscapeString(xmlChars(s))
+}
+
+// xmlCDATA prepares s for a CDATA section by splitting any "]]>", which
+// would end the section early, across two sections.
+func xmlCDATA(s string) string {
+	return strings.ReplaceAll(xmlChars(s), "]]>", "]]]]><![CDATA[>")
+}
+
+// xmlChars replaces invalid UTF-8 and the control characters XML 1.0 does
+// not allow, even escaped, with U+FFFD.
+func xmlChars(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t', r == '\n', r == '\r':
+			return r
+		case r < 0x20, r == 0xFFFE, r == 0xFFFF:
+			return '\uFFFD'
+		}
+		return r
+	}, s)
+}
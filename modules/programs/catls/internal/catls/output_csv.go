@@ -0,0 +1,80 @@
+package catls
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// CSVOutput handles CSV output formatting.
+type CSVOutput struct {
+	writer      *csv.Writer
+	countTokens bool
+	hash        bool
+}
+
+// NewCSVOutput creates a new CSV output formatter writing to out, with a
+// tokens column when countTokens is set and a sha256 column when hash is.
+func NewCSVOutput(out io.Writer, countTokens, hash bool) *CSVOutput {
+	return &CSVOutput{
+		writer:      csv.NewWriter(out),
+		countTokens: countTokens,
+		hash:        hash,
+	}
+}
+
+// WriteHeader writes the CSV column header row.
+func (o *CSVOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	header := []string{"path", "type", "binary", "total_lines", "size", "truncated"}
+	if o.countTokens {
+		header = append(header, "tokens")
+	}
+	if o.hash {
+		header = append(header, "sha256")
+	}
+	return o.writer.Write(header)
+}
+
+// WriteFile writes a single row describing a processed file (no content).
+func (o *CSVOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	row := []string{
+		file.Info.RelPath,
+		file.FileType,
+		strconv.FormatBool(file.Info.IsBinary),
+		strconv.Itoa(file.TotalLines),
+		strconv.FormatInt(file.Info.Size, 10),
+		strconv.FormatBool(file.IsTruncated),
+	}
+	if o.countTokens {
+		row = append(row, strconv.Itoa(file.Tokens))
+	}
+	if o.hash {
+		row = append(row, file.SHA256)
+	}
+	return o.writer.Write(row)
+}
+
+// WriteFooter flushes any buffered CSV rows.
+func (o *CSVOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.writer.Flush()
+	return o.writer.Error()
+}
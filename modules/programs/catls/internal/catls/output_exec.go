@@ -0,0 +1,147 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// execFormatPrefix introduces an external formatter command in --format.
+const execFormatPrefix = "exec:"
+
+// ExecProtocolVersion is the version of the JSON Lines protocol spoken to
+// external formatters. It changes only when a message changes incompatibly.
+const ExecProtocolVersion = 1
+
+// ExecOutput hands processed files to an external formatter command. The
+// command is run with sh -c; its stdout becomes catls's output and its stdin
+// receives one JSON object per line:
+//
+//	{"type":"header","version":1}
+//	{"type":"file","file":{...}}   one per file, shaped like --format json
+//	{"type":"footer","files":N,"totalTokens":N}
+//
+// Formatters should ignore fields and message types they do not know.
+type ExecOutput struct {
+	out     io.Writer
+	command string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	encoder *json.Encoder
+	exited  bool
+	exitErr error
+	count   int
+	tokens  tokenTally
+}
+
+// ExecMessage is one line of the external formatter protocol.
+type ExecMessage struct {
+	Type        string    `json:"type"`
+	Version     int       `json:"version,omitempty"`
+	File        *JSONFile `json:"file,omitempty"`
+	Files       int       `json:"files,omitempty"`
+	TotalTokens int       `json:"totalTokens,omitempty"`
+}
+
+// NewExecOutput creates a formatter that pipes files through command, with
+// the command's output written to out.
+func NewExecOutput(out io.Writer, command string) *ExecOutput {
+	return &ExecOutput{
+		out:     out,
+		command: command,
+	}
+}
+
+// ExecCommand returns the external formatter command of an exec:<cmd>
+// format, and whether the format is one.
+func (f OutputFormat) ExecCommand() (string, bool) {
+	command, ok := strings.CutPrefix(string(f), execFormatPrefix)
+	return strings.TrimSpace(command), ok
+}
+
+// WriteHeader starts the formatter command and sends the header message.
+func (o *ExecOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", o.command)
+	cmd.Stdout = o.out
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start formatter %q: %w", o.command, err)
+	}
+
+	o.cmd = cmd
+	o.stdin = stdin
+	o.encoder = json.NewEncoder(stdin)
+	return o.send(ExecMessage{Type: "header", Version: ExecProtocolVersion})
+}
+
+// WriteFile sends a single processed file to the formatter.
+func (o *ExecOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.count++
+	o.tokens.add(file, cfg)
+	jsonFile := newJSONFile(file)
+	return o.send(ExecMessage{Type: "file", File: &jsonFile})
+}
+
+// WriteFooter sends the footer message, closes the formatter's input, and
+// waits for it to finish writing.
+func (o *ExecOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if err := o.send(ExecMessage{Type: "footer", Files: o.count, TotalTokens: o.tokens.total}); err != nil {
+		return err
+	}
+	return o.wait()
+}
+
+// send writes one protocol message to the formatter's stdin.
+func (o *ExecOutput) send(msg ExecMessage) error {
+	if err := o.encoder.Encode(msg); err != nil {
+		// A formatter that stops reading early has usually failed, and its
+		// exit status says more than the broken pipe
+		if exitErr := o.wait(); exitErr != nil {
+			return exitErr
+		}
+		return fmt.Errorf("failed to write to formatter %q: %w", o.command, err)
+	}
+	return nil
+}
+
+// wait closes the formatter's stdin and waits for it to exit, returning an
+// error if it failed. Only the first call waits; later ones repeat its result.
+func (o *ExecOutput) wait() error {
+	if o.exited {
+		return o.exitErr
+	}
+	o.exited = true
+
+	_ = o.stdin.Close()
+	if err := o.cmd.Wait(); err != nil {
+		o.exitErr = fmt.Errorf("formatter %q failed: %w", o.command, err)
+	}
+	return o.exitErr
+}
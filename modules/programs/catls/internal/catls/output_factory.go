@@ -1,19 +1,38 @@
 package catls
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
-// NewOutputFormatter creates an output formatter for the specified format.
-func NewOutputFormatter(format OutputFormat) (OutputFormatter, error) {
-	switch format {
+// NewOutputFormatter creates an output formatter for the format selected in
+// cfg that writes to out.
+func NewOutputFormatter(cfg *Config, out io.Writer) (OutputFormatter, error) {
+	switch cfg.OutputFormat {
 	case OutputFormatXML:
-		return NewXMLOutput(), nil
+		return NewXMLOutput(out), nil
 	case OutputFormatJSON:
-		return NewJSONOutput(), nil
+		return NewJSONOutput(out), nil
+	case OutputFormatJSONL:
+		return NewJSONLOutput(out), nil
 	case OutputFormatMarkdown:
-		return NewMarkdownOutput(), nil
-	default:
-		return nil, fmt.Errorf("unsupported output format: %s", format)
+		return NewMarkdownOutput(out), nil
+	case OutputFormatCSV:
+		return NewCSVOutput(out, cfg.CountTokens, cfg.Hash), nil
+	case OutputFormatHTML:
+		return NewHTMLOutput(out), nil
+	case OutputFormatTar:
+		return NewTarOutput(out), nil
+	case OutputFormatTerm:
+		return NewTermOutput(out), nil
+	case OutputFormatTemplate:
+		return NewTemplateOutput(out, cfg.TemplateFile), nil
 	}
+
+	if command, ok := cfg.OutputFormat.ExecCommand(); ok && command != "" {
+		return NewExecOutput(out, command), nil
+	}
+	return nil, fmt.Errorf("unsupported output format: %s", cfg.OutputFormat)
 }
 
 // GetSupportedFormats returns a list of all supported output formats.
@@ -21,6 +40,13 @@ func GetSupportedFormats() []string {
 	return []string{
 		OutputFormatXML.String(),
 		OutputFormatJSON.String(),
+		OutputFormatJSONL.String(),
 		OutputFormatMarkdown.String(),
+		OutputFormatCSV.String(),
+		OutputFormatHTML.String(),
+		OutputFormatTar.String(),
+		OutputFormatTerm.String(),
+		OutputFormatTemplate.String(),
+		execFormatPrefix + "<cmd>",
 	}
 }
\ No newline at end of file
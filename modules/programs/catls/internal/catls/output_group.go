@@ -0,0 +1,72 @@
+package catls
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// dirGroup collects the output for the files of one directory under
+// --group-by-dir, so the directory's section header can show its totals
+// before the files themselves.
+type dirGroup struct {
+	dir         string
+	countTokens bool
+	files       int
+	lines       int
+	size        int64
+	tokens      int
+	body        bytes.Buffer
+}
+
+// newDirGroup starts the group for a directory.
+func newDirGroup(dir string, cfg *Config) *dirGroup {
+	return &dirGroup{dir: dir, countTokens: cfg.CountTokens}
+}
+
+// fileDir returns the directory a file is grouped under, "." for files at
+// the top level.
+func fileDir(relPath string) string {
+	return path.Dir(filepath.ToSlash(relPath))
+}
+
+// dirLabel returns the heading shown for a directory.
+func dirLabel(dir string) string {
+	if dir == "." {
+		return "./"
+	}
+	return dir + "/"
+}
+
+// add records a file in the directory's totals.
+func (g *dirGroup) add(file ProcessedFile) {
+	g.files++
+	g.lines += file.TotalLines
+	g.size += file.Info.Size
+	g.tokens += file.Tokens
+}
+
+// summary describes the directory's totals, e.g. "3 files · 120 lines ·
+// 4.2 KiB · ~900 tokens". The separator is passed in so HTML output can
+// use an entity.
+func (g *dirGroup) summary(sep string) string {
+	parts := []string{
+		pluralize(g.files, "file"),
+		pluralize(g.lines, "line"),
+		formatSize(g.size),
+	}
+	if g.countTokens {
+		parts = append(parts, fmt.Sprintf("~%d tokens", g.tokens))
+	}
+	return strings.Join(parts, sep)
+}
+
+// pluralize formats a count with a noun, adding an "s" unless it is one.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
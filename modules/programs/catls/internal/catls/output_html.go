@@ -0,0 +1,295 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// htmlBaseCSS lays out the page: a fixed file index sidebar on the left and
+// one collapsible section per file in the main column.
+const htmlBaseCSS = `body { margin: 0; font-family: sans-serif; }
+nav { position: fixed; top: 0; left: 0; bottom: 0; width: 18rem; overflow-y: auto; padding: 1rem; box-sizing: border-box; border-right: 1px solid #ddd; background: #fafafa; font-size: 0.85rem; }
+nav ul { list-style: none; margin: 0; padding: 0; }
+nav li { margin: 0.2rem 0; word-break: break-all; }
+main { margin-left: 18rem; padding: 1rem 2rem; }
+details { margin-bottom: 1rem; border: 1px solid #ddd; border-radius: 4px; }
+summary { cursor: pointer; padding: 0.5rem; background: #f0f0f0; font-family: monospace; }
+summary .meta, h2 .meta { color: #777; margin-left: 1rem; }
+h2 { font-family: monospace; font-size: 1.1rem; font-weight: normal; border-bottom: 1px solid #ddd; padding-bottom: 0.25rem; }
+h2 .meta { font-size: 0.85rem; }
+nav li ul { padding-left: 1rem; }
+pre.chroma { margin: 0; padding: 0.5rem; overflow-x: auto; }
+.ln { color: #999; user-select: none; margin-right: 0.75rem; }
+.note, .error { margin: 0.5rem; font-style: italic; }
+.error { color: #b00; }
+`
+
+// HTMLOutput handles self-contained HTML output formatting.
+type HTMLOutput struct {
+	out    io.Writer
+	style  *chroma.Style
+	index  []string
+	files  int
+	tokens tokenTally
+
+	// Directory being collected with --group-by-dir, the index entry its
+	// files start at, and the number of directories written
+	group      *dirGroup
+	groupStart int
+	dirs       int
+}
+
+// NewHTMLOutput creates a new HTML output formatter writing to out.
+func NewHTMLOutput(out io.Writer) *HTMLOutput {
+	return &HTMLOutput{
+		out:   out,
+		style: styles.Get("github"),
+		index: make([]string, 0),
+	}
+}
+
+// WriteHeader writes the document head, including all CSS, and opens the
+// main content column.
+func (o *HTMLOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fmt.Fprintln(o.out, "<!DOCTYPE html>")
+	fmt.Fprintln(o.out, "<html>")
+	fmt.Fprintln(o.out, "<head>")
+	fmt.Fprintln(o.out, `<meta charset="utf-8">`)
+	fmt.Fprintln(o.out, "<title>catls</title>")
+	fmt.Fprintln(o.out, "<style>")
+	fmt.Fprint(o.out, htmlBaseCSS)
+	if err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(o.out, o.style); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, "</style>")
+	fmt.Fprintln(o.out, "</head>")
+	fmt.Fprintln(o.out, "<body>")
+	fmt.Fprintln(o.out, "<main>")
+	return nil
+}
+
+// WriteFile writes a single processed file as a collapsible section.
+func (o *HTMLOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.tokens.add(file, cfg)
+	if !cfg.GroupByDir {
+		return o.writeFile(o.out, file, cfg)
+	}
+
+	// Files are sorted by directory, so a new directory closes the last one
+	if dir := fileDir(file.Info.RelPath); o.group == nil || o.group.dir != dir {
+		o.flushGroup()
+		o.group = newDirGroup(dir, cfg)
+		o.groupStart = len(o.index)
+	}
+	o.group.add(file)
+	return o.writeFile(&o.group.body, file, cfg)
+}
+
+// flushGroup writes the collected directory section, headed by the
+// directory's totals, and nests its files under it in the index.
+func (o *HTMLOutput) flushGroup() {
+	if o.group == nil {
+		return
+	}
+	o.dirs++
+	id := fmt.Sprintf("dir-%d", o.dirs)
+	label := html.EscapeString(dirLabel(o.group.dir))
+
+	fmt.Fprintf(o.out, "<section id=\"%s\">\n", id)
+	fmt.Fprintf(o.out, "<h2>%s<span class=\"meta\">%s</span></h2>\n", label, o.group.summary(" &middot; "))
+	_, _ = o.group.body.WriteTo(o.out)
+	fmt.Fprintln(o.out, "</section>")
+
+	entry := fmt.Sprintf(`<li><a href="#%s"><strong>%s</strong></a><ul>`, id, label)
+	o.index = slices.Insert(o.index, o.groupStart, entry)
+	o.index = append(o.index, "</ul></li>")
+	o.group = nil
+}
+
+// writeFile writes a file's collapsible section to w and adds it to the
+// index.
+func (o *HTMLOutput) writeFile(w io.Writer, file ProcessedFile, cfg *Config) error {
+	o.files++
+	id := fmt.Sprintf("file-%d", o.files)
+	o.index = append(o.index, fmt.Sprintf(`<li><a href="#%s">%s</a></li>`, id, html.EscapeString(file.Info.RelPath)))
+
+	fmt.Fprintf(w, "<details open id=\"%s\">\n", id)
+	fmt.Fprintf(w, "<summary>%s", html.EscapeString(file.Info.RelPath))
+	if file.FileType != "" {
+		fmt.Fprintf(w, "<span class=\"meta\">%s &middot; %d lines", html.EscapeString(file.FileType), file.TotalLines)
+		if cfg.CountTokens {
+			fmt.Fprintf(w, " &middot; ~%d tokens", file.Tokens)
+		}
+		fmt.Fprint(w, "</span>")
+	}
+	if file.SHA256 != "" {
+		fmt.Fprintf(w, "<span class=\"meta\">%d bytes &middot; sha256 %s</span>", file.Info.Size, file.SHA256)
+	}
+	fmt.Fprintln(w, "</summary>")
+
+	switch {
+	case file.Error != nil:
+		fmt.Fprintf(w, "<p class=\"error\">%s</p>\n", html.EscapeString(file.Error.Error()))
+	case file.Info.IsBinary:
+		fmt.Fprintf(w, "<p class=\"note\">%s</p>\n", html.EscapeString(file.BinaryNotice()))
+		if dump := file.HexDump(); dump != "" {
+			fmt.Fprintf(w, "<pre class=\"chroma\">%s</pre>\n", html.EscapeString(dump))
+		}
+	default:
+		if err := o.writeContent(w, file, cfg); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "</details>")
+	return nil
+}
+
+// WriteFooter closes the content column and writes the file index sidebar.
+func (o *HTMLOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.flushGroup()
+	fmt.Fprintln(o.out, "</main>")
+	fmt.Fprintln(o.out, "<nav>")
+	fmt.Fprintf(o.out, "<strong>Files (%d)</strong>\n", o.files)
+	if o.tokens.enabled {
+		fmt.Fprintf(o.out, "<p>~%d tokens</p>\n", o.tokens.total)
+	}
+	fmt.Fprintln(o.out, "<ul>")
+	for _, entry := range o.index {
+		fmt.Fprintln(o.out, entry)
+	}
+	fmt.Fprintln(o.out, "</ul>")
+	fmt.Fprintln(o.out, "</nav>")
+	fmt.Fprintln(o.out, "</body>")
+	fmt.Fprintln(o.out, "</html>")
+	return nil
+}
+
+// writeContent writes the syntax-highlighted code block of a file to w.
+func (o *HTMLOutput) writeContent(w io.Writer, file ProcessedFile, cfg *Config) error {
+	lines, err := highlightLines(file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(w, `<pre class="chroma">`)
+	if notice := file.LeadingNotice(); notice != "" {
+		fmt.Fprintln(w, notice)
+	}
+	for i, line := range file.Lines {
+		if cfg.ShowLineNumbers {
+			fmt.Fprintf(w, "<span class=\"ln\">%4d</span>", line.LineNumber)
+		}
+		fmt.Fprintln(w, lines[i])
+	}
+
+	if notice := file.TrailingNotice(); notice != "" {
+		fmt.Fprintln(w, notice)
+	}
+
+	fmt.Fprintln(w, "</pre>")
+	return nil
+}
+
+// highlightLines tokenises a file's displayed lines and returns one string of
+// class-annotated HTML per line, so line numbers stay aligned with the
+// original file even when the content has been filtered.
+func highlightLines(file ProcessedFile) ([]string, error) {
+	tokenLines, err := tokeniseLines(file)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, len(tokenLines))
+	for i, tokens := range tokenLines {
+		var sb strings.Builder
+		for _, token := range tokens {
+			if class := tokenClass(token.Type); class != "" {
+				fmt.Fprintf(&sb, `<span class="%s">%s</span>`, class, html.EscapeString(token.Value))
+			} else {
+				sb.WriteString(html.EscapeString(token.Value))
+			}
+		}
+		lines[i] = sb.String()
+	}
+
+	return lines, nil
+}
+
+// tokeniseLines runs a file's displayed lines through the matching chroma
+// lexer and splits the tokens back into one slice per displayed line.
+func tokeniseLines(file ProcessedFile) ([][]chroma.Token, error) {
+	lexer := lexers.Match(filepath.Base(file.Info.RelPath))
+	if lexer == nil && file.FileType != "" {
+		lexer = lexers.Get(file.FileType)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	source := make([]string, len(file.Lines))
+	for i, line := range file.Lines {
+		source[i] = line.Content
+	}
+
+	iterator, err := lexer.Tokenise(nil, strings.Join(source, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to highlight %s: %w", file.Info.RelPath, err)
+	}
+
+	lines := make([][]chroma.Token, len(file.Lines))
+	current := 0
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		for i, part := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				current++
+			}
+			if part != "" && current < len(lines) {
+				lines[current] = append(lines[current], chroma.Token{Type: token.Type, Value: part})
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// tokenClass returns the chroma CSS class for a token type, falling back to
+// its parent categories like chroma's own HTML formatter does.
+func tokenClass(t chroma.TokenType) string {
+	for t != 0 {
+		if class, ok := chroma.StandardTypes[t]; ok {
+			return class
+		}
+		t = t.Parent()
+	}
+	return chroma.StandardTypes[t]
+}
@@ -20,7 +20,13 @@ type OutputFormat string
 const (
 	OutputFormatXML      OutputFormat = "xml"
 	OutputFormatJSON     OutputFormat = "json"
+	OutputFormatJSONL    OutputFormat = "jsonl"
 	OutputFormatMarkdown OutputFormat = "markdown"
+	OutputFormatCSV      OutputFormat = "csv"
+	OutputFormatHTML     OutputFormat = "html"
+	OutputFormatTar      OutputFormat = "tar"
+	OutputFormatTerm     OutputFormat = "term"
+	OutputFormatTemplate OutputFormat = "template"
 )
 
 // String returns the string representation of the output format.
@@ -28,12 +34,16 @@ func (f OutputFormat) String() string {
 	return string(f)
 }
 
-// IsValid checks if the output format is supported.
+// IsValid checks if the output format is supported, either built in or an
+// exec:<cmd> external formatter.
 func (f OutputFormat) IsValid() bool {
 	switch f {
-	case OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown:
+	case OutputFormatXML, OutputFormatJSON, OutputFormatJSONL,
+		OutputFormatMarkdown, OutputFormatCSV, OutputFormatHTML, OutputFormatTar,
+		OutputFormatTerm, OutputFormatTemplate:
 		return true
 	default:
-		return false
+		command, ok := f.ExecCommand()
+		return ok && command != ""
 	}
 }
\ No newline at end of file
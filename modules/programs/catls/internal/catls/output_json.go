@@ -3,12 +3,21 @@ package catls
 import (
 	"context"
 	"encoding/json"
-	"os"
+	"fmt"
+	"io"
 )
 
-// JSONOutput handles JSON output formatting.
+// JSONOutput handles JSON output formatting. Files are written as they
+// arrive rather than collected, so the document never has to fit in memory.
 type JSONOutput struct {
-	files []JSONFile
+	out    io.Writer
+	count  int
+	tokens tokenTally
+
+	// stats, set with --stats, is written into the document as its stats
+	// field, so the output stays a single JSON document
+	stats      *statsCollector
+	wroteStats bool
 }
 
 // JSONFile represents a file in JSON format.
@@ -20,6 +29,15 @@ type JSONFile struct {
 	Lines      []JSONLine `json:"lines,omitempty"`
 	TotalLines int        `json:"totalLines"`
 	Truncated  bool       `json:"truncated"`
+	Omitted    int        `json:"omittedLines,omitempty"`
+	Notice     string     `json:"truncationNotice,omitempty"`
+	Tokens     int        `json:"tokens,omitempty"`
+	MIMEType   string     `json:"mimeType,omitempty"`
+	HexDump    string     `json:"hexdump,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+	SHA256     string     `json:"sha256,omitempty"`
+
+	Frontmatter map[string]any `json:"frontmatter,omitempty"`
 }
 
 // JSONLine represents a line of content with its number.
@@ -28,14 +46,14 @@ type JSONLine struct {
 	Content string `json:"content"`
 }
 
-// NewJSONOutput creates a new JSON output formatter.
-func NewJSONOutput() *JSONOutput {
+// NewJSONOutput creates a new JSON output formatter writing to out.
+func NewJSONOutput(out io.Writer) *JSONOutput {
 	return &JSONOutput{
-		files: make([]JSONFile, 0),
+		out: out,
 	}
 }
 
-// WriteHeader writes the opening JSON structure (no-op for JSON).
+// WriteHeader opens the JSON document and its files array.
 func (o *JSONOutput) WriteHeader(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -43,11 +61,11 @@ func (o *JSONOutput) WriteHeader(ctx context.Context) error {
 	default:
 	}
 
-	// JSON requires complete structure, so we don't write anything here
-	return nil
+	_, err := fmt.Fprint(o.out, "{\n  \"files\": [")
+	return err
 }
 
-// WriteFile accumulates a processed file for later JSON output.
+// WriteFile writes a processed file as the next element of the files array.
 func (o *JSONOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
 	select {
 	case <-ctx.Done():
@@ -55,11 +73,70 @@ func (o *JSONOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Con
 	default:
 	}
 
+	data, err := json.MarshalIndent(newJSONFile(file), "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n    "
+	if o.count == 0 {
+		separator = "\n    "
+	}
+	o.count++
+	o.tokens.add(file, cfg)
+
+	if _, err := io.WriteString(o.out, separator); err != nil {
+		return err
+	}
+	_, err = o.out.Write(data)
+	return err
+}
+
+// WriteFooter closes the files array and the document.
+func (o *JSONOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if o.count > 0 {
+		fmt.Fprint(o.out, "\n  ")
+	}
+	fmt.Fprint(o.out, "]")
+	if o.tokens.enabled {
+		fmt.Fprintf(o.out, ",\n  \"totalTokens\": %d", o.tokens.total)
+	}
+	if o.stats != nil {
+		data, err := json.MarshalIndent(o.stats.result(), "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(o.out, ",\n  \"stats\": %s", data)
+		o.wroteStats = true
+	}
+	_, err := fmt.Fprintln(o.out, "\n}")
+	return err
+}
+
+// newJSONFile converts a processed file into its JSON representation.
+func newJSONFile(file ProcessedFile) JSONFile {
 	jsonFile := JSONFile{
 		Path:       file.Info.RelPath,
 		Binary:     file.Info.IsBinary,
 		TotalLines: file.TotalLines,
 		Truncated:  file.IsTruncated,
+		Omitted:    file.OmittedLines,
+		Notice:     file.LeadingNotice() + file.TrailingNotice(),
+		Tokens:     file.Tokens,
+		MIMEType:   file.MIMEType,
+		HexDump:    file.HexDump(),
+		SHA256:     file.SHA256,
+
+		Frontmatter: file.Frontmatter,
+	}
+	if file.SHA256 != "" {
+		jsonFile.Size = file.Info.Size
 	}
 
 	// Set file type if available and not binary
@@ -71,7 +148,7 @@ func (o *JSONOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Con
 	if file.Error != nil {
 		errorMsg := file.Error.Error()
 		jsonFile.Error = &errorMsg
-	} else if !file.Info.IsBinary {
+	} else if !file.Info.IsBinary && file.Frontmatter == nil {
 		// Add lines for non-binary files without errors
 		jsonFile.Lines = make([]JSONLine, len(file.Lines))
 		for i, line := range file.Lines {
@@ -82,25 +159,5 @@ func (o *JSONOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Con
 		}
 	}
 
-	o.files = append(o.files, jsonFile)
-	return nil
+	return jsonFile
 }
-
-// WriteFooter writes the complete JSON structure.
-func (o *JSONOutput) WriteFooter(ctx context.Context) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-	}
-
-	output := struct {
-		Files []JSONFile `json:"files"`
-	}{
-		Files: o.files,
-	}
-
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
-}
\ No newline at end of file
@@ -0,0 +1,64 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// JSONLOutput handles JSON Lines output formatting. Unlike JSONOutput it
+// writes each file as soon as it is processed instead of buffering the
+// whole tree until the footer.
+type JSONLOutput struct {
+	encoder *json.Encoder
+	tokens  tokenTally
+}
+
+// NewJSONLOutput creates a new JSON Lines output formatter writing to out.
+func NewJSONLOutput(out io.Writer) *JSONLOutput {
+	return &JSONLOutput{
+		encoder: json.NewEncoder(out),
+	}
+}
+
+// WriteHeader writes the opening JSON Lines structure (no-op for JSON Lines).
+func (o *JSONLOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// WriteFile writes a single processed file as one JSON object per line.
+func (o *JSONLOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.tokens.add(file, cfg)
+	return o.encoder.Encode(newJSONFile(file))
+}
+
+// WriteFooter writes a final summary object when token counting is enabled.
+func (o *JSONLOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if !o.tokens.enabled {
+		return nil
+	}
+
+	return o.encoder.Encode(struct {
+		TotalTokens int `json:"totalTokens"`
+	}{
+		TotalTokens: o.tokens.total,
+	})
+}
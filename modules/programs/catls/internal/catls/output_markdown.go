@@ -3,18 +3,23 @@ package catls
 import (
 	"context"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 )
 
 // MarkdownOutput handles Markdown output formatting.
 type MarkdownOutput struct {
+	out       io.Writer
 	firstFile bool
+	tokens    tokenTally
+	group     *dirGroup // Directory being collected with --group-by-dir
 }
 
-// NewMarkdownOutput creates a new Markdown output formatter.
-func NewMarkdownOutput() *MarkdownOutput {
+// NewMarkdownOutput creates a new Markdown output formatter writing to out.
+func NewMarkdownOutput(out io.Writer) *MarkdownOutput {
 	return &MarkdownOutput{
+		out:       out,
 		firstFile: true,
 	}
 }
@@ -39,56 +44,103 @@ func (o *MarkdownOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg
 	default:
 	}
 
-	// Add spacing between files (except for the first file)
+	o.tokens.add(file, cfg)
+	if !cfg.GroupByDir {
+		o.writeFile(o.out, file, cfg, "##", o.firstFile)
+		o.firstFile = false
+		return nil
+	}
+
+	// Files are sorted by directory, so a new directory closes the last one
+	if dir := fileDir(file.Info.RelPath); o.group == nil || o.group.dir != dir {
+		o.flushGroup()
+		o.group = newDirGroup(dir, cfg)
+	}
+	o.group.add(file)
+	o.writeFile(&o.group.body, file, cfg, "###", o.group.files == 1)
+	return nil
+}
+
+// flushGroup writes the collected directory section, headed by the
+// directory's totals.
+func (o *MarkdownOutput) flushGroup() {
+	if o.group == nil {
+		return
+	}
 	if !o.firstFile {
-		fmt.Println()
+		fmt.Fprintln(o.out)
 	}
 	o.firstFile = false
 
+	fmt.Fprintf(o.out, "## %s\n\n*%s*\n\n", dirLabel(o.group.dir), o.group.summary(" · "))
+	_, _ = o.group.body.WriteTo(o.out)
+	o.group = nil
+}
+
+// writeFile writes one file to w under a heading of the given level.
+func (o *MarkdownOutput) writeFile(w io.Writer, file ProcessedFile, cfg *Config, heading string, first bool) {
+	// Add spacing between files (except for the first file)
+	if !first {
+		fmt.Fprintln(w)
+	}
+
 	// Write file header
-	fmt.Printf("## %s\n\n", file.Info.RelPath)
+	fmt.Fprintf(w, "%s %s\n\n", heading, file.Info.RelPath)
+
+	if cfg.CountTokens && file.Error == nil && !file.Info.IsBinary {
+		fmt.Fprintf(w, "*~%d tokens*\n\n", file.Tokens)
+	}
+
+	if file.SHA256 != "" {
+		fmt.Fprintf(w, "*%d bytes, sha256 `%s`*\n\n", file.Info.Size, file.SHA256)
+	}
 
 	// Handle errors
 	if file.Error != nil {
-		fmt.Printf("**Error:** %s\n\n", file.Error.Error())
-		return nil
+		fmt.Fprintf(w, "**Error:** %s\n\n", file.Error.Error())
+		return
 	}
 
 	// Handle binary files
 	if file.Info.IsBinary {
-		fmt.Println("*Binary file - contents not displayed*")
-		return nil
+		fmt.Fprintf(w, "*%s*\n", file.BinaryNotice())
+		if dump := file.HexDump(); dump != "" {
+			fmt.Fprintf(w, "\n```text\n%s```\n", dump)
+		}
+		return
 	}
 
 	// Determine language for syntax highlighting
 	language := o.getLanguageForSyntaxHighlighting(file.FileType, file.Info.RelPath)
+	if cfg.FrontmatterOnly {
+		language = "yaml"
+	}
 
 	// Write code block with content
-	fmt.Printf("```%s name=\"%s\"\n", language, filepath.Base(file.Info.RelPath))
+	fmt.Fprintf(w, "```%s name=\"%s\"\n", language, filepath.Base(file.Info.RelPath))
+
+	if notice := file.LeadingNotice(); notice != "" {
+		fmt.Fprintln(w, notice)
+	}
 
 	// Write content lines
 	for _, line := range file.Lines {
 		if cfg.ShowLineNumbers {
-			fmt.Printf("%4d| %s\n", line.LineNumber, line.Content)
+			fmt.Fprintf(w, "%4d| %s\n", line.LineNumber, line.Content)
 		} else {
-			fmt.Println(line.Content)
+			fmt.Fprintln(w, line.Content)
 		}
 	}
 
 	// Handle truncation
-	if file.IsTruncated {
-		remainingLines := file.TotalLines - len(file.Lines)
-		if remainingLines > 0 {
-			fmt.Printf("... (%d more lines)\n", remainingLines)
-		}
+	if notice := file.TrailingNotice(); notice != "" {
+		fmt.Fprintln(w, notice)
 	}
 
-	fmt.Println("```")
-
-	return nil
+	fmt.Fprintln(w, "```")
 }
 
-// WriteFooter writes the closing Markdown structure (no-op for Markdown).
+// WriteFooter writes the token total when token counting is enabled.
 func (o *MarkdownOutput) WriteFooter(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -96,7 +148,10 @@ func (o *MarkdownOutput) WriteFooter(ctx context.Context) error {
 	default:
 	}
 
-	// No footer needed for Markdown
+	o.flushGroup()
+	if o.tokens.enabled {
+		fmt.Fprintf(o.out, "\n**Total tokens:** ~%d\n", o.tokens.total)
+	}
 	return nil
 }
 
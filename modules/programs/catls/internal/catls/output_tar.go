@@ -0,0 +1,165 @@
+package catls
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tarManifestName is the name of the generated manifest inside the archive.
+const tarManifestName = "manifest.json"
+
+// TarOutput writes the selected files, byte for byte, into a tar stream and
+// finishes it with a manifest.json describing them.
+type TarOutput struct {
+	writer   *tar.Writer
+	manifest []TarManifestFile
+}
+
+// TarManifestFile describes one selected file in the tar manifest.
+type TarManifestFile struct {
+	Path       string  `json:"path"`
+	Type       string  `json:"type,omitempty"`
+	Binary     bool    `json:"binary"`
+	Size       int64   `json:"size"`
+	TotalLines int     `json:"totalLines"`
+	SHA256     string  `json:"sha256,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+// NewTarOutput creates a new tar output formatter writing to out.
+func NewTarOutput(out io.Writer) *TarOutput {
+	return &TarOutput{
+		writer:   tar.NewWriter(out),
+		manifest: make([]TarManifestFile, 0),
+	}
+}
+
+// WriteHeader writes nothing; a tar stream has no header of its own.
+func (o *TarOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// WriteFile adds the original contents of a file to the archive. Content
+// filters and truncation do not apply; files that cannot be read are only
+// recorded in the manifest.
+func (o *TarOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	entry := TarManifestFile{
+		Path:       tarEntryName(file.Info.RelPath),
+		Type:       file.FileType,
+		Binary:     file.Info.IsBinary,
+		Size:       file.Info.Size,
+		TotalLines: file.TotalLines,
+		SHA256:     file.SHA256,
+	}
+
+	if file.Error != nil {
+		msg := file.Error.Error()
+		entry.Error = &msg
+		o.manifest = append(o.manifest, entry)
+		return nil
+	}
+
+	src, err := os.Open(file.Info.Path)
+	if err != nil {
+		msg := err.Error()
+		entry.Error = &msg
+		o.manifest = append(o.manifest, entry)
+		return nil
+	}
+	defer func() {
+		if closeErr := src.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", file.Info.Path, closeErr)
+		}
+	}()
+
+	size, err := o.writeEntry(src, entry.Path)
+	if err != nil {
+		return err
+	}
+	entry.Size = size
+
+	o.manifest = append(o.manifest, entry)
+	return nil
+}
+
+// WriteFooter adds the manifest and closes the archive.
+func (o *TarOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Files []TarManifestFile `json:"files"`
+	}{o.manifest}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	header := &tar.Header{
+		Name:    tarManifestName,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := o.writer.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := o.writer.Write(data); err != nil {
+		return err
+	}
+	return o.writer.Close()
+}
+
+// writeEntry copies an open file into the archive as name and returns the
+// number of bytes stored.
+func (o *TarOutput) writeEntry(src *os.File, name string) (int64, error) {
+	info, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return 0, err
+	}
+	header.Name = name
+
+	if err := o.writer.WriteHeader(header); err != nil {
+		return 0, err
+	}
+	// Copy exactly the size in the header even if the file changed since
+	if _, err := io.CopyN(o.writer, src, info.Size()); err != nil {
+		return 0, fmt.Errorf("failed to archive %s: %w", src.Name(), err)
+	}
+	return info.Size(), nil
+}
+
+// tarEntryName turns a display path into a relative, slash-separated archive
+// member name that cannot escape the extraction directory.
+func tarEntryName(relPath string) string {
+	name := path.Clean("/" + filepath.ToSlash(relPath))
+	return strings.TrimPrefix(name, "/")
+}
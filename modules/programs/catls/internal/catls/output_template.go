@@ -0,0 +1,164 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateOutput renders files through a user-supplied Go text/template.
+// The template file's top-level body is executed once per file; optional
+// "header" and "footer" templates defined in the same file run before the
+// first and after the last file.
+type TemplateOutput struct {
+	out    io.Writer
+	path   string
+	tmpl   *template.Template
+	count  int
+	tokens tokenTally
+}
+
+// templateFile is the data a template sees for each file: every
+// ProcessedFile field and method, plus the active configuration.
+type templateFile struct {
+	ProcessedFile
+	Config *Config
+}
+
+// templateSummary is the data the header and footer templates see.
+type templateSummary struct {
+	Files       int
+	TotalTokens int
+	CountTokens bool
+}
+
+// templateFuncs are the helpers available to output templates.
+var templateFuncs = template.FuncMap{
+	"xml":     html.EscapeString,
+	"json":    templateJSON,
+	"content": templateContent,
+	"base":    filepath.Base,
+	"ext":     filepath.Ext,
+	"join":    strings.Join,
+	"indent":  templateIndent,
+}
+
+// NewTemplateOutput creates a formatter writing to out that renders files
+// with the template file at path. The template is parsed by WriteHeader.
+func NewTemplateOutput(out io.Writer, path string) *TemplateOutput {
+	return &TemplateOutput{
+		out:  out,
+		path: path,
+	}
+}
+
+// parseOutputTemplate parses the output template file at path.
+func parseOutputTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// WriteHeader parses the template and executes its "header" template, if
+// one is defined.
+func (o *TemplateOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	tmpl, err := parseOutputTemplate(o.path)
+	if err != nil {
+		return err
+	}
+	o.tmpl = tmpl
+
+	return o.executeNamed("header")
+}
+
+// WriteFile executes the template's main body for a single processed file.
+func (o *TemplateOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.count++
+	o.tokens.add(file, cfg)
+	return o.tmpl.Execute(o.out, templateFile{ProcessedFile: file, Config: cfg})
+}
+
+// WriteFooter executes the template's "footer" template, if one is defined.
+func (o *TemplateOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return o.executeNamed("footer")
+}
+
+// executeNamed executes an optional named template with the run summary.
+func (o *TemplateOutput) executeNamed(name string) error {
+	tmpl := o.tmpl.Lookup(name)
+	if tmpl == nil {
+		return nil
+	}
+
+	return tmpl.Execute(o.out, templateSummary{
+		Files:       o.count,
+		TotalTokens: o.tokens.total,
+		CountTokens: o.tokens.enabled,
+	})
+}
+
+// templateJSON encodes a value as JSON, for embedding strings and other
+// values in JSON-like templates.
+func templateJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateContent returns a file's displayed lines with its truncation
+// notices, numbered when --line-numbers is set, exactly as the built-in
+// text formats print them.
+func templateContent(file templateFile) string {
+	var sb strings.Builder
+	if notice := file.LeadingNotice(); notice != "" {
+		sb.WriteString(notice + "\n")
+	}
+	for _, line := range file.Lines {
+		if file.Config != nil && file.Config.ShowLineNumbers {
+			fmt.Fprintf(&sb, "%4d| ", line.LineNumber)
+		}
+		sb.WriteString(line.Content + "\n")
+	}
+	if notice := file.TrailingNotice(); notice != "" {
+		sb.WriteString(notice + "\n")
+	}
+	return sb.String()
+}
+
+// templateIndent prefixes every non-empty line of s with prefix.
+func templateIndent(prefix, s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
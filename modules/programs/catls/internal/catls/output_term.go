@@ -0,0 +1,153 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// ANSI escapes used around headers and notices in colored term output.
+const (
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// TermOutput renders files for reading in a terminal, with ANSI syntax
+// highlighting when Config.Color is set and plain text otherwise.
+type TermOutput struct {
+	out       io.Writer
+	style     *chroma.Style
+	formatter chroma.Formatter
+	firstFile bool
+	tokens    tokenTally
+}
+
+// NewTermOutput creates a new terminal output formatter writing to out.
+func NewTermOutput(out io.Writer) *TermOutput {
+	return &TermOutput{
+		out:       out,
+		style:     styles.Get("monokai"),
+		formatter: formatters.TTY256,
+		firstFile: true,
+	}
+}
+
+// WriteHeader writes nothing; terminal output has no preamble.
+func (o *TermOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// WriteFile writes a file header followed by its (highlighted) lines.
+func (o *TermOutput) WriteFile(ctx context.Context, file ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if !o.firstFile {
+		fmt.Fprintln(o.out)
+	}
+	o.firstFile = false
+	o.tokens.add(file, cfg)
+
+	var meta []string
+	if file.FileType != "" {
+		meta = append(meta, file.FileType)
+	}
+	if file.Error == nil && !file.Info.IsBinary {
+		meta = append(meta, fmt.Sprintf("%d lines", file.TotalLines))
+	}
+	if cfg.CountTokens && file.Error == nil && !file.Info.IsBinary {
+		meta = append(meta, fmt.Sprintf("~%d tokens", file.Tokens))
+	}
+	if file.SHA256 != "" {
+		meta = append(meta, fmt.Sprintf("%d bytes", file.Info.Size), "sha256 "+file.SHA256)
+	}
+	header := "==> " + file.Info.RelPath + " <=="
+	if len(meta) > 0 {
+		header += " (" + strings.Join(meta, ", ") + ")"
+	}
+	fmt.Fprintln(o.out, o.paint(cfg, ansiBold, header))
+
+	switch {
+	case file.Error != nil:
+		fmt.Fprintln(o.out, o.paint(cfg, ansiRed, "error: "+file.Error.Error()))
+	case file.Info.IsBinary:
+		fmt.Fprintln(o.out, o.paint(cfg, ansiDim, file.BinaryNotice()))
+		fmt.Fprint(o.out, file.HexDump())
+	default:
+		return o.writeContent(file, cfg)
+	}
+	return nil
+}
+
+// WriteFooter writes the token total when token counting is enabled.
+func (o *TermOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if o.tokens.enabled {
+		fmt.Fprintf(o.out, "\nTotal tokens: ~%d\n", o.tokens.total)
+	}
+	return nil
+}
+
+// writeContent writes a file's lines, highlighting each one separately so
+// line numbers and truncation notices stay uncolored.
+func (o *TermOutput) writeContent(file ProcessedFile, cfg *Config) error {
+	var tokenLines [][]chroma.Token
+	if cfg.Color {
+		var err error
+		if tokenLines, err = tokeniseLines(file); err != nil {
+			return err
+		}
+	}
+
+	if notice := file.LeadingNotice(); notice != "" {
+		fmt.Fprintln(o.out, o.paint(cfg, ansiDim, notice))
+	}
+
+	for i, line := range file.Lines {
+		if cfg.ShowLineNumbers {
+			fmt.Fprint(o.out, o.paint(cfg, ansiDim, fmt.Sprintf("%4d| ", line.LineNumber)))
+		}
+		if !cfg.Color {
+			fmt.Fprintln(o.out, line.Content)
+			continue
+		}
+		if err := o.formatter.Format(o.out, o.style, chroma.Literator(tokenLines[i]...)); err != nil {
+			return err
+		}
+		fmt.Fprintln(o.out, ansiReset)
+	}
+
+	if notice := file.TrailingNotice(); notice != "" {
+		fmt.Fprintln(o.out, o.paint(cfg, ansiDim, notice))
+	}
+	return nil
+}
+
+// paint wraps text in an ANSI escape when color is enabled.
+func (o *TermOutput) paint(cfg *Config, escape, text string) string {
+	if !cfg.Color {
+		return text
+	}
+	return escape + text + ansiReset
+}
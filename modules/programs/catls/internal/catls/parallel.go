@@ -0,0 +1,135 @@
+package catls
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// jobs returns the number of files to process concurrently.
+func (a *App) jobs() int {
+	if a.cfg.Jobs > 0 {
+		return a.cfg.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// processOrdered filters and processes files on a bounded pool of workers
+// and passes each included file to emit in input order, so output stays
+// deterministic. At most a few files per worker are held in memory ahead of
+// the one being emitted.
+func (a *App) processOrdered(ctx context.Context, files []scanner.FileInfo, emit func(ProcessedFile) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type task struct {
+		file   scanner.FileInfo
+		result chan *ProcessedFile
+	}
+
+	jobs := a.jobs()
+	tasks := make(chan task)
+	pending := make(chan chan *ProcessedFile, 2*jobs)
+
+	// Queue files in order; pending bounds how far ahead workers can get
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		defer close(tasks)
+
+		for _, file := range files {
+			t := task{file: file, result: make(chan *ProcessedFile, 1)}
+			select {
+			case pending <- t.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case tasks <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				t.result <- a.processOne(t.file)
+			}
+		}()
+	}
+
+	for result := range pending {
+		select {
+		case processed := <-result:
+			if processed == nil {
+				continue
+			}
+			if a.stats != nil {
+				a.stats.add(*processed)
+			}
+			if err := emit(*processed); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return ctx.Err()
+}
+
+// processOne filters and processes a single file, returning nil if the file
+// is excluded from output. With --cache, unchanged files are taken from the
+// previous run, and with --changed-only they are excluded.
+func (a *App) processOne(file scanner.FileInfo) *ProcessedFile {
+	if !a.filter.ShouldIncludeFile(file, a.cfg) {
+		return nil
+	}
+
+	processed, cached := a.processCached(file)
+	if cached && a.cfg.ChangedOnly {
+		return nil
+	}
+	if a.langs != nil {
+		a.langs.countFile(processed, a.processor)
+	}
+	return &processed
+}
+
+// processCached returns the processed form of a file, from the snapshot
+// cache when it is unchanged, and reports whether it was cached.
+func (a *App) processCached(file scanner.FileInfo) (ProcessedFile, bool) {
+	var info os.FileInfo
+	if a.cache != nil {
+		var err error
+		if info, err = os.Stat(file.Path); err == nil {
+			if processed, ok := a.cache.lookup(file, info); ok {
+				return processed, true
+			}
+		}
+	}
+
+	processed := a.processor.ProcessFile(file, a.filter)
+	if a.cfg.Hash && processed.Error == nil {
+		processed.SHA256, processed.Error = hashFile(file.Path)
+	}
+	if a.cfg.CountTokens {
+		processed.Tokens = countFileTokens(processed)
+	}
+	if info != nil {
+		a.cache.store(processed, info)
+	}
+	return processed, false
+}
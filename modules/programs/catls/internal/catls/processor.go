@@ -2,7 +2,12 @@ package catls
 
 import (
 	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,16 +18,30 @@ import (
 // FileProcessor handles file content processing.
 type FileProcessor struct {
 	typeDetector TypeDetector
+	head         int
+	tail         int
+	hex          int
+	maxLines     int
+	truncateTo   int
+	noTruncate   bool
+	frontmatter  bool
 }
 
 // ProcessedFile represents a file after processing.
 type ProcessedFile struct {
-	Info        scanner.FileInfo
-	FileType    string
-	Lines       []FilteredLine
-	TotalLines  int
-	IsTruncated bool
-	Error       error
+	Info             scanner.FileInfo
+	FileType         string
+	Lines            []FilteredLine
+	TotalLines       int
+	IsTruncated      bool
+	OmittedLines     int
+	TruncatedAtStart bool
+	Tokens           int
+	MIMEType         string         // Detected MIME type of a previewed binary file
+	BinaryPreview    []byte         // Leading bytes of a binary file, with --hex
+	SHA256           string         // Hex digest of the original file bytes, with --hash
+	Frontmatter      map[string]any // Parsed YAML frontmatter, with --frontmatter-only
+	Error            error          `json:"-"`
 }
 
 // TypeDetector defines interface for detecting file types.
@@ -30,127 +49,313 @@ type TypeDetector interface {
 	DetectType(filePath string) string
 }
 
+const (
+	// maxDisplayLines is the default for the most lines shown from an
+	// unfiltered file before it is cut down to truncateToLines; --max-lines
+	// and --truncate-to override both.
+	maxDisplayLines = 1000
+	truncateToLines = 100
+)
+
 // NewFileProcessor creates a new file processor.
-func NewFileProcessor() *FileProcessor {
+func NewFileProcessor(cfg *Config) *FileProcessor {
 	return &FileProcessor{
-		typeDetector: &ExtensionTypeDetector{},
+		typeDetector: &ContentTypeDetector{},
+		head:         cfg.Head,
+		tail:         cfg.Tail,
+		hex:          cfg.Hex,
+		maxLines:     cmp.Or(cfg.MaxLines, maxDisplayLines),
+		truncateTo:   cmp.Or(cfg.TruncateTo, truncateToLines),
+		noTruncate:   cfg.NoTruncate,
+		frontmatter:  cfg.FrontmatterOnly,
 	}
 }
 
-// ProcessFile processes a single file and returns its content.
+// ProcessFile processes a single file and returns its content. The file is
+// streamed line by line and only the lines that will be displayed are kept,
+// so memory stays bounded by the output rather than the file size. Multiline
+// patterns are the exception, as they must see the whole file at once.
 func (p *FileProcessor) ProcessFile(file scanner.FileInfo, filter *FileFilter) ProcessedFile {
 	result := ProcessedFile{
 		Info: file,
 	}
 
 	if file.IsBinary {
+		if p.hex > 0 {
+			result.MIMEType, result.BinaryPreview, result.Error = p.previewBinary(file.Path)
+		}
 		return result
 	}
 
 	// Detect file type
 	result.FileType = p.typeDetector.DetectType(file.Path)
 
-	// Read file content
-	lines, err := p.readFileLines(file.Path)
-	if err != nil {
-		result.Error = err
+	// Frontmatter-only output shows just the frontmatter block of a file
+	if p.frontmatter {
+		var err error
+		if result.Lines, result.Frontmatter, err = readFrontmatter(file.Path); err != nil {
+			return ProcessedFile{Info: file, FileType: result.FileType, Error: err}
+		}
+		result.TotalLines = len(result.Lines)
 		return result
 	}
 
-	result.TotalLines = len(lines)
-
-	// Apply content filtering
-	filteredLines := filter.FilterContent(lines)
+	collector := &lineCollector{
+		head:       p.head,
+		tail:       p.tail,
+		truncate:   p.head == 0 && p.tail == 0 && filter.contentPattern == nil && !p.noTruncate,
+		maxLines:   p.maxLines,
+		truncateTo: p.truncateTo,
+	}
 
-	// Check if we need to truncate for display
-	const maxDisplayLines = 1000
-	const truncateToLines = 100
+	var err error
+	if filter.contentPattern != nil && filter.multiline {
+		var lines []string
+		lines, err = p.readFileLines(file.Path)
+		for _, line := range filter.FilterContent(lines) {
+			collector.add(line)
+		}
+		result.TotalLines = len(lines)
+	} else {
+		err = p.forEachFileLine(file.Path, func(line string) {
+			result.TotalLines++
+			if filter.MatchLine(line) {
+				collector.add(FilteredLine{LineNumber: result.TotalLines, Content: line})
+			}
+		})
+	}
+	if err != nil {
+		return ProcessedFile{Info: file, FileType: result.FileType, Error: err}
+	}
 
-	if len(filteredLines) > maxDisplayLines && filter.contentPattern == nil {
-		result.Lines = filteredLines[:truncateToLines]
+	result.Lines = collector.result()
+	if omitted := collector.seen - len(result.Lines); omitted > 0 {
 		result.IsTruncated = true
-	} else {
-		result.Lines = filteredLines
+		result.OmittedLines = omitted
+		result.TruncatedAtStart = p.tail > 0
 	}
 
 	return result
 }
 
-// readFileLines reads all lines from a file.
-func (p *FileProcessor) readFileLines(filePath string) ([]string, error) {
+// lineCollector keeps the filtered lines of a file that will be displayed
+// while the file is streamed: the first head lines, a ring buffer of the
+// last tail lines, or, for unfiltered files, up to maxLines lines until the
+// file turns out to be longer and is cut to truncateTo.
+type lineCollector struct {
+	head       int
+	tail       int
+	truncate   bool
+	maxLines   int
+	truncateTo int
+	lines      []FilteredLine
+	next       int // Oldest entry of the tail ring buffer once it is full
+	seen       int // Filtered lines offered, including dropped ones
+}
+
+// add offers the next filtered line to the collector.
+func (c *lineCollector) add(line FilteredLine) {
+	c.seen++
+	switch {
+	case c.head > 0:
+		if len(c.lines) < c.head {
+			c.lines = append(c.lines, line)
+		}
+	case c.tail > 0:
+		if len(c.lines) < c.tail {
+			c.lines = append(c.lines, line)
+			return
+		}
+		c.lines[c.next] = line
+		c.next = (c.next + 1) % c.tail
+	case c.truncate:
+		if c.seen <= c.maxLines {
+			c.lines = append(c.lines, line)
+		} else if len(c.lines) > c.truncateTo {
+			c.lines = c.lines[:c.truncateTo:c.truncateTo]
+		}
+	default:
+		c.lines = append(c.lines, line)
+	}
+}
+
+// result returns the collected lines in file order.
+func (c *lineCollector) result() []FilteredLine {
+	if c.next == 0 {
+		return c.lines
+	}
+	return append(c.lines[c.next:], c.lines[:c.next]...)
+}
+
+// previewBinary returns the MIME type and the first p.hex bytes of a binary
+// file. Content sniffing looks at up to 512 bytes regardless of p.hex.
+func (p *FileProcessor) previewBinary(filePath string) (string, []byte, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
-			// Log close error - in a real app you'd use a proper logger
 			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", filePath, closeErr)
 		}
 	}()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	data, err := io.ReadAll(io.LimitReader(file, int64(max(p.hex, 512))))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return http.DetectContentType(data), data[:min(p.hex, len(data))], nil
+}
+
+// hashFile returns the hex-encoded SHA-256 digest of a file's bytes.
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// HexDump returns the hexdump of a binary file's preview bytes, or an empty
+// string if there is no preview.
+func (f ProcessedFile) HexDump() string {
+	if len(f.BinaryPreview) == 0 {
+		return ""
+	}
+	return hex.Dump(f.BinaryPreview)
+}
+
+// BinaryNotice describes a binary file in place of its contents.
+func (f ProcessedFile) BinaryNotice() string {
+	if f.MIMEType == "" {
+		return "Binary file - contents not displayed"
+	}
+	return fmt.Sprintf("Binary file (%s) - first %d of %d bytes", f.MIMEType, len(f.BinaryPreview), f.Info.Size)
+}
+
+// LeadingNotice returns the truncation notice to print before a file's lines
+// when its beginning was omitted, or an empty string.
+func (f ProcessedFile) LeadingNotice() string {
+	if !f.TruncatedAtStart || f.OmittedLines == 0 {
+		return ""
+	}
+	return fmt.Sprintf("... (%d earlier lines)", f.OmittedLines)
+}
 
-	if err := scanner.Err(); err != nil {
+// TrailingNotice returns the truncation notice to print after a file's lines
+// when its end was omitted, or an empty string.
+func (f ProcessedFile) TrailingNotice() string {
+	if f.TruncatedAtStart || f.OmittedLines == 0 {
+		return ""
+	}
+	return fmt.Sprintf("... (%d more lines)", f.OmittedLines)
+}
+
+// readFileLines reads all lines from a file.
+func (p *FileProcessor) readFileLines(filePath string) ([]string, error) {
+	var lines []string
+	err := p.forEachFileLine(filePath, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return lines, nil
 }
 
+// forEachFileLine calls fn for every line of a file, without its line
+// ending, reading the file incrementally.
+func (p *FileProcessor) forEachFileLine(filePath string, fn func(line string)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			// Log close error - in a real app you'd use a proper logger
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", filePath, closeErr)
+		}
+	}()
+
+	return forEachLine(file, fn)
+}
+
+// forEachLine calls fn for every line read from r, stripping "\n" and
+// "\r\n" endings and decoding UTF-16 text. Unlike bufio.Scanner it has no
+// line length limit.
+func forEachLine(r io.Reader, fn func(line string)) error {
+	reader := bufio.NewReader(newTextReader(r))
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			fn(strings.TrimSuffix(line, "\r"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extensionTypes maps lowercase file extensions to file types.
+var extensionTypes = map[string]string{
+	"sh":         "bash",
+	"bash":       "bash",
+	"rb":         "ruby",
+	"py":         "python",
+	"js":         "javascript",
+	"ts":         "typescript",
+	"jsx":        "javascript",
+	"tsx":        "typescript",
+	"html":       "html",
+	"htm":        "html",
+	"nix":        "nix",
+	"css":        "css",
+	"scss":       "scss",
+	"sass":       "sass",
+	"json":       "json",
+	"md":         "markdown",
+	"markdown":   "markdown",
+	"xml":        "xml",
+	"c":          "c",
+	"cpp":        "cpp",
+	"cxx":        "cpp",
+	"cc":         "cpp",
+	"h":          "c",
+	"hpp":        "cpp",
+	"hxx":        "cpp",
+	"toml":       "toml",
+	"java":       "java",
+	"rs":         "rust",
+	"go":         "go",
+	"php":        "php",
+	"pl":         "perl",
+	"sql":        "sql",
+	"templ":      "templ",
+	"yml":        "yaml",
+	"yaml":       "yaml",
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+	"mk":         "makefile",
+	"mak":        "makefile",
+}
+
 // ExtensionTypeDetector detects file types based on extensions.
 type ExtensionTypeDetector struct{}
 
 // DetectType implements TypeDetector.
 func (d *ExtensionTypeDetector) DetectType(filePath string) string {
 	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
-
-	typeMap := map[string]string{
-		"sh":         "bash",
-		"bash":       "bash",
-		"rb":         "ruby",
-		"py":         "python",
-		"js":         "javascript",
-		"ts":         "typescript",
-		"jsx":        "javascript",
-		"tsx":        "typescript",
-		"html":       "html",
-		"htm":        "html",
-		"nix":        "nix",
-		"css":        "css",
-		"scss":       "scss",
-		"sass":       "sass",
-		"json":       "json",
-		"md":         "markdown",
-		"markdown":   "markdown",
-		"xml":        "xml",
-		"c":          "c",
-		"cpp":        "cpp",
-		"cxx":        "cpp",
-		"cc":         "cpp",
-		"h":          "c",
-		"hpp":        "cpp",
-		"hxx":        "cpp",
-		"toml":       "toml",
-		"java":       "java",
-		"rs":         "rust",
-		"go":         "go",
-		"php":        "php",
-		"pl":         "perl",
-		"sql":        "sql",
-		"templ":      "templ",
-		"yml":        "yaml",
-		"yaml":       "yaml",
-		"dockerfile": "dockerfile",
-		"makefile":   "makefile",
-	}
-
-	if fileType, exists := typeMap[ext]; exists {
-		return fileType
-	}
-	return ""
+	return extensionTypes[ext]
 }
@@ -0,0 +1,73 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// remoteSchemes are the prefixes that mark the directory argument as a git
+// remote rather than a local path.
+var remoteSchemes = []string{"https://", "http://", "ssh://", "git://", "file://", "git@"}
+
+// RemoteSource is a git repository to scan instead of a local directory.
+type RemoteSource struct {
+	URL string
+	Ref string // Branch, tag, or commit; empty for the default branch
+}
+
+// ParseRemoteSource reports whether arg names a remote git repository,
+// optionally pinned with a trailing @ref, e.g. https://github.com/org/repo@v1.2.
+func ParseRemoteSource(arg string) (RemoteSource, bool) {
+	remote := false
+	for _, scheme := range remoteSchemes {
+		if strings.HasPrefix(arg, scheme) {
+			remote = true
+			break
+		}
+	}
+	if !remote {
+		return RemoteSource{}, false
+	}
+
+	// The ref follows the last @ in the final path element, so the user part
+	// of git@host:org/repo is left alone
+	last := strings.LastIndexAny(arg, "/:")
+	if at := strings.LastIndex(arg, "@"); at > last {
+		return RemoteSource{URL: arg[:at], Ref: arg[at+1:]}, true
+	}
+	return RemoteSource{URL: arg}, true
+}
+
+// cloneRemote shallow-clones the repository at the source's ref into a new
+// temporary directory and returns its path along with a function that
+// removes it. Fetching the ref directly works for branches, tags, and commit
+// hashes alike.
+func cloneRemote(ctx context.Context, src RemoteSource) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "catls-remote-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	steps := [][]string{
+		{"init", "-q"},
+		{"remote", "add", "origin", src.URL},
+		{"fetch", "-q", "--depth", "1", "origin", ref},
+		{"checkout", "-q", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		if _, err := gitOutput(ctx, dir, args...); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to clone %s at %s: %w", src.URL, ref, err)
+		}
+	}
+
+	return dir, cleanup, nil
+}
@@ -0,0 +1,166 @@
+package catls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// largestFilesShown is how many of the largest files the statistics list.
+const largestFilesShown = 10
+
+// Stats holds aggregate statistics about the files included in the output.
+type Stats struct {
+	Files       int             `json:"files"`
+	BinaryFiles int             `json:"binaryFiles"`
+	TotalLines  int             `json:"totalLines"`
+	TotalSize   int64           `json:"totalSize"`
+	Languages   []LanguageStats `json:"languages"`
+	Largest     []FileStats     `json:"largest"`
+}
+
+// LanguageStats holds the file count, line total, and size of one language.
+type LanguageStats struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Lines    int    `json:"lines"`
+	Size     int64  `json:"size"`
+}
+
+// FileStats describes a single file in the largest files list.
+type FileStats struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Lines int    `json:"lines"`
+}
+
+// statsCollector accumulates Stats as files are processed.
+type statsCollector struct {
+	mu        sync.Mutex
+	stats     Stats
+	languages map[string]*LanguageStats
+	files     []FileStats
+}
+
+// newStatsCollector creates an empty statistics collector.
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		languages: make(map[string]*LanguageStats),
+	}
+}
+
+// add records a processed file.
+func (c *statsCollector) add(file ProcessedFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	language := file.FileType
+	switch {
+	case file.Info.IsBinary:
+		language = "binary"
+		c.stats.BinaryFiles++
+	case language == "":
+		language = "other"
+	}
+
+	lang, ok := c.languages[language]
+	if !ok {
+		lang = &LanguageStats{Language: language}
+		c.languages[language] = lang
+	}
+	lang.Files++
+	lang.Lines += file.TotalLines
+	lang.Size += file.Info.Size
+
+	c.stats.Files++
+	c.stats.TotalLines += file.TotalLines
+	c.stats.TotalSize += file.Info.Size
+	c.files = append(c.files, FileStats{
+		Path:  file.Info.RelPath,
+		Size:  file.Info.Size,
+		Lines: file.TotalLines,
+	})
+}
+
+// result returns the statistics with languages ordered by line count and
+// the largest files ordered by size.
+func (c *statsCollector) result() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Languages = make([]LanguageStats, 0, len(c.languages))
+	for _, lang := range c.languages {
+		stats.Languages = append(stats.Languages, *lang)
+	}
+	sort.Slice(stats.Languages, func(i, j int) bool {
+		a, b := stats.Languages[i], stats.Languages[j]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return a.Language < b.Language
+	})
+
+	stats.Largest = append([]FileStats{}, c.files...)
+	sort.SliceStable(stats.Largest, func(i, j int) bool {
+		return stats.Largest[i].Size > stats.Largest[j].Size
+	})
+	if len(stats.Largest) > largestFilesShown {
+		stats.Largest = stats.Largest[:largestFilesShown]
+	}
+
+	return stats
+}
+
+// writeStats writes the collected statistics, as JSON for the JSON output
+// formats and as a table otherwise. A JSON document that already holds them
+// is left alone.
+func (a *App) writeStats() error {
+	if output, ok := a.output.(*JSONOutput); ok && output.wroteStats {
+		return nil
+	}
+	stats := a.stats.result()
+
+	if a.cfg.OutputFormat == OutputFormatJSON || a.cfg.OutputFormat == OutputFormatJSONL {
+		encoder := json.NewEncoder(a.out)
+		if a.cfg.OutputFormat == OutputFormatJSON {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(struct {
+			Stats Stats `json:"stats"`
+		}{stats})
+	}
+
+	if !a.cfg.StatsOnly {
+		fmt.Fprintln(a.out)
+	}
+	return writeStatsTable(a.out, stats)
+}
+
+// writeStatsTable renders statistics as aligned human-readable tables.
+func writeStatsTable(w io.Writer, stats Stats) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "Language\tFiles\tLines\tSize\t")
+	for _, lang := range stats.Languages {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t\n", lang.Language, lang.Files, lang.Lines, formatSize(lang.Size))
+	}
+	fmt.Fprintf(table, "Total\t%d\t%d\t%s\t\n", stats.Files, stats.TotalLines, formatSize(stats.TotalSize))
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nBinary files: %d\n", stats.BinaryFiles)
+	if len(stats.Largest) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w, "\nLargest files:")
+	table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, file := range stats.Largest {
+		fmt.Fprintf(table, "  %s\t%d lines\t%s\n", formatSize(file.Size), file.Lines, file.Path)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,251 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/connerosiu/dotfiles/modules/programs/catls/internal/scanner"
+)
+
+// scanTargets scans the directory argument plus any additional paths in
+// Config.Files and merges the results in stable path order. Directories are
+// scanned; files inside a scanned directory narrow that directory's output to
+// the named files, while files elsewhere are included on their own; paths
+// that do not exist are treated as glob patterns. With several roots and no
+// --relative-to, paths are shown relative to their common parent directory.
+// With --follow-imports, the local files the sources import are added; with
+// --changed-since, only files changed relative to that git ref remain,
+// and with --archives, zip and tar archives are replaced by their entries.
+func (a *App) scanTargets(ctx context.Context) ([]scanner.FileInfo, error) {
+	var dirs, files []string
+	for i, path := range append([]string{a.cfg.Directory}, a.cfg.Files...) {
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			dirs = append(dirs, path)
+		case err == nil:
+			files = append(files, path)
+		case i > 0:
+			a.cfg.Globs = append(a.cfg.Globs, path)
+		}
+	}
+
+	// Files inside a scanned directory select from it instead of adding a
+	// root, keyed by absolute path with the path as given
+	selected := make(map[string]map[string]string)
+	var standalone []string
+	for _, file := range files {
+		dir := containingRoot(dirs, file)
+		if dir == "" {
+			standalone = append(standalone, file)
+			continue
+		}
+		key := absPath(dir)
+		if selected[key] == nil {
+			selected[key] = make(map[string]string)
+		}
+		selected[key][absPath(file)] = file
+	}
+
+	if a.cfg.RelativeTo == "" && len(dirs)+len(standalone) > 1 {
+		roots := append([]string{}, dirs...)
+		for _, file := range standalone {
+			roots = append(roots, filepath.Dir(file))
+		}
+		a.cfg.RelativeTo = commonRoot(roots)
+	}
+
+	seen := make(map[string]bool)
+	var result []scanner.FileInfo
+	add := func(file scanner.FileInfo) {
+		key := absPath(file.Path)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, file)
+		}
+	}
+
+	for _, dir := range dirs {
+		found, err := a.scanner.Scan(ctx, a.scannerConfig(dir))
+		if err != nil {
+			return nil, err
+		}
+		only := selected[absPath(dir)]
+		for _, file := range found {
+			if only != nil {
+				if _, ok := only[absPath(file.Path)]; !ok {
+					continue
+				}
+				file.Explicit = true
+			}
+			add(file)
+		}
+
+		// Selected files the scan did not reach, such as ones in
+		// subdirectories without -r, are read on their own
+		for abs, path := range only {
+			if seen[abs] {
+				continue
+			}
+			file, err := a.scanner.ScanFile(pathLike(path, dir), a.scannerConfig(dir))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			add(file)
+		}
+	}
+
+	for _, path := range standalone {
+		file, err := a.scanner.ScanFile(path, a.scannerConfig(filepath.Dir(path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		add(file)
+	}
+
+	if a.cfg.FollowImports {
+		result = a.followImports(result)
+	}
+
+	if a.cfg.ChangedSince != "" {
+		var err error
+		if result, err = a.filterChanged(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.cfg.Archives {
+		var err error
+		if result, err = a.expandArchives(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		// Grouped output needs each directory's files together, ahead of
+		// its subdirectories
+		if a.cfg.GroupByDir {
+			if di, dj := fileDir(result[i].RelPath), fileDir(result[j].RelPath); di != dj {
+				return di < dj
+			}
+		}
+		return result[i].RelPath < result[j].RelPath
+	})
+	return result, nil
+}
+
+// scannerConfig returns the scanner configuration for one root directory.
+func (a *App) scannerConfig(dir string) scanner.Config {
+	return scanner.Config{
+		Directory:   dir,
+		ShowAll:     a.cfg.ShowAll,
+		Recursive:   a.cfg.Recursive,
+		MaxDepth:    a.cfg.MaxDepth,
+		IgnoreDir:   a.cfg.IgnoreDir,
+		IgnoreGlobs: a.cfg.AllIgnoreGlobs(),
+		Debug:       a.cfg.Debug,
+		RelativeTo:  a.cfg.RelativeTo,
+		GitIgnore:   a.cfg.GitIgnore,
+		Jobs:        a.jobs(),
+		NewerThan:   a.cfg.NewerThan,
+		OlderThan:   a.cfg.OlderThan,
+		ExcludeFile: a.outputFiles(),
+	}
+}
+
+// outputFiles returns the absolute paths of the files this run writes: the
+// output file and any numbered chunk files next to it, so a scan covering
+// them leaves them out.
+func (a *App) outputFiles() []string {
+	if a.cfg.Output == "" {
+		return nil
+	}
+
+	output := absPath(a.cfg.Output)
+	paths := []string{output}
+
+	// Chunk files from this or an earlier run, whatever their count
+	pattern := chunkOutputPath(output, 0)
+	cut := strings.LastIndex(pattern, "-000")
+	prefix, suffix := pattern[:cut], pattern[cut+len("-000"):]
+	matches, _ := filepath.Glob(prefix + "-*" + suffix)
+	for _, match := range matches {
+		index := strings.TrimSuffix(strings.TrimPrefix(match, prefix+"-"), suffix)
+		if _, err := strconv.Atoi(index); err == nil {
+			paths = append(paths, match)
+		}
+	}
+	return paths
+}
+
+// containingRoot returns the directory in dirs that contains path, or an
+// empty string if none does.
+func containingRoot(dirs []string, path string) string {
+	abs := absPath(path)
+	for _, dir := range dirs {
+		if strings.HasPrefix(abs, absPath(dir)+string(filepath.Separator)) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// commonRoot returns the deepest directory containing all of paths, found
+// from their absolute forms so that roots such as "." and "../docs" meet in
+// their real parent. The result is relative to the working directory when
+// every path is relative, and absolute otherwise.
+func commonRoot(paths []string) string {
+	allRelative := true
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			allRelative = false
+		}
+	}
+
+	common := strings.Split(absPath(paths[0]), string(filepath.Separator))
+	for _, path := range paths[1:] {
+		parts := strings.Split(absPath(path), string(filepath.Separator))
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	root := strings.Join(common, string(filepath.Separator))
+	if root == "" {
+		root = string(filepath.Separator)
+	}
+	if allRelative {
+		return pathLike(root, ".")
+	}
+	return root
+}
+
+// pathLike returns path in the same form as like: absolute if like is, and
+// otherwise relative to the working directory.
+func pathLike(path, like string) string {
+	if filepath.IsAbs(like) {
+		return absPath(path)
+	}
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(absPath("."), path); err == nil {
+			return rel
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// absPath returns the absolute form of path, or path itself if that fails.
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,50 @@
+package catls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// relativeAgePattern matches ages in days or weeks, which time.ParseDuration
+// does not accept.
+var relativeAgePattern = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// timeBoundLayouts are the absolute time formats accepted by ParseTimeBound,
+// interpreted in local time unless they carry a zone.
+var timeBoundLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeBound parses a --newer-than or --older-than value: either an
+// absolute date or time such as 2024-01-01, or an age relative to now such
+// as 7d, 2w, or 36h.
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	if match := relativeAgePattern.FindStringSubmatch(value); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid age %q: %w", value, err)
+		}
+		days := n
+		if match[2] == "w" {
+			days *= 7
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+
+	if age, err := time.ParseDuration(value); err == nil {
+		return now.Add(-age), nil
+	}
+
+	for _, layout := range timeBoundLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a date like 2024-01-01 or an age like 7d, 2w, or 36h)", value)
+}
@@ -0,0 +1,66 @@
+package catls
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenPiecePattern approximates the cl100k_base pre-tokenizer split:
+// contractions, words with an optional leading symbol, digit groups of up
+// to three, punctuation runs, and whitespace.
+var tokenPiecePattern = regexp.MustCompile(
+	`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\pL\pN]?\pL+|\pN{1,3}| ?[^\s\pL\pN]+[\r\n]*|\s*[\r\n]+|\s+`,
+)
+
+const (
+	// wholePieceLen is the longest piece assumed to be a single vocabulary
+	// token; common words and operators almost always are.
+	wholePieceLen = 6
+	// charsPerToken is the average number of characters BPE merges longer,
+	// rarer pieces into.
+	charsPerToken = 4
+)
+
+// EstimateTokens returns an approximate cl100k_base token count for text.
+// It splits text the same way the real tokenizer does before byte-pair
+// merging, counts short pieces as one token, and assumes longer pieces
+// merge into roughly four-character tokens.
+func EstimateTokens(text string) int {
+	tokens := 0
+	for _, piece := range tokenPiecePattern.FindAllString(text, -1) {
+		n := utf8.RuneCountInString(piece)
+		if n <= wholePieceLen {
+			tokens++
+			continue
+		}
+		tokens += (n + charsPerToken - 1) / charsPerToken
+	}
+	return tokens
+}
+
+// countFileTokens estimates the tokens of the lines a processed file will
+// actually emit.
+func countFileTokens(file ProcessedFile) int {
+	lines := make([]string, len(file.Lines))
+	for i, line := range file.Lines {
+		lines[i] = line.Content
+	}
+	return EstimateTokens(strings.Join(lines, "\n"))
+}
+
+// tokenTally accumulates per-file token estimates so a formatter can report
+// the grand total in its footer.
+type tokenTally struct {
+	enabled bool
+	total   int
+}
+
+// add records a file's token estimate when token counting is enabled.
+func (t *tokenTally) add(file ProcessedFile, cfg *Config) {
+	if !cfg.CountTokens {
+		return
+	}
+	t.enabled = true
+	t.total += file.Tokens
+}
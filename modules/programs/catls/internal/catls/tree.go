@@ -0,0 +1,125 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// treeNode is a directory or file in the structure-only tree view.
+type treeNode struct {
+	name     string
+	file     *ProcessedFile
+	children map[string]*treeNode
+}
+
+// newTreeNode creates an empty directory node.
+func newTreeNode(name string) *treeNode {
+	return &treeNode{
+		name:     name,
+		children: make(map[string]*treeNode),
+	}
+}
+
+// insert adds a processed file to the tree under its relative path.
+func (n *treeNode) insert(file ProcessedFile) {
+	parts := strings.Split(strings.TrimPrefix(file.Info.RelPath, "/"), "/")
+	current := n
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := current.children[part]
+		if !ok {
+			child = newTreeNode(part)
+			current.children[part] = child
+		}
+		current = child
+	}
+
+	leaf := parts[len(parts)-1]
+	current.children[leaf] = &treeNode{name: leaf, file: &file}
+}
+
+// sortedChildren returns the node's children ordered by name.
+func (n *treeNode) sortedChildren() []*treeNode {
+	children := make([]*treeNode, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+// writeTree prints the directory hierarchy of the included files with
+// per-file line counts and sizes, without any file contents.
+func (a *App) writeTree(ctx context.Context, files []ProcessedFile) error {
+	label := a.cfg.Directory
+	if a.cfg.RelativeTo != "" {
+		label = a.cfg.RelativeTo
+	}
+
+	root := newTreeNode(label)
+	for _, file := range files {
+		root.insert(file)
+	}
+
+	fmt.Fprintln(a.out, root.name)
+	return writeTreeChildren(ctx, a.out, root, "")
+}
+
+// writeTreeChildren prints the children of a node using box-drawing prefixes.
+func writeTreeChildren(ctx context.Context, w io.Writer, node *treeNode, prefix string) error {
+	children := node.sortedChildren()
+	for i, child := range children {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		connector, childPrefix := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+
+		if child.file == nil {
+			fmt.Fprintf(w, "%s%s%s/\n", prefix, connector, child.name)
+			if err := writeTreeChildren(ctx, w, child, prefix+childPrefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, connector, child.name, treeFileSummary(*child.file))
+	}
+	return nil
+}
+
+// treeFileSummary describes a file's line count and size for the tree view.
+func treeFileSummary(file ProcessedFile) string {
+	switch {
+	case file.Error != nil:
+		return fmt.Sprintf("error: %v", file.Error)
+	case file.Info.IsBinary:
+		return fmt.Sprintf("binary, %s", formatSize(file.Info.Size))
+	default:
+		return fmt.Sprintf("%d lines, %s", file.TotalLines, formatSize(file.Info.Size))
+	}
+}
+
+// formatSize renders a byte count using binary units.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
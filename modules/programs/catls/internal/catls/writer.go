@@ -0,0 +1,118 @@
+package catls
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported --compress formats.
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// compressionExtensions maps output file extensions to the compression they
+// enable when --compress is not given.
+var compressionExtensions = map[string]string{
+	".gz":  CompressGzip,
+	".zst": CompressZstd,
+}
+
+// zstdSkippableMagic starts a zstd skippable frame, which decoders ignore,
+// used to carry the manifest alongside the compressed data.
+const zstdSkippableMagic = 0x184D2A50
+
+// createOutputFile creates (or truncates) the file at path, creating any
+// missing parent directories.
+func createOutputFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return file, nil
+}
+
+// chunkOutputPath returns the numbered file name for one chunk of output,
+// e.g. "out.xml" becomes "out-002.xml" and "out.xml.gz" becomes
+// "out-002.xml.gz".
+func chunkOutputPath(path string, index int) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	if _, ok := compressionExtensions[ext]; ok {
+		inner := filepath.Ext(base)
+		base = strings.TrimSuffix(base, inner)
+		ext = inner + ext
+	}
+	return fmt.Sprintf("%s-%03d%s", base, index, ext)
+}
+
+// compressionManifest describes a compressed catls stream. It is stored in
+// the gzip header comment or a leading zstd skippable frame so tools can
+// tell what the stream holds without decompressing it.
+type compressionManifest struct {
+	Generator   string `json:"generator"`
+	Format      string `json:"format"`
+	Compression string `json:"compression"`
+}
+
+// compressWriter wraps out in the configured compressor. The returned writer
+// must be closed to complete the compressed stream; without compression
+// closing it is a no-op.
+func (a *App) compressWriter(out io.Writer) (io.WriteCloser, error) {
+	if a.cfg.Compress == "" {
+		return nopWriteCloser{out}, nil
+	}
+
+	manifest, err := json.Marshal(compressionManifest{
+		Generator:   "catls",
+		Format:      a.cfg.OutputFormat.String(),
+		Compression: a.cfg.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.cfg.Compress {
+	case CompressGzip:
+		writer := gzip.NewWriter(out)
+		writer.Comment = string(manifest)
+		if a.cfg.Output != "" {
+			writer.Name = strings.TrimSuffix(filepath.Base(a.cfg.Output), ".gz")
+		}
+		return writer, nil
+	case CompressZstd:
+		header := make([]byte, 8)
+		binary.LittleEndian.PutUint32(header, zstdSkippableMagic)
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(manifest)))
+		if _, err := out.Write(append(header, manifest...)); err != nil {
+			return nil, fmt.Errorf("failed to write output: %w", err)
+		}
+		return zstd.NewWriter(out)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", a.cfg.Compress)
+	}
+}
+
+// nopWriteCloser adds a no-op Close to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close implements io.Closer.
+func (nopWriteCloser) Close() error {
+	return nil
+}
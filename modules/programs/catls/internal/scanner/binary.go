@@ -5,32 +5,76 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
+// Binary detection strategies.
+const (
+	// BinaryDetectionFile asks the file command and falls back to byte
+	// analysis when it is unavailable.
+	BinaryDetectionFile = "file"
+	// BinaryDetectionBytes only inspects the leading bytes of each file.
+	BinaryDetectionBytes = "bytes"
+)
+
+// defaultSampleSize is how many leading bytes byte analysis inspects.
+const defaultSampleSize = 1024
+
+// utf16BOMs are the byte order marks of UTF-16 text, whose many NUL bytes
+// would otherwise look binary.
+var utf16BOMs = [][]byte{{0xFF, 0xFE}, {0xFE, 0xFF}}
+
 // BinaryDetector defines the interface for detecting binary files.
 type BinaryDetector interface {
 	IsBinary(path string) bool
 }
 
+// BinaryOptions configures how FileBinaryDetector classifies files.
+type BinaryOptions struct {
+	Strategy         string   // BinaryDetectionFile (default) or BinaryDetectionBytes
+	SampleSize       int      // Leading bytes inspected by byte analysis (0 means 1024)
+	NullThreshold    float64  // Fraction of NUL bytes in the sample above which a file is binary
+	TextExtensions   []string // Extensions always treated as text, e.g. "txt" or ".csv"
+	BinaryExtensions []string // Extensions always treated as binary
+}
+
 // FileBinaryDetector implements BinaryDetector using file command and byte analysis.
-type FileBinaryDetector struct{}
+type FileBinaryDetector struct {
+	Options BinaryOptions
+}
 
-// IsBinary detects if a file is binary using the file command as primary method
-// and falls back to byte analysis.
+// IsBinary detects if a file is binary. Extension overrides are checked
+// first; then, depending on the strategy, the file command is tried before
+// falling back to byte analysis.
 func (d *FileBinaryDetector) IsBinary(path string) bool {
-	// Try using the file command first
-	cmd := exec.Command("file", path)
-	output, err := cmd.Output()
-	if err == nil {
-		return !strings.Contains(strings.ToLower(string(output)), "text")
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext != "" {
+		if hasExtension(d.Options.TextExtensions, ext) {
+			return false
+		}
+		if hasExtension(d.Options.BinaryExtensions, ext) {
+			return true
+		}
+	}
+
+	if d.Options.Strategy != BinaryDetectionBytes {
+		// Try using the file command first
+		cmd := exec.Command("file", path)
+		output, err := cmd.Output()
+		if err == nil {
+			return !strings.Contains(strings.ToLower(string(output)), "text")
+		}
 	}
 
 	// Fallback to byte analysis
 	return d.isBinaryByBytes(path)
 }
 
-// isBinaryByBytes checks for null bytes in the first 1024 bytes of a file.
+// isBinaryByBytes checks the share of NUL bytes in the first SampleSize
+// bytes of a file against NullThreshold. UTF-16 text with a byte order mark
+// is never binary.
 func (d *FileBinaryDetector) isBinaryByBytes(path string) bool {
 	file, err := os.Open(path)
 	if err != nil {
@@ -43,11 +87,32 @@ func (d *FileBinaryDetector) isBinaryByBytes(path string) bool {
 		}
 	}()
 
-	chunk := make([]byte, 1024)
+	sampleSize := d.Options.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
+
+	chunk := make([]byte, sampleSize)
 	n, err := file.Read(chunk)
 	if err != nil {
 		return true
 	}
+	chunk = chunk[:n]
+
+	for _, bom := range utf16BOMs {
+		if bytes.HasPrefix(chunk, bom) {
+			return false
+		}
+	}
 
-	return bytes.Contains(chunk[:n], []byte{0})
-}
\ No newline at end of file
+	nulls := bytes.Count(chunk, []byte{0})
+	return nulls > 0 && float64(nulls)/float64(n) > d.Options.NullThreshold
+}
+
+// hasExtension reports whether ext is in exts, ignoring case and any
+// leading dot in exts.
+func hasExtension(exts []string, ext string) bool {
+	return slices.ContainsFunc(exts, func(candidate string) bool {
+		return strings.EqualFold(strings.TrimPrefix(candidate, "."), ext)
+	})
+}
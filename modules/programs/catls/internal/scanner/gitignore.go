@@ -0,0 +1,240 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitIgnoreRule is a single compiled pattern from a gitignore file.
+type gitIgnoreRule struct {
+	base    string // Directory of the defining file, relative to the repo root
+	regex   *regexp.Regexp
+	negate  bool // Pattern started with "!"
+	dirOnly bool // Pattern ended with "/"
+}
+
+// GitIgnore matches paths against the .gitignore files of a git repository
+// and its .git/info/exclude file, following git's precedence rules: deeper
+// files override shallower ones and later patterns override earlier ones.
+type GitIgnore struct {
+	root   string
+	rules  []gitIgnoreRule
+	loaded map[string]bool
+}
+
+// FindGitRoot returns the root of the git work tree containing dir, or an
+// empty string if dir is not inside a git repository.
+func FindGitRoot(dir string) string {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
+}
+
+// NewGitIgnore creates a matcher for the repository rooted at root and loads
+// its .git/info/exclude file.
+func NewGitIgnore(root string) *GitIgnore {
+	g := &GitIgnore{
+		root:   root,
+		loaded: make(map[string]bool),
+	}
+	g.loadFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	return g
+}
+
+// LoadDir loads the .gitignore file in dir, if any. Directories are only
+// loaded once, and directories outside the repository are ignored.
+func (g *GitIgnore) LoadDir(dir string) {
+	rel, ok := g.relative(dir)
+	if !ok || g.loaded[rel] {
+		return
+	}
+	g.loaded[rel] = true
+
+	if rel == "." {
+		rel = ""
+	}
+	g.loadFile(filepath.Join(dir, ".gitignore"), rel)
+}
+
+// LoadParents loads the .gitignore files of every directory from the
+// repository root down to and including dir.
+func (g *GitIgnore) LoadParents(dir string) {
+	rel, ok := g.relative(dir)
+	if !ok {
+		return
+	}
+
+	current := g.root
+	g.LoadDir(current)
+	if rel == "." {
+		return
+	}
+	for _, part := range strings.Split(rel, "/") {
+		current = filepath.Join(current, part)
+		g.LoadDir(current)
+	}
+}
+
+// Match reports whether path is ignored by the loaded gitignore rules.
+func (g *GitIgnore) Match(path string, isDir bool) bool {
+	rel, ok := g.relative(path)
+	if !ok || rel == "." {
+		return false
+	}
+
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		candidate := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			candidate = strings.TrimPrefix(rel, rule.base+"/")
+		}
+
+		if rule.regex.MatchString(candidate) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// relative returns path relative to the repository root using forward
+// slashes, and whether path lies inside the repository.
+func (g *GitIgnore) relative(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(g.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// loadFile parses a gitignore-format file whose patterns are relative to base.
+func (g *GitIgnore) loadFile(path, base string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", path, closeErr)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseGitIgnoreLine(scanner.Text(), base); ok {
+			g.rules = append(g.rules, rule)
+		}
+	}
+}
+
+// parseGitIgnoreLine compiles one line of a gitignore file.
+func parseGitIgnoreLine(line, base string) (gitIgnoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(strings.TrimSuffix(line, `\ `), " ") + " "
+	} else {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitIgnoreRule{}, false
+	}
+
+	rule := gitIgnoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return gitIgnoreRule{}, false
+	}
+
+	// Patterns without a slash match a name at any depth; patterns with one
+	// are anchored to the directory of the gitignore file.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := GlobToRegex(line)
+	if !anchored {
+		expr = `(?:^|.*/)` + expr
+	}
+
+	regex, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return gitIgnoreRule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// GlobToRegex converts a gitignore-style glob to an unanchored regular
+// expression. "*" and "?" never match "/", "[...]" is a character class,
+// and "**" as a whole path segment matches zero or more directories.
+func GlobToRegex(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			sb.WriteString(`(?:.*/)?`)
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern) && (i == 0 || pattern[i-1] == '/'):
+			sb.WriteString(`.*`)
+			i++
+		case c == '*':
+			sb.WriteString(`[^/]*`)
+		case c == '?':
+			sb.WriteString(`[^/]`)
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
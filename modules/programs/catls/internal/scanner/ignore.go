@@ -1,9 +1,11 @@
 package scanner
 
 import (
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // shouldIgnoreDir determines if a directory should be ignored.
@@ -20,14 +22,17 @@ func (s *Scanner) shouldIgnoreDir(dirPath string, cfg Config) bool {
 		}
 	}
 
-	// Check ignore globs
-	for _, pattern := range cfg.IgnoreGlobs {
-		if MatchesGlobPattern(dirPath, pattern) {
-			return true
-		}
+	// Check ignore globs. A negated pattern may re-include files below an
+	// ignored directory, so directories are only pruned without negations;
+	// their files are still filtered one by one afterwards.
+	if HasNegatedGlob(cfg.IgnoreGlobs) {
+		return false
 	}
-
-	return false
+	relPath, err := s.getRelativePath(dirPath, cfg)
+	if err != nil {
+		relPath = dirPath
+	}
+	return selectedByGlobs(relPath, cfg.IgnoreGlobs, false, true)
 }
 
 // matchesIgnoreDir checks if a directory matches an ignore pattern.
@@ -62,29 +67,115 @@ func (s *Scanner) matchesIgnoreDir(dirPath, realDirPath, ignoreDir string) bool
 	return false
 }
 
-// MatchesGlobPattern checks if a file path matches a glob pattern.
+// globCache holds compiled path glob patterns, which are matched against
+// every scanned file.
+var globCache sync.Map // pattern -> *regexp.Regexp
+
+// MatchesGlobPattern checks if a relative file path matches a glob pattern.
+// Patterns follow gitignore conventions: "*" and "?" never cross "/", "**"
+// as a whole segment spans any number of directories, and "[...]" is a
+// character class. A pattern without a slash matches a file or directory
+// name at any depth; one with a slash is anchored to the start of the path,
+// so "src/*.go" matches only direct children of src while "**/testdata/*.go"
+// matches at any depth. A pattern that names a directory matches everything
+// below it, and a trailing "/" matches directories only.
 func MatchesGlobPattern(filePath, pattern string) bool {
-	regexPattern := WildcardToRegex(pattern)
-	regex, err := regexp.Compile(regexPattern)
-	if err != nil {
+	return matchesGlob(filePath, pattern, false)
+}
+
+// matchesGlob matches a glob against a file path, or a directory path if
+// isDir is set.
+func matchesGlob(filePath, pattern string, isDir bool) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	regex := compileGlob(pattern)
+	if regex == nil {
 		return false
 	}
-	
-	// Try matching against both full path and just filename
-	if regex.MatchString(filePath) {
-		return true
+
+	filePath = filepath.ToSlash(filePath)
+	if dirOnly && !isDir {
+		// Only the directories above the file can match
+		if filePath = path.Dir(filePath); filePath == "." {
+			return false
+		}
+	}
+
+	if strings.Contains(pattern, "/") {
+		return regex.MatchString(filePath)
 	}
-	
-	// Extract filename and try matching
-	if idx := strings.LastIndex(filePath, "/"); idx >= 0 {
-		filename := filePath[idx+1:]
-		return regex.MatchString(filename)
+	for _, segment := range strings.Split(filePath, "/") {
+		if regex.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectedByGlobs evaluates an ordered list of patterns, in which a leading
+// "!" negates a pattern, against a file path. As in gitignore, the last
+// pattern that matches decides: true for a plain pattern, false for a
+// negated one. If no pattern matches the result is def.
+func SelectedByGlobs(filePath string, patterns []string, def bool) bool {
+	return selectedByGlobs(filePath, patterns, def, false)
+}
+
+// selectedByGlobs is SelectedByGlobs for a file path, or a directory path if
+// isDir is set.
+func selectedByGlobs(filePath string, patterns []string, def, isDir bool) bool {
+	selected := def
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if matchesGlob(filePath, strings.TrimPrefix(pattern, "!"), isDir) {
+			selected = !negated
+		}
+	}
+	return selected
+}
+
+// HasPositiveGlob reports whether patterns contains a pattern that is not
+// negated.
+func HasPositiveGlob(patterns []string) bool {
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "!") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNegatedGlob reports whether patterns contains a negated pattern.
+func HasNegatedGlob(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob returns the anchored regular expression for a path glob, or
+// nil if the pattern is invalid.
+func compileGlob(pattern string) *regexp.Regexp {
+	if cached, ok := globCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	expr := GlobToRegex(strings.TrimPrefix(pattern, "/"))
+	if !strings.HasSuffix(pattern, "**") {
+		expr += "(?:/.*)?"
+	}
+
+	regex, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil
 	}
-	
-	return regex.MatchString(filePath)
+	globCache.Store(pattern, regex)
+	return regex
 }
 
-// WildcardToRegex converts a glob pattern to a regex pattern.
+// WildcardToRegex converts a content --pattern to a regex pattern. Unlike
+// path globs, "*" matches any run of characters.
 func WildcardToRegex(pattern string) string {
 	escaped := regexp.QuoteMeta(pattern)
 	escaped = strings.ReplaceAll(escaped, `\*`, `.*`)
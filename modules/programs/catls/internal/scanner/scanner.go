@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // FileInfo represents information about a discovered file.
@@ -15,17 +18,25 @@ type FileInfo struct {
 	Path     string // Path to the file
 	RelPath  string // Relative path to the file
 	IsBinary bool   // Whether the file is a binary file.
+	Size     int64  // Size of the file in bytes
+	Explicit bool   // Whether the file was named explicitly rather than discovered
 }
 
 // Config holds scanner configuration.
 type Config struct {
-	Directory   string   // Directory to scan
-	ShowAll     bool     // ShowAll option
-	Recursive   bool     // Recursive option
-	IgnoreDir   []string // IgnoreDir option
-	IgnoreGlobs []string // IgnoreGlobs option
-	Debug       bool     // Debug logging
-	RelativeTo  string   // Base directory for relative paths (empty means use Directory)
+	Directory   string    // Directory to scan
+	ShowAll     bool      // ShowAll option
+	Recursive   bool      // Recursive option
+	MaxDepth    int       // Directory levels to descend, 1 being Directory itself (0 means use Recursive)
+	IgnoreDir   []string  // IgnoreDir option
+	IgnoreGlobs []string  // IgnoreGlobs option
+	Debug       bool      // Debug logging
+	RelativeTo  string    // Base directory for relative paths (empty means use Directory)
+	GitIgnore   bool      // Respect .gitignore files when inside a git repository
+	Jobs        int       // Number of files to inspect concurrently (0 means one per CPU)
+	NewerThan   time.Time // Only include files modified after this time (zero means no limit)
+	OlderThan   time.Time // Only include files modified before this time (zero means no limit)
+	ExcludeFile []string  // Absolute paths of files to leave out, such as the output being written
 }
 
 // Scanner handles file discovery and filtering.
@@ -33,10 +44,16 @@ type Scanner struct {
 	binaryDetector BinaryDetector
 }
 
-// New creates a new scanner.
+// New creates a new scanner with the default binary detection.
 func New() *Scanner {
+	return NewWithBinaryOptions(BinaryOptions{})
+}
+
+// NewWithBinaryOptions creates a new scanner whose binary detection is
+// configured by opts.
+func NewWithBinaryOptions(opts BinaryOptions) *Scanner {
 	return &Scanner{
-		binaryDetector: &FileBinaryDetector{},
+		binaryDetector: &FileBinaryDetector{Options: opts},
 	}
 }
 
@@ -47,12 +64,28 @@ func (s *Scanner) Scan(ctx context.Context, cfg Config) ([]FileInfo, error) {
 	if cfg.Recursive {
 		maxDepth = -1
 	}
+	if cfg.MaxDepth > 0 {
+		maxDepth = cfg.MaxDepth
+	}
 
 	type dirEntry struct {
 		path  string
 		depth int
 	}
 
+	var gitIgnore *GitIgnore
+	if cfg.GitIgnore {
+		if root := FindGitRoot(cfg.Directory); root != "" {
+			gitIgnore = NewGitIgnore(root)
+			gitIgnore.LoadParents(cfg.Directory)
+		}
+	}
+
+	excluded := make(map[string]bool, len(cfg.ExcludeFile))
+	for _, path := range cfg.ExcludeFile {
+		excluded[path] = true
+	}
+
 	stack := []dirEntry{{cfg.Directory, 0}}
 
 	for len(stack) > 0 {
@@ -77,6 +110,10 @@ func (s *Scanner) Scan(ctx context.Context, cfg Config) ([]FileInfo, error) {
 			continue
 		}
 
+		if gitIgnore != nil {
+			gitIgnore.LoadDir(current.path)
+		}
+
 		// Sort entries for consistent output
 		var entryNames []string
 		for _, entry := range entries {
@@ -100,6 +137,13 @@ func (s *Scanner) Scan(ctx context.Context, cfg Config) ([]FileInfo, error) {
 				continue
 			}
 
+			if gitIgnore != nil && gitIgnore.Match(fullPath, info.IsDir()) {
+				if cfg.Debug {
+					fmt.Fprintf(os.Stderr, "Debug: Ignoring gitignored path: %s\n", fullPath)
+				}
+				continue
+			}
+
 			if info.IsDir() {
 				if !s.shouldIgnoreDir(fullPath, cfg) {
 					stack = append(stack, dirEntry{fullPath, current.depth + 1})
@@ -107,22 +151,40 @@ func (s *Scanner) Scan(ctx context.Context, cfg Config) ([]FileInfo, error) {
 					fmt.Fprintf(os.Stderr, "Debug: Ignoring directory: %s\n", fullPath)
 				}
 			} else if info.Mode().IsRegular() {
+				if !cfg.modifiedInRange(info.ModTime()) {
+					if cfg.Debug {
+						fmt.Fprintf(os.Stderr, "Debug: Skipping file outside modification time range: %s\n", fullPath)
+					}
+					continue
+				}
+
+				if len(excluded) > 0 {
+					if abs, err := filepath.Abs(fullPath); err == nil && excluded[abs] {
+						if cfg.Debug {
+							fmt.Fprintf(os.Stderr, "Debug: Skipping output file: %s\n", fullPath)
+						}
+						continue
+					}
+				}
+
 				relPath, err := s.getRelativePath(fullPath, cfg)
 				if err != nil {
 					continue
 				}
 
-				isBinary := s.binaryDetector.IsBinary(fullPath)
-
 				files = append(files, FileInfo{
-					Path:     fullPath,
-					RelPath:  relPath,
-					IsBinary: isBinary,
+					Path:    fullPath,
+					RelPath: relPath,
+					Size:    info.Size(),
 				})
 			}
 		}
 	}
 
+	if err := s.detectBinaries(ctx, files, cfg.Jobs); err != nil {
+		return nil, err
+	}
+
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].RelPath < files[j].RelPath
 	})
@@ -130,6 +192,73 @@ func (s *Scanner) Scan(ctx context.Context, cfg Config) ([]FileInfo, error) {
 	return files, nil
 }
 
+// modifiedInRange reports whether a modification time satisfies the
+// NewerThan and OlderThan limits.
+func (cfg Config) modifiedInRange(modTime time.Time) bool {
+	if !cfg.NewerThan.IsZero() && !modTime.After(cfg.NewerThan) {
+		return false
+	}
+	if !cfg.OlderThan.IsZero() && !modTime.Before(cfg.OlderThan) {
+		return false
+	}
+	return true
+}
+
+// detectBinaries runs binary detection for files on a bounded pool of
+// workers, since it reads from (or spawns a process for) every file.
+func (s *Scanner) detectBinaries(ctx context.Context, files []FileInfo, jobs int) error {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				files[i].IsBinary = s.binaryDetector.IsBinary(files[i].Path)
+			}
+		}()
+	}
+
+	defer wg.Wait()
+	defer close(indexes)
+	for i := range files {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ScanFile returns the FileInfo for a single explicitly named file.
+func (s *Scanner) ScanFile(path string, cfg Config) (FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return FileInfo{}, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	relPath, err := s.getRelativePath(path, cfg)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	return FileInfo{
+		Path:     path,
+		RelPath:  relPath,
+		IsBinary: s.binaryDetector.IsBinary(path),
+		Size:     info.Size(),
+		Explicit: true,
+	}, nil
+}
+
 // getRelativePath returns the relative path from base directory.
 func (s *Scanner) getRelativePath(fullPath string, cfg Config) (string, error) {
 	baseDir := cfg.Directory
@@ -143,5 +272,19 @@ func (s *Scanner) getRelativePath(fullPath string, cfg Config) (string, error) {
 		return fullPath, nil
 	}
 
-	return filepath.Rel(baseDir, fullPath)
+	if rel, err := filepath.Rel(baseDir, fullPath); err == nil {
+		return rel, nil
+	}
+
+	// Paths of different forms, such as an absolute --relative-to with a
+	// relative directory, are compared in absolute form
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+	full, err := filepath.Abs(fullPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(base, full)
 }
@@ -1,8 +1,12 @@
 package scanner
 
 import (
+	"context"
+	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetRelativePath(t *testing.T) {
@@ -157,3 +161,336 @@ func TestGetRelativePathWithTempDir(t *testing.T) {
 		}
 	})
 }
+
+func TestGitIgnoreMatch(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	writeFile(".git/info/exclude", "*.local\n")
+	writeFile(".gitignore", "# comment\n*.log\n!keep.log\nbuild/\n/root-only.txt\ndocs/**/draft.md\n")
+	writeFile("sub/.gitignore", "generated.go\n")
+
+	g := NewGitIgnore(root)
+	g.LoadParents(filepath.Join(root, "sub"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"debug.log", false, true},
+		{"nested/dir/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"docs/draft.md", false, true},
+		{"docs/a/b/draft.md", false, true},
+		{"settings.local", false, true},
+		{"sub/generated.go", false, true},
+		{"generated.go", false, false},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := g.Match(filepath.Join(root, tt.path), tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesGlobPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"main.go", "*.go", true},
+		{"cmd/app/main.go", "*.go", true},
+		{"main.gotmpl", "*.go", false},
+		{"src/main.go", "src/*.go", true},
+		{"src/pkg/main.go", "src/*.go", false},
+		{"lib/src/main.go", "src/*.go", false},
+		{"src/pkg/main.go", "src/**/*.go", true},
+		{"src/main.go", "src/**/*.go", true},
+		{"a/testdata/b/c.txt", "**/testdata/**", true},
+		{"testdata/c.txt", "**/testdata/**", true},
+		{"vendor/x/y.go", "vendor", true},
+		{"pkg/vendor/y.go", "vendor", true},
+		{"build/out.txt", "build/", true},
+		{"build", "build/", false},
+		{"a/build/out.txt", "build/", true},
+		{"docs/a.md", "/docs", true},
+		{"x/docs/a.md", "/docs", false},
+		{"file1.txt", "file?.txt", true},
+		{"file10.txt", "file?.txt", false},
+		{"a.c", "*.[ch]", true},
+		{"a.go", "*.[ch]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := MatchesGlobPattern(tt.path, tt.pattern); got != tt.want {
+				t.Errorf("MatchesGlobPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectedByGlobs(t *testing.T) {
+	patterns := []string{"*.go", "!*_test.go", "testutil_test.go"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", true},
+		{"main_test.go", false},
+		{"pkg/testutil_test.go", true},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := SelectedByGlobs(tt.path, patterns, false); got != tt.want {
+			t.Errorf("SelectedByGlobs(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !SelectedByGlobs("README.md", []string{"!*.go"}, true) {
+		t.Error("SelectedByGlobs() should fall back to the default when nothing matches")
+	}
+}
+
+func TestScanIgnoreGlobNegation(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"main.go", "gen/a.go", "gen/keep.go"} {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("package x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	scan := func(ignoreGlobs ...string) []string {
+		files, err := New().Scan(context.Background(), Config{
+			Directory:   root,
+			Recursive:   true,
+			IgnoreGlobs: ignoreGlobs,
+		})
+		if err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.RelPath)
+		}
+		return paths
+	}
+
+	// Without negations an ignored directory is pruned; with one it is
+	// still walked so the re-included file can be found
+	if got, want := scan("gen/"), []string{"main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %v, want %v", got, want)
+	}
+	if got, want := scan("gen/", "!gen/keep.go"), []string{"gen/a.go", "gen/keep.go", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() with negation = %v, want %v", got, want)
+	}
+}
+
+func TestScanRespectsGitIgnore(t *testing.T) {
+	root := t.TempDir()
+
+	for path, content := range map[string]string{
+		".gitignore":      "ignored/\n*.tmp\n",
+		"main.go":         "package main",
+		"scratch.tmp":     "scratch",
+		"ignored/file.go": "package ignored",
+	} {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+
+	scan := func(gitIgnore bool) []string {
+		files, err := New().Scan(context.Background(), Config{
+			Directory: root,
+			Recursive: true,
+			GitIgnore: gitIgnore,
+		})
+		if err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.RelPath)
+		}
+		return paths
+	}
+
+	if got, want := scan(true), []string{"main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() with gitignore = %v, want %v", got, want)
+	}
+	if got, want := scan(false), []string{"ignored/file.go", "main.go", "scratch.tmp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() without gitignore = %v, want %v", got, want)
+	}
+}
+
+func TestScanMaxDepth(t *testing.T) {
+	root := t.TempDir()
+
+	for _, path := range []string{"top.go", "a/mid.go", "a/b/deep.go", "a/b/c/deeper.go"} {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("package x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		maxDepth  int
+		want      []string
+	}{
+		{"not recursive", false, 0, []string{"top.go"}},
+		{"recursive", true, 0, []string{"a/b/c/deeper.go", "a/b/deep.go", "a/mid.go", "top.go"}},
+		{"two levels", false, 2, []string{"a/mid.go", "top.go"}},
+		{"depth limits recursive", true, 3, []string{"a/b/deep.go", "a/mid.go", "top.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := New().Scan(context.Background(), Config{
+				Directory: root,
+				Recursive: tt.recursive,
+				MaxDepth:  tt.maxDepth,
+			})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.RelPath)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Scan() = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanModificationTime(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+
+	for name, age := range map[string]time.Duration{"new.go": time.Hour, "week.go": 8 * 24 * time.Hour, "old.go": 400 * 24 * time.Hour} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte("package x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		modTime := now.Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set times of %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		newerThan time.Time
+		olderThan time.Time
+		want      []string
+	}{
+		{"no limits", time.Time{}, time.Time{}, []string{"new.go", "old.go", "week.go"}},
+		{"newer than a week", now.AddDate(0, 0, -7), time.Time{}, []string{"new.go"}},
+		{"older than a week", time.Time{}, now.AddDate(0, 0, -7), []string{"old.go", "week.go"}},
+		{"between", now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1), []string{"week.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := New().Scan(context.Background(), Config{
+				Directory: root,
+				NewerThan: tt.newerThan,
+				OlderThan: tt.olderThan,
+			})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.RelPath)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Scan() = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileBinaryDetectorOptions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	text := write("notes.txt", []byte("plain text\n"))
+	sparse := write("sparse.dat", append([]byte("mostly text here"), 0))
+	nulls := write("nulls.dat", []byte{0, 0, 0, 'a'})
+	utf16 := write("wide.txt", []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\n', 0})
+	generated := write("model.gen", []byte{0, 1, 2, 3})
+
+	tests := []struct {
+		name string
+		opts BinaryOptions
+		path string
+		want bool
+	}{
+		{"text", BinaryOptions{Strategy: BinaryDetectionBytes}, text, false},
+		{"any NUL is binary by default", BinaryOptions{Strategy: BinaryDetectionBytes}, sparse, true},
+		{"NUL share under threshold", BinaryOptions{Strategy: BinaryDetectionBytes, NullThreshold: 0.1}, sparse, false},
+		{"NUL share over threshold", BinaryOptions{Strategy: BinaryDetectionBytes, NullThreshold: 0.1}, nulls, true},
+		{"NUL outside the sample", BinaryOptions{Strategy: BinaryDetectionBytes, SampleSize: 8}, sparse, false},
+		{"UTF-16 with BOM is text", BinaryOptions{Strategy: BinaryDetectionBytes}, utf16, false},
+		{"treat as text", BinaryOptions{TextExtensions: []string{".gen"}}, generated, false},
+		{"forced binary", BinaryOptions{BinaryExtensions: []string{"TXT"}}, text, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := &FileBinaryDetector{Options: tt.opts}
+			if got := detector.IsBinary(tt.path); got != tt.want {
+				t.Errorf("IsBinary(%s) = %v, want %v", filepath.Base(tt.path), got, tt.want)
+			}
+		})
+	}
+}
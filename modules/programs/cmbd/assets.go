@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// How images referenced by relative paths are handled.
+const (
+	assetsCopy    = "copy"    // Copy them next to the output file
+	assetsRewrite = "rewrite" // Point at them from the output file's location
+	assetsEmbed   = "embed"   // Inline them as base64 data URIs
+)
+
+// htmlImagePattern matches the source of an HTML img tag.
+var htmlImagePattern = regexp.MustCompile(`(<img\s[^>]*?src\s*=\s*["'])([^"']+)(["'])`)
+
+// assetHandler rewrites the image references of the combined document.
+type assetHandler struct {
+	mc        *MarkdownCombiner
+	mode      string
+	inputDir  string
+	outputDir string
+	assetsDir string // Directory images are copied to, relative to outputDir
+
+	// resolved caches the new reference for each image already handled
+	resolved map[string]string
+}
+
+// newAssetHandler returns a handler for the images of the combined document.
+func newAssetHandler(mc *MarkdownCombiner) *assetHandler {
+	stem := strings.TrimSuffix(filepath.Base(mc.outputFile), filepath.Ext(mc.outputFile))
+	if mc.outputFile == stdoutName {
+		stem = "cmbd"
+	}
+
+	return &assetHandler{
+		mc:        mc,
+		mode:      mc.assets,
+		inputDir:  mc.inputDir,
+		outputDir: filepath.Dir(mc.outputFile),
+		assetsDir: stem + "_assets",
+		resolved:  map[string]string{},
+	}
+}
+
+// handleAssets rewrites the relative image references in each section so
+// they still resolve from the output file, copying or embedding the images
+// as asked. Images that cannot be found are left alone with a warning.
+func (mc *MarkdownCombiner) handleAssets(sections []*section) {
+	if mc.assets == "" {
+		return
+	}
+	handler := newAssetHandler(mc)
+
+	for _, s := range sections {
+		lines := strings.Split(s.content, "\n")
+		inFence := false
+		for i, line := range lines {
+			if isFence(line) {
+				inFence = !inFence
+
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			line = inlineLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+				match := inlineLinkPattern.FindStringSubmatch(link)
+				if !strings.HasPrefix(match[1], "!") {
+					return link
+				}
+
+				return match[1] + handler.reference(s.file, match[2]) + match[3]
+			})
+			lines[i] = htmlImagePattern.ReplaceAllStringFunc(line, func(tag string) string {
+				match := htmlImagePattern.FindStringSubmatch(tag)
+
+				return match[1] + handler.reference(s.file, match[2]) + match[3]
+			})
+		}
+		s.content = strings.Join(lines, "\n")
+	}
+}
+
+// reference returns the new reference for an image referenced from file.
+func (h *assetHandler) reference(file *sourceFile, ref string) string {
+	target := strings.Trim(ref, "<>")
+	if schemePattern.MatchString(target) || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") {
+		return ref
+	}
+
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return ref
+	}
+	source := filepath.Clean(filepath.Join(filepath.Dir(file.path), filepath.FromSlash(unescaped)))
+	if resolved, ok := h.resolved[source]; ok {
+		return resolved
+	}
+
+	if info, err := os.Stat(source); err != nil || info.IsDir() {
+		h.mc.logf("Warning: image %s referenced by %s not found\n", target, file.displayPath)
+		h.resolved[source] = ref
+
+		return ref
+	}
+
+	resolved, err := h.resolve(source)
+	if err != nil {
+		h.mc.logf("Warning: leaving image %s referenced by %s: %v\n", target, file.displayPath, err)
+		resolved = ref
+	}
+	h.resolved[source] = resolved
+
+	return resolved
+}
+
+// resolve handles an existing image file according to the mode.
+func (h *assetHandler) resolve(source string) (string, error) {
+	switch h.mode {
+	case assetsEmbed:
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return "", err
+		}
+		mediaType := mime.TypeByExtension(filepath.Ext(source))
+		if mediaType == "" {
+			mediaType = http.DetectContentType(content)
+		}
+
+		return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(content), nil
+	case assetsCopy:
+		// Keep the layout of the input directory so names cannot collide
+		rel, err := filepath.Rel(h.inputDir, source)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(source)
+		}
+		dest := filepath.Join(h.assetsDir, rel)
+		if err := copyFile(source, filepath.Join(h.outputDir, dest)); err != nil {
+			return "", err
+		}
+
+		return escapePath(dest), nil
+	default:
+		absOutputDir, err := filepath.Abs(h.outputDir)
+		if err != nil {
+			return "", err
+		}
+		absSource, err := filepath.Abs(source)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(absOutputDir, absSource)
+		if err != nil {
+			return "", err
+		}
+
+		return escapePath(rel), nil
+	}
+}
+
+// escapePath turns a file path into a URL path usable as a link target.
+func escapePath(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).String()
+}
+
+// copyFile copies the file at src to dst, creating dst's directory.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	//nolint:gosec
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	return out.Close()
+}
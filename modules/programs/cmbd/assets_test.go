@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleAssets(t *testing.T) {
+	logo := "\x89PNG\r\n\x1a\nlogo"
+	page := "![logo](img/logo.png)\n" +
+		"Again: <img src=\"img/logo.png\" alt=\"logo\">\n" +
+		"![gone](missing.png) ![gone again](missing.png)\n" +
+		"```\n![code](img/logo.png)\n```\n" +
+		"![web](https://example.com/x.png) [link](img/logo.png)"
+	other := "![logo](docs/img/logo.png)"
+
+	tests := []struct {
+		mode      string
+		want      string // Reference to the logo in the output
+		wantFiles []string
+	}{
+		{
+			mode:      assetsCopy,
+			want:      "combined_assets/docs/img/logo.png",
+			wantFiles: []string{"combined.md", "combined_assets/docs/img/logo.png"},
+		},
+		{
+			mode:      assetsRewrite,
+			want:      "../in/docs/img/logo.png",
+			wantFiles: []string{"combined.md"},
+		},
+		{
+			mode:      assetsEmbed,
+			want:      "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(logo)),
+			wantFiles: []string{"combined.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			root := t.TempDir()
+			inputDir := filepath.Join(root, "in")
+			outputDir := filepath.Join(root, "out")
+			writeFiles(t, inputDir, map[string]string{"docs/img/logo.png": logo})
+			writeFiles(t, outputDir, map[string]string{"combined.md": ""})
+
+			var log bytes.Buffer
+			mc := &MarkdownCombiner{
+				inputDir:   inputDir,
+				outputFile: filepath.Join(outputDir, "combined.md"),
+				assets:     tt.mode,
+				log:        &log,
+			}
+			sections := []*section{
+				{file: &sourceFile{path: filepath.Join(inputDir, "docs", "page.md"), displayPath: "docs/page.md"}, content: page},
+				{file: &sourceFile{path: filepath.Join(inputDir, "other.md"), displayPath: "other.md"}, content: other},
+			}
+			mc.handleAssets(sections)
+
+			wantPage := "![logo](" + tt.want + ")\n" +
+				"Again: <img src=\"" + tt.want + "\" alt=\"logo\">\n" +
+				"![gone](missing.png) ![gone again](missing.png)\n" +
+				"```\n![code](img/logo.png)\n```\n" +
+				"![web](https://example.com/x.png) [link](img/logo.png)"
+			if sections[0].content != wantPage {
+				t.Errorf("page content = %q, want %q", sections[0].content, wantPage)
+			}
+			if wantOther := "![logo](" + tt.want + ")"; sections[1].content != wantOther {
+				t.Errorf("other content = %q, want %q", sections[1].content, wantOther)
+			}
+
+			// A missing image is reported once, however often it is referenced
+			if n := strings.Count(log.String(), "image missing.png referenced by docs/page.md not found"); n != 1 {
+				t.Errorf("missing image warned %d times, want once; log:\n%s", n, log.String())
+			}
+
+			var files []string
+			err := filepath.WalkDir(outputDir, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(outputDir, path)
+				files = append(files, filepath.ToSlash(rel))
+
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(files, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("output directory holds %q, want %q", files, tt.wantFiles)
+			}
+			if tt.mode == assetsCopy {
+				copied, err := os.ReadFile(filepath.Join(outputDir, "combined_assets", "docs", "img", "logo.png"))
+				if err != nil || string(copied) != logo {
+					t.Errorf("copied image = %q, %v, want %q", copied, err, logo)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleAssetsOff(t *testing.T) {
+	content := "![logo](img/logo.png)"
+	sections := []*section{{file: &sourceFile{path: "docs/page.md"}, content: content}}
+	(&MarkdownCombiner{}).handleAssets(sections)
+	if sections[0].content != content {
+		t.Errorf("content = %q, want %q", sections[0].content, content)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitCommit is the last commit touching a file, for -sort git-date and
+// -changelog.
+type gitCommit struct {
+	date    time.Time
+	subject string
+}
+
+// lastCommit returns the last commit touching a file, or nil if it is not
+// tracked by git.
+func lastCommit(filePath string) *gitCommit {
+	cmd := exec.Command("git", "log", "-1", "--format=%cI%x00%s", "--", filepath.Base(filePath))
+	cmd.Dir = filepath.Dir(filePath)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	date, subject, ok := strings.Cut(strings.TrimSpace(string(out)), "\x00")
+	if !ok {
+		return nil
+	}
+	committed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil
+	}
+
+	return &gitCommit{date: committed, subject: subject}
+}
+
+// changelogEntry returns the line -changelog puts under a section header,
+// giving the date and subject of the file's last commit.
+func changelogEntry(commit *gitCommit) string {
+	return fmt.Sprintf("*%s — %s*", commit.date.Format(time.DateOnly), commit.subject)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangelogEntry(t *testing.T) {
+	tests := []struct {
+		name   string
+		commit gitCommit
+		want   string
+	}{
+		{
+			name:   "date and subject",
+			commit: gitCommit{date: time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC), subject: "Document the setup"},
+			want:   "*2024-05-01 — Document the setup*",
+		},
+		{
+			name:   "date in the committer's zone",
+			commit: gitCommit{date: time.Date(2024, 5, 2, 1, 0, 0, 0, time.FixedZone("", 2*60*60)), subject: "Fix typo"},
+			want:   "*2024-05-02 — Fix typo*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changelogEntry(&tt.commit); got != tt.want {
+				t.Errorf("changelogEntry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortSourceFilesGitDate(t *testing.T) {
+	day := func(d int) *gitCommit {
+		return &gitCommit{date: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)}
+	}
+
+	tests := []struct {
+		name  string
+		files []*sourceFile
+		want  []string
+	}{
+		{
+			name: "oldest commit first",
+			files: []*sourceFile{
+				{path: "c.md", commit: day(3)},
+				{path: "a.md", commit: day(2)},
+				{path: "b.md", commit: day(1)},
+			},
+			want: []string{"b.md", "a.md", "c.md"},
+		},
+		{
+			name: "untracked files last by name",
+			files: []*sourceFile{
+				{path: "z.md"},
+				{path: "b.md", commit: day(5)},
+				{path: "y.md"},
+				{path: "a.md", commit: day(9)},
+			},
+			want: []string{"b.md", "a.md", "y.md", "z.md"},
+		},
+		{
+			name: "files stay grouped by folder",
+			files: []*sourceFile{
+				{path: "guide/new.md", commit: day(1)},
+				{path: "top.md", commit: day(9)},
+				{path: "guide/old.md", commit: day(8)},
+				{path: "api/ref.md", commit: day(2)},
+			},
+			want: []string{"top.md", "api/ref.md", "guide/new.md", "guide/old.md"},
+		},
+		{
+			name: "order front matter comes first",
+			files: []*sourceFile{
+				{path: "a.md", commit: day(1)},
+				{path: "b.md", commit: day(2), frontmatter: map[string]any{"order": 1}},
+			},
+			want: []string{"b.md", "a.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortSourceFiles(tt.files, sortGitDate)
+			var got []string
+			for _, file := range tt.files {
+				got = append(got, file.path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortSourceFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.md":           "Alpha",
+		"b.md":           "Beta",
+		"guide/setup.md": "Setup",
+		"draft.md":       "Draft",
+	})
+	runGit(t, dir, "", "init", "-q")
+	commits := []struct{ file, date, subject string }{
+		{file: "b.md", date: "2024-01-01T10:00:00Z", subject: "Add beta"},
+		{file: "guide/setup.md", date: "2024-01-02T10:00:00Z", subject: "Add setup"},
+		{file: "a.md", date: "2024-01-03T10:00:00Z", subject: "Add alpha"},
+	}
+	for _, c := range commits {
+		runGit(t, dir, c.date, "add", c.file)
+		runGit(t, dir, c.date, "commit", "-q", "-m", c.subject)
+	}
+
+	mc := &MarkdownCombiner{
+		inputDir:     dir,
+		outputFile:   filepath.Join(t.TempDir(), "CHANGELOG.md"),
+		recursive:    true,
+		unlisted:     unlistedAppend,
+		titleSource:  titleFilename,
+		limit:        levelLimit{max: 6, overflow: overflowClamp},
+		headerOffset: 1,
+		sortBy:       sortGitDate,
+		changelog:    true,
+		log:          io.Discard,
+	}
+	if err := mc.combineMarkdownFiles(); err != nil {
+		t.Fatalf("combineMarkdownFiles() unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(mc.outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Top-level files by commit date, the untracked draft after them, then
+	// the guide folder
+	want := strings.Join([]string{
+		"# b", "*2024-01-01 — Add beta*", "Beta",
+		"# a", "*2024-01-03 — Add alpha*", "Alpha",
+		"# draft", "Draft",
+		"# guide", "## setup", "*2024-01-02 — Add setup*", "Setup",
+	}, "\n\n") + "\n\n"
+	if string(got) != want {
+		t.Errorf("changelog output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "strings"
+
+// defaultKeptComments are the comment prefixes -strip-comments keeps by
+// default: directives for cmbd itself and for pandoc.
+const defaultKeptComments = "include:,cmbd:,pandoc"
+
+// stripComments removes HTML comments from markdown content, except those
+// whose text starts with one of the keep prefixes and those in code blocks.
+// Lines left blank by a removed comment are dropped.
+func stripComments(content string, keep []string) string {
+	var out []string
+	inFence, inComment, keeping := false, false, false
+
+	for _, line := range strings.Split(content, "\n") {
+		if !inComment && isFence(line) {
+			inFence = !inFence
+		}
+		if inFence && !inComment {
+			out = append(out, line)
+
+			continue
+		}
+
+		var kept strings.Builder
+		removed := false
+		rest := line
+		for rest != "" {
+			if !inComment {
+				start := strings.Index(rest, "<!--")
+				if start < 0 {
+					kept.WriteString(rest)
+
+					break
+				}
+				kept.WriteString(rest[:start])
+				rest = rest[start:]
+				inComment = true
+				keeping = keepComment(rest[len("<!--"):], keep)
+			}
+
+			// Take the comment up to its end, or the rest of the line
+			chunk := rest
+			if end := strings.Index(rest, "-->"); end >= 0 {
+				chunk = rest[:end+len("-->")]
+				inComment = false
+			}
+			if keeping {
+				kept.WriteString(chunk)
+			} else {
+				removed = true
+			}
+			rest = rest[len(chunk):]
+		}
+
+		if removed && strings.TrimSpace(kept.String()) == "" {
+			continue
+		}
+		out = append(out, kept.String())
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// keepComment reports whether a comment, given the text after its opening,
+// starts with one of the keep prefixes.
+func keepComment(text string, keep []string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range keep {
+		if prefix != "" && strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	keep := strings.Split(defaultKeptComments, ",")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "comment line is dropped",
+			content: "Before\n<!-- note to self -->\nAfter",
+			want:    "Before\nAfter",
+		},
+		{
+			name:    "inline comment",
+			content: "Text <!-- hidden --> more <!-- gone -->",
+			want:    "Text  more ",
+		},
+		{
+			name:    "multi-line comment",
+			content: "Before\n<!--\nline one\nline two\n-->\nAfter",
+			want:    "Before\nAfter",
+		},
+		{
+			name:    "text after a multi-line comment is kept",
+			content: "Before <!-- start\nend --> after",
+			want:    "Before \n after",
+		},
+		{
+			name:    "kept prefixes",
+			content: "<!-- include: part.md -->\n<!-- cmbd:start -->\n<!--pandoc-options -->\n<!-- todo -->",
+			want:    "<!-- include: part.md -->\n<!-- cmbd:start -->\n<!--pandoc-options -->",
+		},
+		{
+			name:    "code blocks are left alone",
+			content: "```html\n<!-- example -->\n```\n<!-- removed -->",
+			want:    "```html\n<!-- example -->\n```",
+		},
+		{
+			name:    "fence inside a comment",
+			content: "<!--\n```\n-->\nText",
+			want:    "Text",
+		},
+		{
+			name:    "blank lines are kept",
+			content: "One\n\nTwo",
+			want:    "One\n\nTwo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripComments(tt.content, keep); got != tt.want {
+				t.Errorf("stripComments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripCommentsWithoutKeptPrefixes(t *testing.T) {
+	got := stripComments("<!-- include: part.md -->\nText", nil)
+	if got != "Text" {
+		t.Errorf("stripComments() = %q, want %q", got, "Text")
+	}
+}
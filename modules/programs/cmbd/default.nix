@@ -72,7 +72,7 @@ Enabled via:
   program = pkgs.buildGoModule {
     name = "cmbd";
     src = ./.;
-    vendorHash = null;
+    vendorHash = "sha256-pe43SLZgYe38nUan2CvpboDvlixp67/EPQStLy8WQ6Q=";
   };
 in
   delib.module {
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// heading is a header of the combined document.
+type heading struct {
+	level  int
+	text   string
+	anchor string
+	bold   bool // Whether it overflowed the maximum level into bold text
+}
+
+// section is a source file's part of the combined document.
+type section struct {
+	file          *sourceFile
+	folders       []heading // Folder headers opened just before the section
+	header        heading
+	headless      bool   // Whether the header naming the file is left out
+	titleFromBody bool   // Whether the title was taken from the file's first heading
+	changelog     string // Last commit shown under the header for -changelog
+	meta          string // Metadata block shown under the header
+	content       string
+
+	// start and end are the byte offsets of the section, from its header to
+	// the end of its content, in the rendered sections
+	start, end int
+
+	// headings are the file's own headings, with their document anchors
+	headings []heading
+
+	// anchors maps the anchors of the file's own headings, as they were in
+	// the file, to their anchors in the combined document
+	anchors map[string]string
+}
+
+// buildSections prepares a section for each file, opening a header for each
+// folder entered since the previous file.
+func (mc *MarkdownCombiner) buildSections(files []*sourceFile) ([]*section, error) {
+	var sections []*section
+	var openDirs []string
+
+	var headerTmpl, metaTmpl *template.Template
+	if mc.headerTmpl != "" {
+		var err error
+		headerTmpl, err = template.New("header").Parse(mc.headerTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing header template: %v", err)
+		}
+	}
+	if mc.sectionMeta != "" {
+		var err error
+		metaTmpl, err = template.New("section-meta").Parse(mc.sectionMeta)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing section metadata template: %v", err)
+		}
+	}
+
+	// Prepare each file's content concurrently; folder headers depend on
+	// the order, so they are added afterwards
+	type prepared struct {
+		section *section
+		title   string
+		err     error
+	}
+	results := parallelMap(len(files), mc.workers(), func(i int) prepared {
+		file := files[i]
+		title, body := sectionTitle(file, mc.titleSource)
+		if mc.noFileHeader {
+			// The first heading stays put without a header to move it to
+			body = file.body
+		}
+		data := newSectionData(file, title)
+		if headerTmpl != nil {
+			var err error
+			title, err = renderHeader(headerTmpl, data)
+			if err != nil {
+				return prepared{err: err}
+			}
+		}
+
+		content, err := processMarkdownFile(body, len(file.dirs)+mc.headerOffset, mc.limit)
+		if err != nil {
+			return prepared{err: err}
+		}
+		s := &section{
+			file:          file,
+			headless:      mc.noFileHeader,
+			titleFromBody: body != file.body,
+			content:       content,
+		}
+
+		if mc.changelog && file.commit != nil {
+			s.changelog = changelogEntry(file.commit)
+		}
+		if metaTmpl != nil {
+			s.meta, err = renderSectionMeta(metaTmpl, data)
+			if err != nil {
+				return prepared{err: err}
+			}
+		}
+
+		return prepared{section: s, title: title}
+	})
+
+	for i, file := range files {
+		if results[i].err != nil {
+			return nil, fmt.Errorf("%s: %v", file.displayPath, results[i].err)
+		}
+		s := results[i].section
+		dirs := file.dirs
+
+		s.header = heading{level: len(dirs) + 1, text: results[i].title}
+		if !s.headless {
+			var err error
+			s.header, err = mc.limit.apply(s.header)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file.displayPath, err)
+			}
+		}
+		for i := commonPrefixLen(openDirs, dirs); i < len(dirs); i++ {
+			folder, err := mc.limit.apply(heading{level: i + 1, text: dirs[i]})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file.displayPath, err)
+			}
+			s.folders = append(s.folders, folder)
+		}
+		openDirs = dirs
+
+		sections = append(sections, s)
+	}
+
+	return sections, nil
+}
+
+// renderHeader renders a file's section title through the header template,
+// joining its lines so the header stays on one.
+func renderHeader(tmpl *template.Template, data *sectionData) (string, error) {
+	var header strings.Builder
+	if err := tmpl.Execute(&header, data); err != nil {
+		return "", fmt.Errorf("error rendering header template: %v", err)
+	}
+
+	return strings.Join(strings.Fields(header.String()), " "), nil
+}
+
+// assignAnchors gives every header in the document a unique anchor, in
+// document order, and records how each file's heading anchors map to them.
+func assignAnchors(sections []*section) {
+	document := newSlugger()
+
+	for _, s := range sections {
+		// Headers turned into bold text have no anchor to link to
+		for i := range s.folders {
+			if !s.folders[i].bold {
+				s.folders[i].anchor = document.slug(s.folders[i].text)
+			}
+		}
+		if !s.headless && !s.header.bold {
+			s.header.anchor = document.slug(s.header.text)
+		}
+
+		// Anchors within the file, as its own renderer would have made them
+		local := newSlugger()
+		s.anchors = map[string]string{}
+		if s.titleFromBody && s.header.anchor != "" {
+			s.anchors[local.slug(s.header.text)] = s.header.anchor
+		}
+		s.headings = nil
+		for _, text := range headingTexts(s.content) {
+			h := heading{text: text, anchor: document.slug(text)}
+			s.anchors[local.slug(text)] = h.anchor
+			s.headings = append(s.headings, h)
+		}
+
+		// Without a header of its own to link to, a file is found at its
+		// first heading
+		if s.header.anchor == "" && len(s.headings) > 0 {
+			s.header.anchor = s.headings[0].anchor
+		}
+	}
+}
+
+// renderSections writes the combined document, with separator, if any,
+// between the files, and records where each section lands in it.
+func renderSections(sections []*section, separator string) string {
+	var combinedContent strings.Builder
+
+	for i, s := range sections {
+		if i > 0 && separator != "" {
+			combinedContent.WriteString(separator + "\n\n")
+		}
+
+		for _, folder := range s.folders {
+			combinedContent.WriteString(folder.markdown() + "\n\n")
+		}
+
+		// Add the title as a header one level below its folder
+		s.start = combinedContent.Len()
+		if !s.headless {
+			combinedContent.WriteString(s.header.markdown() + "\n\n")
+		}
+		if s.changelog != "" {
+			combinedContent.WriteString(s.changelog + "\n\n")
+		}
+		if s.meta != "" {
+			combinedContent.WriteString(s.meta + "\n\n")
+		}
+
+		// Add processed content if it's not empty
+		if s.content != "" {
+			combinedContent.WriteString(s.content)
+		} else if !s.headless {
+			combinedContent.WriteString(emptySectionText)
+		}
+		s.end = combinedContent.Len()
+		combinedContent.WriteString("\n\n")
+	}
+
+	return combinedContent.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestAssignAnchors(t *testing.T) {
+	sections := []*section{
+		{
+			header:  heading{level: 1, text: "Intro", anchor: "stale"},
+			content: "## Usage\n\n```\n## not a heading\n```",
+		},
+		{
+			folders: []heading{{level: 1, text: "Guides"}, {level: 2, text: "Deep", bold: true}},
+			header:  heading{level: 3, text: "Setup", bold: true},
+			content: "**Usage**\n\n## Usage\n\n## Intro",
+		},
+		{
+			header:   heading{level: 1, text: "Hidden"},
+			headless: true,
+			content:  "Text only",
+		},
+	}
+	assignAnchors(sections)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "header", got: sections[0].header.anchor, want: "intro"},
+		{name: "heading", got: sections[0].anchors["usage"], want: "usage"},
+		{name: "folder", got: sections[1].folders[0].anchor, want: "guides"},
+		{name: "bold folder", got: sections[1].folders[1].anchor, want: ""},
+		{name: "bold header falls back to the first heading", got: sections[1].header.anchor, want: "usage-1"},
+		{name: "repeated heading", got: sections[1].anchors["intro"], want: "intro-1"},
+		{name: "headless file without headings", got: sections[2].header.anchor, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("anchor = %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter formats, told apart by how the block opens: --- for YAML,
+// +++ for TOML, and { for a JSON object, as Hugo and Zola use them.
+const (
+	frontmatterYAML = "yaml"
+	frontmatterTOML = "toml"
+	frontmatterJSON = "json"
+)
+
+// splitFrontmatter separates front matter from markdown content, returning
+// the front matter without its delimiters, its format, and the remaining
+// body. Content without front matter comes back whole with an empty format.
+func splitFrontmatter(content string) (string, string, string) {
+	lines := strings.Split(content, "\n")
+
+	switch first := strings.TrimSpace(lines[0]); {
+	case first == "---":
+		return splitDelimitedFrontmatter(content, lines, first, frontmatterYAML)
+	case first == "+++":
+		return splitDelimitedFrontmatter(content, lines, first, frontmatterTOML)
+	case strings.HasPrefix(first, "{"):
+		return splitJSONFrontmatter(content)
+	}
+
+	return "", "", content
+}
+
+// splitDelimitedFrontmatter splits front matter that sits between two
+// delimiter lines, such as --- for YAML or +++ for TOML.
+func splitDelimitedFrontmatter(content string, lines []string, delimiter, format string) (string, string, string) {
+	if len(lines) < 3 {
+		return "", "", content
+	}
+
+	// Find the closing delimiter
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == delimiter {
+			frontmatter := strings.Join(lines[1:i], "\n")
+
+			// Found closing delimiter, return content after it
+			if i+1 < len(lines) {
+				return frontmatter, format, strings.Join(lines[i+1:], "\n")
+			}
+
+			return frontmatter, format, ""
+		}
+	}
+
+	// No closing delimiter found, return original content
+	return "", "", content
+}
+
+// splitJSONFrontmatter splits off a JSON object at the start of content.
+// Content that does not open with a well-formed object, such as a Hugo
+// shortcode, is left whole.
+func splitJSONFrontmatter(content string) (string, string, string) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	var object map[string]json.RawMessage
+	if err := decoder.Decode(&object); err != nil {
+		return "", "", content
+	}
+
+	end := int(decoder.InputOffset())
+	body := content[end:]
+
+	// Drop the rest of the line the object closes on
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 && strings.TrimSpace(body[:newline]) == "" {
+		body = body[newline+1:]
+	} else if strings.TrimSpace(body) == "" {
+		body = ""
+	}
+
+	return strings.TrimSpace(content[:end]), frontmatterJSON, body
+}
+
+// parseFrontmatter decodes front matter in the given format into its
+// top-level fields.
+func parseFrontmatter(frontmatter, format string) (map[string]any, error) {
+	fields := map[string]any{}
+	if strings.TrimSpace(frontmatter) == "" {
+		return fields, nil
+	}
+
+	var err error
+	switch format {
+	case frontmatterTOML:
+		_, err = toml.Decode(frontmatter, &fields)
+	case frontmatterJSON:
+		err = json.Unmarshal([]byte(frontmatter), &fields)
+	default:
+		err = yaml.Unmarshal([]byte(frontmatter), &fields)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// frontmatterWeight returns the position a file asks for through an order
+// or weight front matter field, and whether it has one.
+func frontmatterWeight(fields map[string]any) (float64, bool) {
+	for _, key := range []string{"order", "weight"} {
+		switch value := fields[key].(type) {
+		case int:
+			return float64(value), true
+		case int64:
+			return float64(value), true
+		case float64:
+			return value, true
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return n, true
+			}
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestSplitFrontmatter(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantFrontmatter string
+		wantFormat      string
+		wantBody        string
+	}{
+		{
+			name:            "yaml",
+			content:         "---\ntitle: Intro\norder: 2\n---\n# Body\n",
+			wantFrontmatter: "title: Intro\norder: 2",
+			wantFormat:      frontmatterYAML,
+			wantBody:        "# Body\n",
+		},
+		{
+			name:            "toml",
+			content:         "+++\ntitle = \"Intro\"\n+++\nBody",
+			wantFrontmatter: "title = \"Intro\"",
+			wantFormat:      frontmatterTOML,
+			wantBody:        "Body",
+		},
+		{
+			name:            "json",
+			content:         "{\n  \"title\": \"Intro\"\n}\nBody\n",
+			wantFrontmatter: "{\n  \"title\": \"Intro\"\n}",
+			wantFormat:      frontmatterJSON,
+			wantBody:        "Body\n",
+		},
+		{
+			name:            "front matter only",
+			content:         "---\ntitle: Intro\n---",
+			wantFrontmatter: "title: Intro",
+			wantFormat:      frontmatterYAML,
+			wantBody:        "",
+		},
+		{
+			name:     "no front matter",
+			content:  "# Title\n\n---\n",
+			wantBody: "# Title\n\n---\n",
+		},
+		{
+			name:     "unclosed block",
+			content:  "---\ntitle: Intro\nBody",
+			wantBody: "---\ntitle: Intro\nBody",
+		},
+		{
+			name:     "shortcode",
+			content:  "{{< note >}}\nText\n{{< /note >}}",
+			wantBody: "{{< note >}}\nText\n{{< /note >}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontmatter, format, body := splitFrontmatter(tt.content)
+			if frontmatter != tt.wantFrontmatter || format != tt.wantFormat || body != tt.wantBody {
+				t.Errorf("splitFrontmatter() = %q, %q, %q, want %q, %q, %q",
+					frontmatter, format, body, tt.wantFrontmatter, tt.wantFormat, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontmatter string
+		format      string
+		wantErr     bool
+	}{
+		{name: "yaml", frontmatter: "title: Intro", format: frontmatterYAML},
+		{name: "toml", frontmatter: "title = \"Intro\"", format: frontmatterTOML},
+		{name: "json", frontmatter: "{\"title\": \"Intro\"}", format: frontmatterJSON},
+		{name: "invalid yaml", frontmatter: "title: [Intro", format: frontmatterYAML, wantErr: true},
+		{name: "invalid toml", frontmatter: "title = ", format: frontmatterTOML, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := parseFrontmatter(tt.frontmatter, tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseFrontmatter() = %v, want an error", fields)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFrontmatter() unexpected error: %v", err)
+			}
+			if fields["title"] != "Intro" {
+				t.Errorf("parseFrontmatter() title = %v, want %q", fields["title"], "Intro")
+			}
+		})
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/conneroisu/dotfiles/modules/programs/cmbd
 
 go 1.24.3
+
+require (
+	github.com/BurntSushi/toml v1.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
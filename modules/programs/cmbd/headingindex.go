@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// headingIndexTitle is the header of the -heading-index.
+const headingIndexTitle = "Index"
+
+// indexedHeading is a heading listed in the heading index.
+type indexedHeading struct {
+	text    string
+	anchor  string
+	section string // Title of the section it appears in
+}
+
+// renderHeadingIndex returns an alphabetical index linking to every header
+// in the document, grouped by first letter. Headings that appear more than
+// once are listed together, each link named after its section.
+func renderHeadingIndex(sections []*section) string {
+	byText := map[string][]indexedHeading{}
+	add := func(h heading, sectionTitle string) {
+		if h.bold {
+			return
+		}
+		byText[h.text] = append(byText[h.text], indexedHeading{h.text, h.anchor, sectionTitle})
+	}
+	for _, s := range sections {
+		for _, folder := range s.folders {
+			add(folder, folder.text)
+		}
+		if !s.headless {
+			add(s.header, s.header.text)
+		}
+		for _, h := range s.headings {
+			add(h, s.header.text)
+		}
+	}
+
+	texts := make([]string, 0, len(byText))
+	for text := range byText {
+		texts = append(texts, text)
+	}
+	sort.Slice(texts, func(i, j int) bool {
+		a, b := strings.ToLower(texts[i]), strings.ToLower(texts[j])
+		if a != b {
+			return a < b
+		}
+
+		return texts[i] < texts[j]
+	})
+
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n", headingIndexTitle)
+
+	group := ""
+	for _, text := range texts {
+		if letter := indexGroup(text); letter != group {
+			group = letter
+			fmt.Fprintf(&index, "\n**%s**\n\n", group)
+		}
+
+		entries := byText[text]
+		if len(entries) == 1 {
+			fmt.Fprintf(&index, "- [%s](#%s)\n", text, entries[0].anchor)
+
+			continue
+		}
+		links := make([]string, len(entries))
+		for i, entry := range entries {
+			links[i] = fmt.Sprintf("[%s](#%s)", entry.section, entry.anchor)
+		}
+		fmt.Fprintf(&index, "- %s: %s\n", text, strings.Join(links, ", "))
+	}
+
+	return index.String()
+}
+
+// indexGroup returns the letter a heading is grouped under in the index,
+// or # for headings that do not start with a letter.
+func indexGroup(text string) string {
+	r, _ := utf8.DecodeRuneInString(text)
+	if !unicode.IsLetter(r) {
+		return "#"
+	}
+
+	return string(unicode.ToUpper(r))
+}
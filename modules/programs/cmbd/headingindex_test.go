@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestRenderHeadingIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []*section
+		want     string
+	}{
+		{
+			name: "folders, headers, and headings",
+			sections: []*section{
+				{
+					folders: []heading{{level: 1, text: "guide"}},
+					header:  heading{level: 2, text: "Setup"},
+					content: "### Usage\n\n### install\n\n```\n### not a heading\n```",
+				},
+				{
+					header:  heading{level: 1, text: "api"},
+					content: "## Usage\n\n## 2FA",
+				},
+			},
+			want: "# Index\n" +
+				"\n**#**\n\n- [2FA](#2fa)\n" +
+				"\n**A**\n\n- [api](#api)\n" +
+				"\n**G**\n\n- [guide](#guide)\n" +
+				"\n**I**\n\n- [install](#install)\n" +
+				"\n**S**\n\n- [Setup](#setup)\n" +
+				"\n**U**\n\n- Usage: [Setup](#usage), [api](#usage-1)\n",
+		},
+		{
+			name: "nested folders",
+			sections: []*section{
+				{
+					folders: []heading{{level: 1, text: "docs"}, {level: 2, text: "api"}},
+					header:  heading{level: 3, text: "Auth"},
+				},
+				{
+					folders: []heading{{level: 2, text: "cli"}},
+					header:  heading{level: 3, text: "Auth"},
+				},
+			},
+			want: "# Index\n" +
+				"\n**A**\n\n- [api](#api)\n- Auth: [Auth](#auth), [Auth](#auth-1)\n" +
+				"\n**C**\n\n- [cli](#cli)\n" +
+				"\n**D**\n\n- [docs](#docs)\n",
+		},
+		{
+			name: "headless and bold headers are left out",
+			sections: []*section{
+				{header: heading{level: 1, text: "Hidden"}, headless: true, content: "## Shown"},
+				{
+					folders: []heading{{level: 7, text: "deep", bold: true}},
+					header:  heading{level: 7, text: "Deeper", bold: true},
+					content: "Text",
+				},
+			},
+			want: "# Index\n\n**S**\n\n- [Shown](#shown)\n",
+		},
+		{
+			name: "no headings",
+			want: "# Index\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignAnchors(tt.sections)
+			if got := renderHeadingIndex(tt.sections); got != tt.want {
+				t.Errorf("renderHeadingIndex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexGroup(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "setup", want: "S"},
+		{text: "Setup", want: "S"},
+		{text: "éclair", want: "É"},
+		{text: "2FA", want: "#"},
+		{text: "_private", want: "#"},
+		{text: "", want: "#"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := indexGroup(tt.text); got != tt.want {
+				t.Errorf("indexGroup(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
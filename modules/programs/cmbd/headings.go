@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How headers pushed past the maximum level are handled.
+const (
+	overflowClamp = "clamp" // Keep them at the maximum level
+	overflowBold  = "bold"  // Turn them into bold text
+	overflowError = "error" // Fail
+)
+
+// levelLimit caps the level of the headers in the combined document.
+type levelLimit struct {
+	max      int
+	overflow string
+}
+
+// apply returns h within the limit: unchanged if it fits, otherwise handled
+// by the overflow strategy.
+func (l levelLimit) apply(h heading) (heading, error) {
+	if h.level <= l.max {
+		return h, nil
+	}
+
+	switch l.overflow {
+	case overflowBold:
+		h.bold = true
+	case overflowError:
+		return h, fmt.Errorf("header %q would be level %d, past the maximum of %d", h.text, h.level, l.max)
+	default:
+		h.level = l.max
+	}
+
+	return h, nil
+}
+
+// markdown returns the header as a line of markdown.
+func (h heading) markdown() string {
+	if h.bold {
+		return "**" + h.text + "**"
+	}
+
+	return strings.Repeat("#", h.level) + " " + h.text
+}
+
+// parseHeading parses an ATX markdown heading, returning its level and text
+// without the surrounding hashes.
+func parseHeading(line string) (int, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, "", false
+	}
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+
+	// Drop an optional closing sequence of hashes
+	text := strings.TrimSpace(rest)
+	if closing := strings.TrimRight(text, "#"); closing == "" || strings.HasSuffix(closing, " ") {
+		text = strings.TrimSpace(closing)
+	}
+
+	return level, text, true
+}
+
+// isFence reports whether a line opens or closes a fenced code block.
+func isFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// takeFirstHeading finds the first heading outside code blocks in markdown
+// content and returns its text along with the content without it.
+func takeFirstHeading(content string) (string, string, bool) {
+	lines := strings.Split(content, "\n")
+	inFence := false
+
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if _, text, ok := parseHeading(line); ok && text != "" {
+			rest := append(lines[:i:i], lines[i+1:]...)
+
+			return text, strings.Join(rest, "\n"), true
+		}
+	}
+
+	return "", content, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestLevelLimitApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   levelLimit
+		header  heading
+		want    heading
+		wantErr bool
+	}{
+		{
+			name:   "within the limit",
+			limit:  levelLimit{max: 3, overflow: overflowError},
+			header: heading{level: 3, text: "Setup"},
+			want:   heading{level: 3, text: "Setup"},
+		},
+		{
+			name:   "clamp",
+			limit:  levelLimit{max: 3, overflow: overflowClamp},
+			header: heading{level: 5, text: "Setup"},
+			want:   heading{level: 3, text: "Setup"},
+		},
+		{
+			name:   "unknown strategy clamps",
+			limit:  levelLimit{max: 2},
+			header: heading{level: 4, text: "Setup"},
+			want:   heading{level: 2, text: "Setup"},
+		},
+		{
+			name:   "bold",
+			limit:  levelLimit{max: 3, overflow: overflowBold},
+			header: heading{level: 4, text: "Setup"},
+			want:   heading{level: 4, text: "Setup", bold: true},
+		},
+		{
+			name:    "error",
+			limit:   levelLimit{max: 3, overflow: overflowError},
+			header:  heading{level: 4, text: "Setup"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.limit.apply(tt.header)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("apply() = %+v, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
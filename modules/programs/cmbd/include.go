@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// includePattern matches an include directive on a line of its own.
+var includePattern = regexp.MustCompile(`^\s*<!--\s*include:\s*(.+?)\s*-->\s*$`)
+
+// expandIncludes replaces each include directive in a markdown file's body
+// with the body of the file it names, relative to the including file, and
+// returns the paths of every file it pulled in. Included files may include
+// others; stack holds the files being expanded to catch cycles.
+func expandIncludes(body, filePath string, stack []string) (string, []string, error) {
+	stack = append(stack, filepath.Clean(filePath))
+
+	var includes []string
+	lines := strings.Split(body, "\n")
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		match := includePattern.FindStringSubmatch(line)
+		if inFence || match == nil {
+			continue
+		}
+
+		includePath := filepath.Clean(filepath.Join(filepath.Dir(filePath), filepath.FromSlash(match[1])))
+		for _, open := range stack {
+			if open == includePath {
+				return "", nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), includePath)
+			}
+		}
+
+		content, err := os.ReadFile(includePath)
+		if err != nil {
+			return "", nil, fmt.Errorf("error including %s: %v", match[1], err)
+		}
+		_, _, included := splitFrontmatter(string(content))
+		included, nested, err := expandIncludes(included, includePath, stack)
+		if err != nil {
+			return "", nil, err
+		}
+		includes = append(append(includes, includePath), nested...)
+
+		// Keep relative links working from the including file
+		lines[i] = strings.TrimSpace(rebaseLinks(included, filepath.Dir(includePath), filepath.Dir(filePath)))
+	}
+
+	return strings.Join(lines, "\n"), includes, nil
+}
+
+// rebaseLinks rewrites the relative link and image destinations in content
+// written in directory from so they resolve the same from directory to.
+func rebaseLinks(content, from, to string) string {
+	if filepath.Clean(from) == filepath.Clean(to) {
+		return content
+	}
+
+	rebase := func(destination string) string {
+		target := strings.Trim(destination, "<>")
+		if target == "" || schemePattern.MatchString(target) || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") {
+			return destination
+		}
+
+		rel, err := filepath.Rel(to, filepath.Join(from, filepath.FromSlash(target)))
+		if err != nil {
+			return destination
+		}
+
+		return filepath.ToSlash(rel)
+	}
+
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if match := referenceLinkPattern.FindStringSubmatch(line); match != nil {
+			lines[i] = match[1] + rebase(match[2]) + match[3]
+
+			continue
+		}
+		line = inlineLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+			match := inlineLinkPattern.FindStringSubmatch(link)
+
+			return match[1] + rebase(match[2]) + match[3]
+		})
+		lines[i] = htmlImagePattern.ReplaceAllStringFunc(line, func(tag string) string {
+			match := htmlImagePattern.FindStringSubmatch(tag)
+
+			return match[1] + rebase(match[2]) + match[3]
+		})
+	}
+
+	return strings.Join(lines, "\n")
+}
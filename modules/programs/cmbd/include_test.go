@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, given by slash-separated path, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandIncludes(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		want         string
+		wantIncludes []string
+		wantErr      string
+	}{
+		{
+			name: "include",
+			files: map[string]string{
+				"main.md": "Before\n<!-- include: part.md -->\nAfter",
+				"part.md": "---\ntitle: Part\n---\nPart body\n",
+			},
+			want:         "Before\nPart body\nAfter",
+			wantIncludes: []string{"part.md"},
+		},
+		{
+			name: "nested includes",
+			files: map[string]string{
+				"main.md":      "<!-- include: parts/one.md -->",
+				"parts/one.md": "One\n<!--include:two.md-->",
+				"parts/two.md": "Two",
+			},
+			want:         "One\nTwo",
+			wantIncludes: []string{"parts/one.md", "parts/two.md"},
+		},
+		{
+			name: "links are rebased",
+			files: map[string]string{
+				"main.md":      "<!-- include: parts/one.md -->",
+				"parts/one.md": "![diagram](img/d.png) [site](https://example.com) [top](#top)\n\n[ref]: ../other.md",
+			},
+			want:         "![diagram](parts/img/d.png) [site](https://example.com) [top](#top)\n\n[ref]: other.md",
+			wantIncludes: []string{"parts/one.md"},
+		},
+		{
+			name: "code blocks are left alone",
+			files: map[string]string{
+				"main.md": "```\n<!-- include: missing.md -->\n```",
+			},
+			want: "```\n<!-- include: missing.md -->\n```",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"main.md": "<!-- include: missing.md -->",
+			},
+			wantErr: "error including missing.md",
+		},
+		{
+			name: "self include",
+			files: map[string]string{
+				"main.md": "<!-- include: main.md -->",
+			},
+			wantErr: "include cycle: main.md -> main.md",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.md": "<!-- include: a.md -->",
+				"a.md":    "<!-- include: b.md -->",
+				"b.md":    "<!-- include: a.md -->",
+			},
+			wantErr: "include cycle: main.md -> a.md -> b.md -> a.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			got, includes, err := expandIncludes(tt.files["main.md"], filepath.Join(dir, "main.md"), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), ""), tt.wantErr) {
+					t.Errorf("expandIncludes() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandIncludes() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expandIncludes() = %q, want %q", got, tt.want)
+			}
+
+			var rel []string
+			for _, include := range includes {
+				r, err := filepath.Rel(dir, include)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			if !reflect.DeepEqual(rel, tt.wantIncludes) {
+				t.Errorf("expandIncludes() includes = %q, want %q", rel, tt.wantIncludes)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// How files missing from an index file are handled.
+const (
+	unlistedAppend = "append" // Add them after the listed files
+	unlistedSkip   = "skip"   // Leave them out
+)
+
+// indexFileNames are the index files looked for in the input directory, in
+// order of preference.
+var indexFileNames = []string{"index.yaml", "index.yml", "SUMMARY.md"}
+
+// summaryLinkPattern matches a chapter link in an mdBook style SUMMARY.md.
+var summaryLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]*)\)`)
+
+// indexEntry is a file listed in an index file, with an optional title for
+// its section.
+type indexEntry struct {
+	Path  string `yaml:"file"`
+	Title string `yaml:"title"`
+}
+
+// UnmarshalYAML accepts an entry either as a bare path or as a mapping with
+// file and title keys.
+func (e *indexEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.Path = node.Value
+
+		return nil
+	}
+
+	type plain indexEntry
+
+	return node.Decode((*plain)(e))
+}
+
+// findIndexFile returns the index file in the input directory, or "" if it
+// has none.
+func findIndexFile(inputDir string) string {
+	for _, name := range indexFileNames {
+		path := filepath.Join(inputDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// readIndex reads the files listed in an index file, either an index.yaml
+// with a files list or a SUMMARY.md of chapter links. Paths are resolved
+// relative to the index file.
+func readIndex(indexPath string) ([]indexEntry, error) {
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading index file: %v", err)
+	}
+
+	var entries []indexEntry
+	ext := strings.ToLower(filepath.Ext(indexPath))
+	if ext == ".md" || ext == ".markdown" {
+		entries = parseSummary(string(content))
+	} else {
+		var index struct {
+			Files []indexEntry `yaml:"files"`
+		}
+		if err := yaml.Unmarshal(content, &index); err != nil {
+			return nil, fmt.Errorf("error parsing index file %s: %v", indexPath, err)
+		}
+		entries = index.Files
+	}
+
+	dir := filepath.Dir(indexPath)
+	for i := range entries {
+		if entries[i].Path == "" {
+			return nil, fmt.Errorf("index file %s has an entry without a file", indexPath)
+		}
+		entries[i].Path = filepath.Join(dir, filepath.FromSlash(entries[i].Path))
+	}
+
+	return entries, nil
+}
+
+// parseSummary returns the chapters linked from an mdBook style SUMMARY.md,
+// in order, titled by their link text. Draft chapters without a link target
+// and external links are skipped.
+func parseSummary(content string) []indexEntry {
+	var entries []indexEntry
+
+	for _, line := range strings.Split(content, "\n") {
+		for _, match := range summaryLinkPattern.FindAllStringSubmatch(line, -1) {
+			target := match[2]
+			if i := strings.Index(target, "#"); i >= 0 {
+				target = target[:i]
+			}
+			if target == "" || strings.Contains(target, "://") {
+				continue
+			}
+			entries = append(entries, indexEntry{Path: target, Title: strings.TrimSpace(match[1])})
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   string
+		content string
+		want    []indexEntry
+		wantErr bool
+	}{
+		{
+			name:  "yaml with bare and mapping entries",
+			index: "index.yaml",
+			content: "files:\n" +
+				"  - intro.md\n" +
+				"  - file: guide/setup.md\n" +
+				"    title: Getting Set Up\n" +
+				"  - file: faq.md\n",
+			want: []indexEntry{
+				{Path: "intro.md"},
+				{Path: "guide/setup.md", Title: "Getting Set Up"},
+				{Path: "faq.md"},
+			},
+		},
+		{
+			name:  "summary with nested list items",
+			index: "SUMMARY.md",
+			content: "# Summary\n\n" +
+				"[Preface](preface.md)\n\n" +
+				"- [Intro](intro.md)\n" +
+				"  - [Setup](guide/setup.md#install)\n" +
+				"    - [Deep Dive](guide/deep.md)\n" +
+				"- [Draft]()\n" +
+				"- [Site](https://example.com/page.md)\n" +
+				"* [FAQ](faq.md)\n",
+			want: []indexEntry{
+				{Path: "preface.md", Title: "Preface"},
+				{Path: "intro.md", Title: "Intro"},
+				{Path: "guide/setup.md", Title: "Setup"},
+				{Path: "guide/deep.md", Title: "Deep Dive"},
+				{Path: "faq.md", Title: "FAQ"},
+			},
+		},
+		{
+			name:    "entry without a file",
+			index:   "index.yaml",
+			content: "files:\n  - title: Lost\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			index:   "index.yaml",
+			content: "files: [intro.md\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{tt.index: tt.content})
+
+			got, err := readIndex(filepath.Join(dir, tt.index))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readIndex() = %v, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("readIndex() unexpected error: %v", err)
+			}
+
+			// Paths come back relative to the index file's directory
+			for i := range tt.want {
+				tt.want[i].Path = filepath.Join(dir, filepath.FromSlash(tt.want[i].Path))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFilesUnlisted(t *testing.T) {
+	files := map[string]string{
+		"index.yaml": "files:\n  - b.md\n  - file: a.md\n    title: First\n",
+		"a.md":       "A",
+		"b.md":       "B",
+		"c.md":       "C",
+		"d.md":       "D",
+	}
+
+	tests := []struct {
+		name     string
+		unlisted string
+		want     []string
+	}{
+		{name: "append", unlisted: unlistedAppend, want: []string{"b.md", "a.md", "c.md", "d.md"}},
+		{name: "skip", unlisted: unlistedSkip, want: []string{"b.md", "a.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, files)
+
+			mc := &MarkdownCombiner{
+				inputDir:    dir,
+				unlisted:    tt.unlisted,
+				titleSource: titleFilename,
+				sortBy:      sortName,
+				log:         io.Discard,
+			}
+			if got := loadedPaths(t, mc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// inlineLinkPattern matches an inline link or image, capturing the part
+	// before the destination, the destination, and the rest.
+	inlineLinkPattern = regexp.MustCompile(`(!?\[[^\]]*\]\(\s*)([^)\s]+)([^)]*\))`)
+
+	// referenceLinkPattern matches a link reference definition.
+	referenceLinkPattern = regexp.MustCompile(`^( {0,3}\[[^\]^][^\]]*\]:\s*)(\S+)(.*)$`)
+
+	// schemePattern matches a destination with a URL scheme.
+	schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// slugger makes GitHub style heading anchors, numbering repeated ones.
+type slugger struct {
+	seen map[string]int
+}
+
+func newSlugger() *slugger {
+	return &slugger{seen: map[string]int{}}
+}
+
+// slug returns the anchor for the next heading with the given text.
+func (s *slugger) slug(text string) string {
+	base := slugify(text)
+	n := s.seen[base]
+	s.seen[base]++
+	if n == 0 {
+		return base
+	}
+
+	return base + "-" + strconv.Itoa(n)
+}
+
+// slugify lowercases heading text, drops punctuation, and turns spaces into
+// hyphens, as GitHub does for heading anchors.
+func slugify(text string) string {
+	// Link text stands in for the link
+	text = inlineLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		return link[strings.Index(link, "[")+1 : strings.Index(link, "]")]
+	})
+
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+
+	return slug.String()
+}
+
+// headingTexts returns the text of each heading in markdown content, in
+// order, skipping code blocks.
+func headingTexts(content string) []string {
+	var texts []string
+	inFence := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if _, text, ok := parseHeading(line); ok && !inFence {
+			texts = append(texts, text)
+		}
+	}
+
+	return texts
+}
+
+// rewriteLinks points links between combined files at their sections in the
+// combined document. Links to other files, external links, and links inside
+// code blocks are left alone.
+func rewriteLinks(sections []*section) {
+	byPath := map[string]*section{}
+	for _, s := range sections {
+		byPath[filepath.Clean(s.file.path)] = s
+	}
+
+	for _, s := range sections {
+		resolve := func(target string) (string, bool) {
+			return resolveLink(s, target, byPath)
+		}
+
+		lines := strings.Split(s.content, "\n")
+		inFence := false
+		for i, line := range lines {
+			if isFence(line) {
+				inFence = !inFence
+
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			if match := referenceLinkPattern.FindStringSubmatch(line); match != nil {
+				if anchor, ok := resolve(match[2]); ok {
+					lines[i] = match[1] + anchor + match[3]
+				}
+
+				continue
+			}
+
+			lines[i] = inlineLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+				match := inlineLinkPattern.FindStringSubmatch(link)
+				if strings.HasPrefix(match[1], "!") {
+					return link
+				}
+				if anchor, ok := resolve(match[2]); ok {
+					return match[1] + anchor + match[3]
+				}
+
+				return link
+			})
+		}
+		s.content = strings.Join(lines, "\n")
+	}
+}
+
+// resolveLink returns the in-document anchor for a link destination in a
+// section, if it points at a combined file or one of its headings.
+func resolveLink(from *section, destination string, byPath map[string]*section) (string, bool) {
+	target := strings.Trim(destination, "<>")
+	if schemePattern.MatchString(target) || strings.HasPrefix(target, "/") {
+		return "", false
+	}
+
+	path, fragment, _ := strings.Cut(target, "#")
+	to := from
+	if path != "" {
+		unescaped, err := url.PathUnescape(path)
+		if err != nil {
+			return "", false
+		}
+		ext := strings.ToLower(filepath.Ext(unescaped))
+		if ext != ".md" && ext != ".markdown" {
+			return "", false
+		}
+
+		resolved := filepath.Join(filepath.Dir(from.file.path), filepath.FromSlash(unescaped))
+		if to = byPath[filepath.Clean(resolved)]; to == nil {
+			return "", false
+		}
+	} else if fragment == "" {
+		return "", false
+	}
+
+	if anchor, ok := to.anchors[fragment]; ok && fragment != "" {
+		return "#" + anchor, true
+	}
+
+	if to.header.anchor == "" {
+		return "", false
+	}
+
+	return "#" + to.header.anchor, true
+}
@@ -0,0 +1,180 @@
+package main
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Getting Started", want: "getting-started"},
+		{text: "  What's new?  ", want: "whats-new"},
+		{text: "snake_case and kebab-case", want: "snake_case-and-kebab-case"},
+		{text: "Version 2.0", want: "version-20"},
+		{text: "See [the guide](guide.md)", want: "see-the-guide"},
+		{text: "Ünïcode Tïtle", want: "ünïcode-tïtle"},
+		{text: "A  B", want: "a--b"},
+		{text: "!!!", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := slugify(tt.text); got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSluggerNumbersRepeats(t *testing.T) {
+	s := newSlugger()
+	var got []string
+	for _, text := range []string{"Usage", "Setup", "Usage", "usage"} {
+		got = append(got, s.slug(text))
+	}
+
+	want := []string{"usage", "setup", "usage-1", "usage-2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("slug() = %q, want %q", got, want)
+
+			break
+		}
+	}
+}
+
+// linkSections returns sections for files a.md and sub/b.md, as
+// assignAnchors would leave them.
+func linkSections() []*section {
+	a := &section{
+		file:    &sourceFile{path: "docs/a.md"},
+		header:  heading{level: 1, text: "A", anchor: "a"},
+		anchors: map[string]string{"intro": "intro", "usage": "usage"},
+	}
+	b := &section{
+		file:    &sourceFile{path: "docs/sub/b.md"},
+		header:  heading{level: 2, text: "B", anchor: "b"},
+		anchors: map[string]string{"usage": "usage-1"},
+	}
+
+	return []*section{a, b}
+}
+
+func TestResolveLink(t *testing.T) {
+	sections := linkSections()
+	byPath := map[string]*section{}
+	for _, s := range sections {
+		byPath[s.file.path] = s
+	}
+
+	tests := []struct {
+		name        string
+		from        int
+		destination string
+		want        string
+		wantOK      bool
+	}{
+		{name: "file", from: 0, destination: "sub/b.md", want: "#b", wantOK: true},
+		{name: "file heading", from: 0, destination: "sub/b.md#usage", want: "#usage-1", wantOK: true},
+		{name: "unknown heading falls back to the file", from: 0, destination: "sub/b.md#nope", want: "#b", wantOK: true},
+		{name: "parent directory", from: 1, destination: "../a.md#intro", want: "#intro", wantOK: true},
+		{name: "angle brackets", from: 0, destination: "<sub/b.md>", want: "#b", wantOK: true},
+		{name: "escaped path", from: 0, destination: "sub/%62.md", want: "#b", wantOK: true},
+		{name: "own heading", from: 1, destination: "#usage", want: "#usage-1", wantOK: true},
+		{name: "unknown own heading", from: 1, destination: "#nope", want: "#b", wantOK: true},
+		{name: "not combined", from: 0, destination: "other.md"},
+		{name: "not markdown", from: 0, destination: "image.png"},
+		{name: "external", from: 0, destination: "https://example.com/a.md"},
+		{name: "absolute", from: 0, destination: "/docs/a.md"},
+		{name: "empty fragment", from: 0, destination: "#"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveLink(sections[tt.from], tt.destination, byPath)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("resolveLink(%q) = %q, %v, want %q, %v", tt.destination, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveLinkWithoutAnchor(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   heading
+		headless bool
+	}{
+		{name: "headless file without headings", header: heading{level: 2, text: "B"}, headless: true},
+		{name: "bold header", header: heading{level: 7, text: "B", bold: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := linkSections()
+			sections[1].header = tt.header
+			sections[1].headless = tt.headless
+			byPath := map[string]*section{}
+			for _, s := range sections {
+				byPath[s.file.path] = s
+			}
+
+			if got, ok := resolveLink(sections[0], "sub/b.md", byPath); ok {
+				t.Errorf("resolveLink() = %q, want no anchor", got)
+			}
+			if got, ok := resolveLink(sections[0], "sub/b.md#usage", byPath); got != "#usage-1" || !ok {
+				t.Errorf("resolveLink() = %q, %v, want %q", got, ok, "#usage-1")
+			}
+		})
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "inline links",
+			content: "See [B](sub/b.md) and [usage](sub/b.md#usage \"Usage\").",
+			want:    "See [B](#b) and [usage](#usage-1 \"Usage\").",
+		},
+		{
+			name:    "own heading",
+			content: "Back to [intro](#intro).",
+			want:    "Back to [intro](#intro).",
+		},
+		{
+			name:    "reference definition",
+			content: "[b]: sub/b.md#usage",
+			want:    "[b]: #usage-1",
+		},
+		{
+			name:    "images are left alone",
+			content: "![diagram](sub/b.md)",
+			want:    "![diagram](sub/b.md)",
+		},
+		{
+			name:    "other links are left alone",
+			content: "[site](https://example.com) [other](other.md)",
+			want:    "[site](https://example.com) [other](other.md)",
+		},
+		{
+			name:    "code blocks are left alone",
+			content: "```\n[B](sub/b.md)\n```\n[B](sub/b.md)",
+			want:    "```\n[B](sub/b.md)\n```\n[B](#b)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := linkSections()
+			sections[0].content = tt.content
+			rewriteLinks(sections)
+			if got := sections[0].content; got != tt.want {
+				t.Errorf("rewriteLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,88 +2,126 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // MarkdownCombiner handles the combination of markdown files.
 type MarkdownCombiner struct {
-	inputDir   string
-	outputFile string
+	inputDir    string
+	outputFile  string
+	recursive   bool
+	indexFile   string
+	unlisted    string
+	titleSource string
+	assets      string
+	dryRun      bool
+	limit       levelLimit
+	template    string
+	title       string
+	separator   string
+	headerTmpl  string
+	sectionMeta string // Template for a metadata block under each header
+	headingIdx  bool
+
+	// headerOffset shifts each file's headers, on top of one level per
+	// folder; noFileHeader leaves out the header naming each file
+	headerOffset int
+	noFileHeader bool
+
+	sortBy    string
+	marker    string
+	jobs      int
+	manifest  string
+	update    bool // Replace only the part of the output between update markers
+	changelog bool // Show each file's last commit under its header
+	stats     bool // Report word, line, and heading counts and reading times
+
+	// stripComments removes HTML comments except those starting with one
+	// of keepComments
+	stripComments bool
+	keepComments  []string
+
+	// log receives progress messages, kept off standard output when the
+	// document is written there
+	log io.Writer
 }
 
-// removeYAMLFrontmatter removes YAML front matter from markdown content.
-func removeYAMLFrontmatter(content string) string {
-	lines := strings.Split(content, "\n")
+// stdoutName is the output file name that writes to standard output.
+const stdoutName = "-"
 
-	// Check if first line is "---" (YAML front matter start)
-	if len(lines) < 3 || strings.TrimSpace(lines[0]) != "---" {
-		return content
-	}
+// errGenerated reports a file that is the output of a previous run.
+var errGenerated = errors.New("generated by a previous run")
 
-	// Find the closing "---"
-	for i := 1; i < len(lines); i++ {
-		if strings.TrimSpace(lines[i]) == "---" {
-			// Found closing YAML delimiter, return content after it
-			if i+1 < len(lines) {
-				return strings.Join(lines[i+1:], "\n")
-			}
+// logf writes a progress message.
+func (mc *MarkdownCombiner) logf(format string, args ...any) {
+	fmt.Fprintf(mc.log, format, args...)
+}
 
-			return ""
-		}
-	}
+// Where a file's section header takes its title from.
+const (
+	titleFilename     = "filename"      // The filename without extension
+	titleFrontmatter  = "frontmatter"   // The front matter title, else the filename
+	titleFirstHeading = "first-heading" // The file's first heading, else the filename
+)
 
-	// No closing "---" found, return original content
-	return content
+// sourceFile is a markdown file read for combining.
+type sourceFile struct {
+	path        string
+	displayPath string
+	dirs        []string
+	frontmatter map[string]any
+	body        string
+	title       string
+	modTime     time.Time
+	warnings    []string   // Problems found while reading, reported in order
+	commit      *gitCommit // Last git commit, for -sort git-date and -changelog
+	includes    []string   // Files pulled in by include directives
 }
 
-// increaseHeaderLevels increases all markdown header levels by one.
-func increaseHeaderLevels(content string) string {
+// shiftHeaderLevels moves all markdown header levels by the given number of
+// levels, which may be negative, handling headers pushed past the maximum
+// level as limit says. Headers do not go above level one, and lines in code
+// blocks are left alone.
+func shiftHeaderLevels(content string, levels int, limit levelLimit) (string, error) {
 	lines := strings.Split(content, "\n")
 	var processedLines []string
+	inFence := false
 
 	for _, line := range lines {
-		trimmed := strings.TrimLeft(line, " \t")
-
-		// Check if line starts with # and is a valid header
-		if strings.HasPrefix(trimmed, "#") {
-			// Find where the hashes end
-			hashEnd := 0
-			for i, char := range trimmed {
-				if char == '#' {
-					hashEnd = i + 1
-				} else {
-					break
-				}
-			}
+		if isFence(line) {
+			inFence = !inFence
+		}
 
-			// Check if it's a valid header and process accordingly
-			switch {
-			case hashEnd < len(trimmed) && trimmed[hashEnd] == ' ':
-				// Valid header with space - add one more #
-				processedLines = append(processedLines, "#"+line)
-			case hashEnd == len(trimmed):
-				// Header with only hashes - add one more #
-				processedLines = append(processedLines, "#"+line)
-			default:
-				// Not a valid header
-				processedLines = append(processedLines, line)
-			}
-		} else {
+		// Check if line is a valid header outside a code block
+		level, text, ok := parseHeading(line)
+		if inFence || !ok {
 			processedLines = append(processedLines, line)
+
+			continue
 		}
+
+		header, err := limit.apply(heading{level: max(level+levels, 1), text: text})
+		if err != nil {
+			return "", err
+		}
+		processedLines = append(processedLines, header.markdown())
 	}
 
-	return strings.Join(processedLines, "\n")
+	return strings.Join(processedLines, "\n"), nil
 }
 
-// getMarkdownFiles returns all markdown files in the specified directory.
-func getMarkdownFiles(directory string) ([]string, error) {
+// getMarkdownFiles returns all markdown files in the specified directory,
+// and in its subdirectories when recursive is set.
+func getMarkdownFiles(directory string, recursive bool) ([]string, error) {
 	var markdownFiles []string
 
 	// Check if directory exists
@@ -97,9 +135,13 @@ func getMarkdownFiles(directory string) ([]string, error) {
 			return err
 		}
 
-		// Skip subdirectories - only process files in the specified directory
-		if d.IsDir() && path != directory {
-			return filepath.SkipDir
+		if d.IsDir() {
+			// Skip subdirectories unless recursing, and hidden ones always
+			if path != directory && (!recursive || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+
+			return nil
 		}
 
 		// Check if file has markdown extension
@@ -115,82 +157,421 @@ func getMarkdownFiles(directory string) ([]string, error) {
 		return nil, fmt.Errorf("error reading directory: %v", err)
 	}
 
-	// Sort files alphabetically for consistent ordering
-	sort.Strings(markdownFiles)
+	// Sort files alphabetically for consistent ordering, keeping each
+	// directory's files together ahead of its subdirectories
+	sort.Slice(markdownFiles, func(i, j int) bool {
+		return lessByDirectory(markdownFiles[i], markdownFiles[j], nil)
+	})
 
 	return markdownFiles, nil
 }
 
-// processMarkdownFile processes a single markdown file.
-func processMarkdownFile(filePath string) (string, string, error) {
-	// Read file content
+// lessByDirectory orders paths alphabetically within a directory, or by
+// less if given, with the files of a directory before those of its
+// subdirectories.
+func lessByDirectory(a, b string, less func(a, b string) bool) bool {
+	aParts := strings.Split(filepath.ToSlash(a), "/")
+	bParts := strings.Split(filepath.ToSlash(b), "/")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+
+		// A file sorts before a directory at the same level
+		aIsFile := i == len(aParts)-1
+		bIsFile := i == len(bParts)-1
+		if aIsFile != bIsFile {
+			return aIsFile
+		}
+
+		if less != nil {
+			return less(aParts[i], bParts[i])
+		}
+
+		return aParts[i] < bParts[i]
+	}
+
+	return len(aParts) < len(bParts)
+}
+
+// sectionDirs returns the directories between the input directory and a
+// file, which become nested section headers in recursive mode.
+func sectionDirs(inputDir, filePath string) []string {
+	rel, err := filepath.Rel(inputDir, filepath.Dir(filePath))
+	if err != nil || rel == "." {
+		return nil
+	}
+
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// displayPath returns a file's path relative to the input directory for
+// progress messages.
+func displayPath(inputDir, filePath string) string {
+	if rel, err := filepath.Rel(inputDir, filePath); err == nil {
+		return rel
+	}
+
+	return filepath.Base(filePath)
+}
+
+// commonPrefixLen returns the number of leading elements a and b share.
+func commonPrefixLen(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+
+	return n
+}
+
+// loadSourceFile reads a markdown file in the input directory and separates
+// its front matter from its body.
+func (mc *MarkdownCombiner) loadSourceFile(filePath string) (*sourceFile, error) {
+	if mc.isOutputFile(filePath) {
+		return nil, errGenerated
+	}
+
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", "", fmt.Errorf("error reading file: %v", err)
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	if mc.marker != "" && strings.Contains(string(content), mc.marker) {
+		return nil, errGenerated
+	}
+
+	file := &sourceFile{
+		path:        filePath,
+		displayPath: displayPath(mc.inputDir, filePath),
+		dirs:        sectionDirs(mc.inputDir, filePath),
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		file.modTime = info.ModTime()
+	}
+
+	if mc.sortBy == sortGitDate || mc.changelog {
+		file.commit = lastCommit(filePath)
+	}
+
+	frontmatter, format, body := splitFrontmatter(string(content))
+	file.frontmatter, err = parseFrontmatter(frontmatter, format)
+	if err != nil {
+		file.warnings = append(file.warnings, fmt.Sprintf("ignoring invalid front matter in %s: %v", file.displayPath, err))
+	}
+
+	file.body, file.includes, err = expandIncludes(body, filePath, nil)
+	if err != nil {
+		return nil, err
 	}
+	if mc.stripComments {
+		file.body = stripComments(file.body, mc.keepComments)
+	}
+
+	return file, nil
+}
+
+// sortSourceFiles orders files within each directory by their order or
+// weight front matter field, lowest first, ahead of files without one, which
+// follow in the order sortBy asks for. Files without a time to sort by come
+// after those with one, in name order.
+func sortSourceFiles(files []*sourceFile, sortBy string) {
+	var less func(a, b string) bool
+	if sortBy == sortNatural {
+		less = naturalLess
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if filepath.Dir(a.path) == filepath.Dir(b.path) {
+			aWeight, aOK := frontmatterWeight(a.frontmatter)
+			bWeight, bOK := frontmatterWeight(b.frontmatter)
+			if aOK != bOK {
+				return aOK
+			}
+			if aOK && aWeight != bWeight {
+				return aWeight < bWeight
+			}
 
-	contentStr := string(content)
+			aTime, aOK := sortTime(a, sortBy)
+			bTime, bOK := sortTime(b, sortBy)
+			if aOK != bOK {
+				return aOK
+			}
+			if aOK && !aTime.Equal(bTime) {
+				return aTime.Before(bTime)
+			}
+		}
 
-	// Remove YAML front matter
-	contentStr = removeYAMLFrontmatter(contentStr)
+		return lessByDirectory(a.path, b.path, less)
+	})
+}
 
-	// Increase header levels
-	contentStr = increaseHeaderLevels(contentStr)
+// sectionTitle returns the title of a file's section and its body. A title
+// from the index file always wins; a first heading used as the title is
+// removed from the body so it is not repeated.
+func sectionTitle(file *sourceFile, titleSource string) (string, string) {
+	if file.title != "" {
+		return file.title, file.body
+	}
+
+	switch titleSource {
+	case titleFrontmatter:
+		if title, ok := file.frontmatter["title"].(string); ok && strings.TrimSpace(title) != "" {
+			return strings.TrimSpace(title), file.body
+		}
+	case titleFirstHeading:
+		if title, body, ok := takeFirstHeading(file.body); ok {
+			return title, body
+		}
+	}
+
+	// Fall back to the filename without extension
+	return strings.TrimSuffix(filepath.Base(file.path), filepath.Ext(file.path)), file.body
+}
+
+// processMarkdownFile prepares a markdown file's body for its section,
+// shifting its headers by the given number of levels.
+func processMarkdownFile(body string, levels int, limit levelLimit) (string, error) {
+	// Shift header levels to fit under the file's folders and own header
+	contentStr, err := shiftHeaderLevels(body, levels, limit)
+	if err != nil {
+		return "", err
+	}
 
 	// Strip leading/trailing whitespace
-	contentStr = strings.TrimSpace(contentStr)
+	return strings.TrimSpace(contentStr), nil
+}
 
-	// Get filename without extension
-	filename := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+// isOutputFile reports whether filePath is the file being written, so a
+// previous run's output inside the input directory is not combined again.
+func (mc *MarkdownCombiner) isOutputFile(filePath string) bool {
+	if mc.outputFile == stdoutName {
+		return false
+	}
 
-	return filename, contentStr, nil
+	if fileInfo, err := os.Stat(filePath); err == nil {
+		if outputInfo, err := os.Stat(mc.outputFile); err == nil {
+			return os.SameFile(fileInfo, outputInfo)
+		}
+	}
+
+	absFile, fileErr := filepath.Abs(filePath)
+	absOutput, outputErr := filepath.Abs(mc.outputFile)
+
+	return fileErr == nil && outputErr == nil && absFile == absOutput
+}
+
+// loadFiles reads the markdown files to combine and puts them in order:
+// the files listed in the index file, if there is one, followed by the
+// rest ordered by front matter unless unlisted files are skipped. Files
+// another file includes appear only where they are included, unless the
+// index file lists them.
+func (mc *MarkdownCombiner) loadFiles(markdownFiles []string) ([]*sourceFile, error) {
+	indexFile := mc.indexFile
+	if indexFile == "" {
+		indexFile = findIndexFile(mc.inputDir)
+	}
+
+	var paths, titles []string
+	seen := map[string]bool{}
+	if indexFile != "" {
+		entries, err := readIndex(indexFile)
+		if err != nil {
+			return nil, err
+		}
+		mc.logf("Using index file: %s\n", indexFile)
+
+		// An index that is itself markdown is not part of the document
+		seen[filepath.Clean(indexFile)] = true
+		for _, entry := range entries {
+			if seen[filepath.Clean(entry.Path)] {
+				continue
+			}
+			seen[filepath.Clean(entry.Path)] = true
+
+			paths = append(paths, entry.Path)
+			titles = append(titles, entry.Title)
+		}
+	}
+	listedCount := len(paths)
+
+	if indexFile == "" || mc.unlisted != unlistedSkip {
+		for _, filePath := range markdownFiles {
+			if !seen[filepath.Clean(filePath)] {
+				paths = append(paths, filePath)
+			}
+		}
+	}
+
+	// Read the files concurrently, then report on them in order
+	type loaded struct {
+		file *sourceFile
+		err  error
+	}
+	results := parallelMap(len(paths), mc.workers(), func(i int) loaded {
+		file, err := mc.loadSourceFile(paths[i])
+
+		return loaded{file, err}
+	})
+
+	// Note which file includes each one
+	includedBy := map[string]string{}
+	for i, result := range results {
+		if result.file == nil {
+			continue
+		}
+		for _, included := range result.file.includes {
+			if abs, err := filepath.Abs(included); err == nil {
+				includedBy[abs] = displayPath(mc.inputDir, paths[i])
+			}
+		}
+	}
+
+	var listed, unlisted []*sourceFile
+	for i, result := range results {
+		path := displayPath(mc.inputDir, paths[i])
+		mc.logf("Processing: %s\n", path)
+
+		if abs, err := filepath.Abs(paths[i]); err == nil && includedBy[abs] != "" && i >= listedCount {
+			mc.logf("Skipping %s: included by %s\n", path, includedBy[abs])
+
+			continue
+		}
+
+		if errors.Is(result.err, errGenerated) {
+			mc.logf("Skipping %s: %v\n", path, result.err)
+
+			continue
+		}
+		if result.err != nil {
+			mc.logf("Error processing %s: %v\n", path, result.err)
+
+			continue
+		}
+		for _, warning := range result.file.warnings {
+			mc.logf("Warning: %s\n", warning)
+		}
+
+		if i < listedCount {
+			result.file.title = titles[i]
+			listed = append(listed, result.file)
+		} else {
+			unlisted = append(unlisted, result.file)
+		}
+	}
+	sortSourceFiles(unlisted, mc.sortBy)
+
+	return append(listed, unlisted...), nil
 }
 
 // combineMarkdownFiles combines all markdown files in a directory into a single file.
 func (mc *MarkdownCombiner) combineMarkdownFiles() error {
 	// Get all markdown files
-	markdownFiles, err := getMarkdownFiles(mc.inputDir)
+	markdownFiles, err := getMarkdownFiles(mc.inputDir, mc.recursive)
 	if err != nil {
 		return err
 	}
 
 	if len(markdownFiles) == 0 {
-		fmt.Printf("No markdown files found in '%s'\n", mc.inputDir)
+		mc.logf("No markdown files found in '%s'\n", mc.inputDir)
 
 		return nil
 	}
 
-	fmt.Printf("Found %d markdown files\n", len(markdownFiles))
+	mc.logf("Found %d markdown files\n", len(markdownFiles))
 
-	// Process each file and combine content
-	var combinedContent strings.Builder
+	// Read each file in the order of the index file or front matter
+	files, err := mc.loadFiles(markdownFiles)
+	if err != nil {
+		return err
+	}
 
-	for _, filePath := range markdownFiles {
-		fmt.Printf("Processing: %s\n", filepath.Base(filePath))
+	// Combine the content of each file, with links between them pointing
+	// at their sections
+	sections, err := mc.buildSections(files)
+	if err != nil {
+		return err
+	}
+	assignAnchors(sections)
+	namespaceReferences(sections)
+	rewriteLinks(sections)
+	if !mc.dryRun {
+		mc.handleAssets(sections)
+	}
 
-		filename, content, err := processMarkdownFile(filePath)
+	body := renderSections(sections, mc.separator)
+	if mc.headingIdx {
+		body += renderHeadingIndex(sections)
+	}
+	combinedContent := body
+	if mc.template != "" {
+		combinedContent, err = mc.applyTemplate(sections, combinedContent)
 		if err != nil {
-			fmt.Printf("Error processing %s: %v\n", filepath.Base(filePath), err)
+			return err
+		}
+	}
 
-			continue
+	// Mark the output so later runs leave it out wherever it ends up
+	if mc.marker != "" {
+		combinedContent = mc.marker + "\n\n" + combinedContent
+	}
+
+	// Keep what was written around the generated part of the output
+	if mc.update {
+		combinedContent, err = mc.updateOutput(combinedContent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Show what would be written, leaving images and the output alone
+	if mc.dryRun {
+		mc.writePlan(os.Stdout, sections, combinedContent)
+		if mc.stats {
+			writeStats(os.Stdout, sections)
 		}
 
-		// Add filename as H1 header
-		combinedContent.WriteString(fmt.Sprintf("# %s\n\n", filename))
+		return nil
+	}
 
-		// Add processed content if it's not empty
-		if content != "" {
-			combinedContent.WriteString(content)
-			combinedContent.WriteString("\n\n")
-		} else {
-			combinedContent.WriteString("*This file was empty or contained only YAML front matter.*\n\n")
+	if err := mc.writeOutput(combinedContent); err != nil {
+		return err
+	}
+	if mc.manifest != "" {
+		if err := mc.writeManifest(sections, body, combinedContent); err != nil {
+			return err
 		}
+		mc.logf("Wrote manifest to '%s'\n", mc.manifest)
+	}
+
+	if mc.outputFile == stdoutName {
+		mc.logf("Successfully combined %d files to standard output\n", len(files))
+	} else {
+		mc.logf("Successfully combined %d files into '%s'\n", len(files), mc.outputFile)
+	}
+	if mc.stats {
+		writeStats(mc.log, sections)
+	}
+
+	return nil
+}
+
+// writeOutput writes the combined document to the output file, or to
+// standard output when the output file is "-".
+func (mc *MarkdownCombiner) writeOutput(combinedContent string) error {
+	if mc.outputFile == stdoutName {
+		if _, err := io.WriteString(os.Stdout, combinedContent); err != nil {
+			return fmt.Errorf("error writing to standard output: %v", err)
+		}
+
+		return nil
 	}
 
 	// Create output directory if it doesn't exist
 	outputDir := filepath.Dir(mc.outputFile)
 	if outputDir != "." && outputDir != "" {
-		err = os.MkdirAll(outputDir, 0755)
+		err := os.MkdirAll(outputDir, 0755)
 		if err != nil {
 			return fmt.Errorf("error creating output directory: %v", err)
 		}
@@ -198,13 +579,11 @@ func (mc *MarkdownCombiner) combineMarkdownFiles() error {
 
 	// Write combined content to output file
 	//nolint:gosec
-	err = os.WriteFile(mc.outputFile, []byte(combinedContent.String()), 0644)
+	err := os.WriteFile(mc.outputFile, []byte(combinedContent), 0644)
 	if err != nil {
 		return fmt.Errorf("error writing output file: %v", err)
 	}
 
-	fmt.Printf("Successfully combined %d files into '%s'\n", len(markdownFiles), mc.outputFile)
-
 	return nil
 }
 
@@ -216,37 +595,189 @@ Combines all markdown files in a directory into a single file.
 
 Usage:
   %s [options] <input_directory> [output_file]
+  %s split [options] <combined_file>
 
 Arguments:
   input_directory    Directory containing markdown files to combine
-  output_file        Output file path (default: combined_markdown.md)
+  output_file        Output file path, or - for standard output
+                     (default: combined_markdown.md)
 
 Options:
   -o, -output string    Alternative way to specify output file
+  -r, -recursive        Include subdirectories, with folders as nested sections
+  -index string         Index file listing the files in order (default:
+                        index.yaml, index.yml, or SUMMARY.md in the input
+                        directory)
+  -unlisted string      Files missing from the index: append or skip
+                        (default: append)
+  -sort string          Order within a directory: name, natural (2 before
+                        10), mtime, frontmatter-date, or git-date of the
+                        last commit (oldest first) (default: name)
+  -title-source string  Section titles from filename, frontmatter, or
+                        first-heading (default: frontmatter)
+  -assets string        Relative images: copy them next to the output,
+                        rewrite their paths for the output location, or
+                        embed them as data URIs (default: left as is)
+  -header-offset int    Levels added to each file's headers, which may be
+                        0 or negative (default: 1, or 0 with
+                        -no-filename-header)
+  -no-filename-header   Do not add a header naming each file
+  -max-level int        Deepest header level (default: 6)
+  -overflow string      Headers past -max-level: clamp to it, bold turns
+                        them into bold text, or error (default: clamp)
+  -separator string     Text put between files, such as --- or a page
+                        break; \n starts a new line (default: none)
+  -header-template string
+                        Go template for each section's title, given
+                        .Title, .Filename, .Path, .Frontmatter, and
+                        .ModTime (default: {{.Title}})
+  -section-meta         Show each file's path, modification date, and
+                        author (from front matter or git) under its header
+  -section-meta-template string
+                        Go template for the -section-meta block, given the
+                        -header-template fields and .Author
+  -heading-index        Append an alphabetical index linking to every
+                        header (not -index, which names the index file)
+  -template string      Go template wrapping the document, given .Title,
+                        .Date, .Time, .Files, .TOC, and .Content
+  -title string         Document title for -template (default: the input
+                        directory name)
+  -generated-marker string
+                        Text marking generated files: files containing it
+                        are skipped, and it starts the output
+  -update               Replace only the part of an existing output file
+                        between <!-- cmbd:start --> and <!-- cmbd:end -->,
+                        keeping what is written around it
+  -manifest string      Write a JSON description of the sections, their
+                        source files, offsets, anchors, and front matter
+  -changelog            Show the date and subject of each file's last
+                        commit under its header, ordering files by
+                        git-date unless -sort is given
+  -stats                Report each file's word, line, and heading counts
+                        and reading time, and the totals, also adding them
+                        to the -manifest
+  -jobs int             Number of files to read and process at once
+                        (default: one per CPU)
+  -strip-comments       Remove HTML comments, except in code blocks
+  -keep-comments string Comma-separated prefixes of comments to keep with
+                        -strip-comments (default: include:,cmbd:,pandoc)
+  -dry-run              Show the file order, section titles, front matter,
+                        and size without writing anything
   -h, -help            Show this help message
 
 Examples:
   %s docs/ combined.md
   %s /path/to/markdown/files output/all_docs.md
   %s . -o merged_documentation.md
+  %s -r docs/ handbook.md
+  %s -assets embed docs/ standalone.md
+  %s -o - docs/ | pandoc -o docs.pdf
+  %s -dry-run -r docs/
+  %s -template cover.md.tmpl -title Handbook docs/ handbook.md
+  %s -separator --- -header-template '{{.Title}} ({{.ModTime.Format "Jan 2"}})' docs/
+  %s -update docs/ README.md
+  %s -changelog notes/ CHANGELOG.md
+  %s split combined.md -out-dir docs/
 
 The program will:
-- Find all .md and .markdown files in the input directory
-- Remove YAML front matter from each file
-- Add the filename as an H1 header
-- Increase all existing header levels by one
-- Combine everything into a single output file
-
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+- Find all .md and .markdown files in the input directory, leaving out
+  the output file and files containing the -generated-marker
+- Remove YAML (---), TOML (+++), or JSON ({ }) front matter from each file
+- Replace <!-- include: path/to/file.md --> lines with that file's content,
+  which is then not combined again on its own
+- With -strip-comments, remove HTML comments other than kept directives
+- Order files as listed in an index file, if any, then by an order or
+  weight front matter field, then as -sort says
+- Add the front matter title or filename as an H1 header, through
+  -header-template if one is given, with a -section-meta block under it,
+  unless -no-filename-header is given
+- Increase all existing header levels by one, or by -header-offset
+- Point links between the combined files at their sections
+- Rename footnotes and link references defined by more than one file so
+  each file's references keep pointing at its own definitions
+- With -assets, copy, rewrite, or embed relative images
+- With -recursive, add each folder as a header and nest its files one
+  level below it
+- Combine everything into a single output file, followed by the
+  -heading-index and rendered into the -template wrapper if given, or
+  with -update put between the markers of the existing output file
+
+The split command reverses this, writing each H1 section of a combined
+file to its own file. Run '%s split -h' for its options.
+
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
 
 func main() {
+	// Hand the split command its own arguments
+	if len(os.Args) > 1 && os.Args[1] == "split" {
+		if err := runSplit(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	// Define command line flags
 	var outputFile string
 	var showHelp bool
+	var recursive bool
+	var indexFile string
+	var unlisted string
+	var titleSource string
+	var assets string
+	var dryRun bool
+	var maxLevel int
+	var overflow string
+	var templateFile string
+	var title string
+	var separator string
+	var headerTemplate string
+	var sectionMeta bool
+	var sectionMetaTemplate string
+	var headingIndex bool
+	var headerOffset int
+	var noFileHeader bool
+	var sortBy string
+	var marker string
+	var jobs int
+	var manifestFile string
+	var update bool
+	var stats bool
+	var changelog bool
+	var stripHTMLComments bool
+	var keepComments string
 
 	flag.StringVar(&outputFile, "output", "", "Output file path")
 	flag.StringVar(&outputFile, "o", "", "Output file path (shorthand)")
+	flag.BoolVar(&recursive, "recursive", false, "Include subdirectories as nested sections")
+	flag.BoolVar(&recursive, "r", false, "Include subdirectories as nested sections (shorthand)")
+	flag.StringVar(&indexFile, "index", "", "Index file listing the files in order")
+	flag.StringVar(&unlisted, "unlisted", unlistedAppend, "Files missing from the index: append or skip")
+	flag.StringVar(&sortBy, "sort", sortName, "Order within a directory: name, natural, mtime, frontmatter-date, or git-date")
+	flag.StringVar(&titleSource, "title-source", titleFrontmatter, "Section titles from filename, frontmatter, or first-heading")
+	flag.StringVar(&assets, "assets", "", "Relative images: copy, rewrite, or embed")
+	flag.IntVar(&headerOffset, "header-offset", 1, "Levels added to each file's headers (default 0 with -no-filename-header)")
+	flag.BoolVar(&noFileHeader, "no-filename-header", false, "Do not add a header naming each file")
+	flag.IntVar(&maxLevel, "max-level", 6, "Deepest header level")
+	flag.StringVar(&overflow, "overflow", overflowClamp, "Headers past -max-level: clamp, bold, or error")
+	flag.StringVar(&separator, "separator", "", "Text put between files")
+	flag.StringVar(&headerTemplate, "header-template", "", "Go template for each section's title")
+	flag.BoolVar(&sectionMeta, "section-meta", false, "Show each file's path, modification date, and author under its header")
+	flag.StringVar(&sectionMetaTemplate, "section-meta-template", "", "Go template for the -section-meta block")
+	flag.BoolVar(&headingIndex, "heading-index", false, "Append an alphabetical index linking to every header")
+	flag.StringVar(&templateFile, "template", "", "Go template wrapping the combined document")
+	flag.StringVar(&title, "title", "", "Document title for -template")
+	flag.StringVar(&marker, "generated-marker", "", "Text marking generated files, which are skipped")
+	flag.StringVar(&manifestFile, "manifest", "", "Write a JSON description of the sections to this file")
+	flag.BoolVar(&update, "update", false, "Replace only the part of the output file between cmbd:start and cmbd:end markers")
+	flag.BoolVar(&changelog, "changelog", false, "Show each file's last commit date and subject under its header")
+	flag.BoolVar(&stats, "stats", false, "Report word, line, and heading counts and reading times")
+	flag.IntVar(&jobs, "jobs", 0, "Number of files to process at once (0 means one per CPU)")
+	flag.BoolVar(&stripHTMLComments, "strip-comments", false, "Remove HTML comments")
+	flag.StringVar(&keepComments, "keep-comments", defaultKeptComments, "Comma-separated prefixes of comments to keep")
+	flag.BoolVar(&dryRun, "dry-run", false, "Show the plan without writing anything")
 	flag.BoolVar(&showHelp, "help", false, "Show help message")
 	flag.BoolVar(&showHelp, "h", false, "Show help message (shorthand)")
 
@@ -274,6 +805,70 @@ func main() {
 
 	inputDir := args[0]
 
+	if unlisted != unlistedAppend && unlisted != unlistedSkip {
+		fmt.Fprintf(os.Stderr, "Error: -unlisted must be %s or %s, not %q\n", unlistedAppend, unlistedSkip, unlisted)
+		os.Exit(1)
+	}
+
+	switch sortBy {
+	case sortName, sortNatural, sortMtime, sortFrontmatterDate, sortGitDate:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: -sort must be %s, %s, %s, %s, or %s, not %q\n",
+			sortName, sortNatural, sortMtime, sortFrontmatterDate, sortGitDate, sortBy)
+		os.Exit(1)
+	}
+
+	switch titleSource {
+	case titleFilename, titleFrontmatter, titleFirstHeading:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: -title-source must be %s, %s, or %s, not %q\n",
+			titleFilename, titleFrontmatter, titleFirstHeading, titleSource)
+		os.Exit(1)
+	}
+
+	switch assets {
+	case "", assetsCopy, assetsRewrite, assetsEmbed:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: -assets must be %s, %s, or %s, not %q\n",
+			assetsCopy, assetsRewrite, assetsEmbed, assets)
+		os.Exit(1)
+	}
+
+	if maxLevel < 1 || maxLevel > 6 {
+		fmt.Fprintf(os.Stderr, "Error: -max-level must be between 1 and 6, not %d\n", maxLevel)
+		os.Exit(1)
+	}
+
+	switch overflow {
+	case overflowClamp, overflowBold, overflowError:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: -overflow must be %s, %s, or %s, not %q\n",
+			overflowClamp, overflowBold, overflowError, overflow)
+		os.Exit(1)
+	}
+
+	// Flags given on the command line, for defaults that follow others
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	// Without a header naming each file, headers keep their levels unless
+	// asked otherwise
+	if noFileHeader && !given["header-offset"] {
+		headerOffset = 0
+	}
+
+	// A changelog follows the history unless another order is asked for
+	if changelog && !given["sort"] {
+		sortBy = sortGitDate
+	}
+
+	// A custom metadata template turns the block on
+	if sectionMeta && sectionMetaTemplate == "" {
+		sectionMetaTemplate = defaultSectionMetaTemplate
+	}
+
 	// Determine output file
 	defaultOutput := "combined_markdown.md"
 	finalOutput := defaultOutput
@@ -285,10 +880,45 @@ func main() {
 		finalOutput = args[1]
 	}
 
+	if update && finalOutput == stdoutName {
+		fmt.Fprintf(os.Stderr, "Error: -update needs an output file, not standard output\n")
+		os.Exit(1)
+	}
+
 	// Create combiner and execute
 	combiner := &MarkdownCombiner{
-		inputDir:   inputDir,
-		outputFile: finalOutput,
+		inputDir:      inputDir,
+		outputFile:    finalOutput,
+		recursive:     recursive,
+		indexFile:     indexFile,
+		unlisted:      unlisted,
+		titleSource:   titleSource,
+		assets:        assets,
+		dryRun:        dryRun,
+		limit:         levelLimit{max: maxLevel, overflow: overflow},
+		template:      templateFile,
+		title:         title,
+		separator:     strings.ReplaceAll(separator, `\n`, "\n"),
+		headerTmpl:    headerTemplate,
+		sectionMeta:   sectionMetaTemplate,
+		headingIdx:    headingIndex,
+		headerOffset:  headerOffset,
+		noFileHeader:  noFileHeader,
+		sortBy:        sortBy,
+		marker:        marker,
+		jobs:          jobs,
+		manifest:      manifestFile,
+		update:        update,
+		stats:         stats,
+		changelog:     changelog,
+		stripComments: stripHTMLComments,
+		keepComments:  strings.Split(keepComments, ","),
+		log:           os.Stdout,
+	}
+
+	// Keep progress messages out of a document or plan on standard output
+	if finalOutput == stdoutName || dryRun {
+		combiner.log = os.Stderr
 	}
 
 	err := combiner.combineMarkdownFiles()
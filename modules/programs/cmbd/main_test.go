@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestShiftHeaderLevels(t *testing.T) {
+	clamp := levelLimit{max: 6, overflow: overflowClamp}
+
+	tests := []struct {
+		name    string
+		content string
+		levels  int
+		limit   levelLimit
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "shift down",
+			content: "# Title\n\nText\n\n## Part ##",
+			levels:  1,
+			limit:   clamp,
+			want:    "## Title\n\nText\n\n### Part",
+		},
+		{
+			name:    "shift up stops at level one",
+			content: "# Title\n### Part",
+			levels:  -2,
+			limit:   clamp,
+			want:    "# Title\n# Part",
+		},
+		{
+			name:    "code blocks are left alone",
+			content: "# Title\n```sh\n# comment\n```\n~~~\n## not a header\n~~~",
+			levels:  1,
+			limit:   clamp,
+			want:    "## Title\n```sh\n# comment\n```\n~~~\n## not a header\n~~~",
+		},
+		{
+			name:    "not headers",
+			content: "#hashtag\n    # indented code\n####### seven",
+			levels:  1,
+			limit:   clamp,
+			want:    "#hashtag\n    # indented code\n####### seven",
+		},
+		{
+			name:    "clamp past the maximum",
+			content: "## Part\n### Detail",
+			levels:  2,
+			limit:   levelLimit{max: 4, overflow: overflowClamp},
+			want:    "#### Part\n#### Detail",
+		},
+		{
+			name:    "bold past the maximum",
+			content: "## Part\n### Detail",
+			levels:  2,
+			limit:   levelLimit{max: 4, overflow: overflowBold},
+			want:    "#### Part\n**Detail**",
+		},
+		{
+			name:    "error past the maximum",
+			content: "## Part\n### Detail",
+			levels:  2,
+			limit:   levelLimit{max: 4, overflow: overflowError},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shiftHeaderLevels(tt.content, tt.levels, tt.limit)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("shiftHeaderLevels() = %q, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("shiftHeaderLevels() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("shiftHeaderLevels() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// loadedPaths returns the display paths of the files mc combines, in order.
+func loadedPaths(t *testing.T, mc *MarkdownCombiner) []string {
+	t.Helper()
+	markdownFiles, err := getMarkdownFiles(mc.inputDir, mc.recursive)
+	if err != nil {
+		t.Fatalf("getMarkdownFiles() unexpected error: %v", err)
+	}
+	files, err := mc.loadFiles(markdownFiles)
+	if err != nil {
+		t.Fatalf("loadFiles() unexpected error: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.displayPath)
+	}
+
+	return paths
+}
+
+func TestLoadFilesSkipsIncludedFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "included file",
+			files: map[string]string{
+				"main.md": "<!-- include: part.md -->",
+				"part.md": "Part",
+			},
+			want: []string{"main.md"},
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"main.md":      "<!-- include: parts/one.md -->",
+				"other.md":     "Other",
+				"parts/one.md": "<!-- include: two.md -->",
+				"parts/two.md": "Two",
+			},
+			want: []string{"main.md", "other.md"},
+		},
+		{
+			name: "listed in the index",
+			files: map[string]string{
+				"index.yaml": "files:\n  - main.md\n  - part.md\n",
+				"main.md":    "<!-- include: part.md -->",
+				"part.md":    "Part",
+			},
+			want: []string{"main.md", "part.md"},
+		},
+		{
+			name: "unlisted in the index",
+			files: map[string]string{
+				"index.yaml": "files:\n  - main.md\n",
+				"main.md":    "<!-- include: part.md -->",
+				"part.md":    "Part",
+			},
+			want: []string{"main.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			mc := &MarkdownCombiner{
+				inputDir:    dir,
+				recursive:   true,
+				unlisted:    unlistedAppend,
+				titleSource: titleFilename,
+				sortBy:      sortName,
+				log:         io.Discard,
+			}
+			if got := loadedPaths(t, mc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifest describes a combined document for -manifest.
+type manifest struct {
+	Output    string            `json:"output"`
+	Generated time.Time         `json:"generated"`
+	Size      int               `json:"size"`
+	Stats     *textStats        `json:"stats,omitempty"`
+	Sections  []manifestSection `json:"sections"`
+}
+
+// manifestSection maps a section of the combined document back to its
+// source file. Offsets run from the section header to the end of its
+// content: bytes count from 0 with the end exclusive, and lines count from 1
+// with the end inclusive.
+type manifestSection struct {
+	Source      string         `json:"source"`
+	Title       string         `json:"title"`
+	Anchor      string         `json:"anchor"`
+	Level       int            `json:"level"`
+	Folders     []string       `json:"folders,omitempty"`
+	StartByte   int            `json:"start_byte"`
+	EndByte     int            `json:"end_byte"`
+	StartLine   int            `json:"start_line"`
+	EndLine     int            `json:"end_line"`
+	Frontmatter map[string]any `json:"frontmatter,omitempty"`
+	Stats       *textStats     `json:"stats,omitempty"`
+}
+
+// writeManifest writes the -manifest file for document, the final output
+// containing the rendered sections as body.
+func (mc *MarkdownCombiner) writeManifest(sections []*section, body, document string) error {
+	// The template or marker may have put text before the sections
+	base := strings.Index(document, body)
+	if base < 0 {
+		mc.logf("Warning: sections not found in the output; manifest offsets are relative to them\n")
+		base = 0
+	}
+
+	m := manifest{
+		Output:    mc.outputFile,
+		Generated: time.Now(),
+		Size:      len(document),
+		Sections:  []manifestSection{},
+	}
+	if mc.stats {
+		m.Stats = &textStats{}
+	}
+	for _, s := range sections {
+		start, end := base+s.start, base+s.end
+		entry := manifestSection{
+			Source:      filepath.ToSlash(s.file.displayPath),
+			Title:       s.header.text,
+			Anchor:      s.header.anchor,
+			Level:       s.header.level,
+			Folders:     s.file.dirs,
+			StartByte:   start,
+			EndByte:     end,
+			StartLine:   lineAt(document, start),
+			EndLine:     lineAt(document, end),
+			Frontmatter: s.file.frontmatter,
+		}
+
+		// Word counts go along with -stats
+		if mc.stats {
+			stats := sectionStats(s)
+			entry.Stats = &stats
+			*m.Stats = m.Stats.add(stats)
+		}
+		m.Sections = append(m.Sections, entry)
+	}
+
+	encoded, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %v", err)
+	}
+
+	if dir := filepath.Dir(mc.manifest); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating manifest directory: %v", err)
+		}
+	}
+
+	//nolint:gosec
+	if err := os.WriteFile(mc.manifest, append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+
+	return nil
+}
+
+// lineAt returns the 1-based line number of a byte offset in text.
+func lineAt(text string, offset int) int {
+	return strings.Count(text[:min(offset, len(text))], "\n") + 1
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	files := map[string]string{
+		"a.md":       "---\ntitle: Alpha\n---\nAlpha text\n\n## Part\n\nmore\n",
+		"b.md":       "Beta\n",
+		"sub/c.md":   "Gamma\n",
+		"notes.tmpl": "Cover\n\n{{.TOC}}\n{{.Content}}\nBack cover\n",
+	}
+	existing := "Intro\nkept above\n\n<!-- cmbd:start -->\nold\n<!-- cmbd:end -->\n\nFooter\n"
+
+	// Each section runs from its header to the end of its content
+	want := []struct {
+		source, header, last string
+	}{
+		{source: "a.md", header: "# Alpha", last: "more"},
+		{source: "b.md", header: "# b", last: "Beta"},
+		{source: "sub/c.md", header: "## c", last: "Gamma"},
+	}
+
+	tests := []struct {
+		name     string
+		template bool
+		marker   string
+		update   bool
+	}{
+		{name: "plain"},
+		{name: "template", template: true},
+		{name: "generated marker", marker: "<!-- generated by cmbd -->"},
+		{name: "update", update: true},
+		{name: "template, marker, and update", template: true, marker: "<!-- generated by cmbd -->", update: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputDir := t.TempDir()
+			writeFiles(t, inputDir, files)
+			outputDir := t.TempDir()
+			if tt.update {
+				writeFiles(t, outputDir, map[string]string{"combined.md": existing})
+			}
+
+			mc := &MarkdownCombiner{
+				inputDir:     inputDir,
+				outputFile:   filepath.Join(outputDir, "combined.md"),
+				recursive:    true,
+				unlisted:     unlistedAppend,
+				titleSource:  titleFrontmatter,
+				limit:        levelLimit{max: 6, overflow: overflowClamp},
+				headerOffset: 1,
+				sortBy:       sortName,
+				marker:       tt.marker,
+				update:       tt.update,
+				manifest:     filepath.Join(outputDir, "combined.json"),
+				log:          io.Discard,
+			}
+			if tt.template {
+				mc.template = filepath.Join(inputDir, "notes.tmpl")
+			}
+			if err := mc.combineMarkdownFiles(); err != nil {
+				t.Fatalf("combineMarkdownFiles() unexpected error: %v", err)
+			}
+
+			output, err := os.ReadFile(mc.outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			document := string(output)
+			encoded, err := os.ReadFile(mc.manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m manifest
+			if err := json.Unmarshal(encoded, &m); err != nil {
+				t.Fatalf("manifest is not valid JSON: %v", err)
+			}
+
+			if m.Size != len(document) {
+				t.Errorf("manifest size = %d, want %d", m.Size, len(document))
+			}
+			if len(m.Sections) != len(want) {
+				t.Fatalf("manifest has %d sections, want %d", len(m.Sections), len(want))
+			}
+
+			lines := strings.Split(document, "\n")
+			for i, s := range m.Sections {
+				w := want[i]
+				if s.Source != w.source {
+					t.Errorf("section %d source = %q, want %q", i, s.Source, w.source)
+				}
+				if s.StartByte < 0 || s.EndByte > len(document) || s.StartByte > s.EndByte {
+					t.Fatalf("section %d bytes [%d, %d) outside the %d byte document", i, s.StartByte, s.EndByte, len(document))
+				}
+				text := document[s.StartByte:s.EndByte]
+				if !strings.HasPrefix(text, w.header+"\n") || !strings.HasSuffix(text, "\n"+w.last) {
+					t.Errorf("section %d bytes [%d, %d) = %q, want it from %q to %q", i, s.StartByte, s.EndByte, text, w.header, w.last)
+				}
+				if s.StartLine < 1 || s.EndLine > len(lines) {
+					t.Fatalf("section %d lines %d-%d outside the %d line document", i, s.StartLine, s.EndLine, len(lines))
+				}
+				if got := lines[s.StartLine-1]; got != w.header {
+					t.Errorf("section %d start line %d = %q, want %q", i, s.StartLine, got, w.header)
+				}
+				if got := lines[s.EndLine-1]; got != w.last {
+					t.Errorf("section %d end line %d = %q, want %q", i, s.EndLine, got, w.last)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// defaultSectionMetaTemplate is the metadata block -section-meta puts under
+// each section header.
+const defaultSectionMetaTemplate = `*Source: {{.Path}} · Modified: {{.ModTime.Format "2006-01-02"}}` +
+	`{{with .Author}} · Author: {{.}}{{end}}*`
+
+// sectionData is what the -header-template and -section-meta-template are
+// rendered with.
+type sectionData struct {
+	Title       string // Title from the index file, front matter, or filename
+	Filename    string // Filename without extension
+	Path        string // Path relative to the input directory
+	Frontmatter map[string]any
+	ModTime     time.Time
+
+	file       *sourceFile
+	authorOnce sync.Once
+	author     string
+}
+
+// newSectionData returns the template data for a file's section.
+func newSectionData(file *sourceFile, title string) *sectionData {
+	return &sectionData{
+		Title:       title,
+		Filename:    strings.TrimSuffix(filepath.Base(file.path), filepath.Ext(file.path)),
+		Path:        file.displayPath,
+		Frontmatter: file.frontmatter,
+		ModTime:     file.modTime,
+		file:        file,
+	}
+}
+
+// Author returns the author front matter field, or else the author of the
+// file's last git commit, or "" if neither is known. Git is only asked when
+// a template uses the author.
+func (d *sectionData) Author() string {
+	d.authorOnce.Do(func() {
+		switch author := d.Frontmatter["author"].(type) {
+		case string:
+			d.author = strings.TrimSpace(author)
+		case []any:
+			names := make([]string, 0, len(author))
+			for _, name := range author {
+				names = append(names, fmt.Sprint(name))
+			}
+			d.author = strings.Join(names, ", ")
+		}
+		if d.author == "" {
+			d.author = gitAuthor(d.file.path)
+		}
+	})
+
+	return d.author
+}
+
+// gitAuthor returns the author of the last commit touching a file, or "" if
+// it is not tracked by git.
+func gitAuthor(filePath string) string {
+	cmd := exec.Command("git", "log", "-1", "--format=%an", "--", filepath.Base(filePath))
+	cmd.Dir = filepath.Dir(filePath)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// renderSectionMeta renders the metadata block for a file's section.
+func renderSectionMeta(tmpl *template.Template, data *sectionData) (string, error) {
+	var meta strings.Builder
+	if err := tmpl.Execute(&meta, data); err != nil {
+		return "", fmt.Errorf("error rendering section metadata template: %v", err)
+	}
+
+	return strings.TrimSpace(meta.String()), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// runGit runs git in dir as a fixed author, committing at date if given,
+// and skips the test when git is not available.
+func runGit(t *testing.T, dir, date string, args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Grace Hopper", "GIT_AUTHOR_EMAIL=grace@example.com",
+		"GIT_COMMITTER_NAME=Grace Hopper", "GIT_COMMITTER_EMAIL=grace@example.com")
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestRenderSectionMeta(t *testing.T) {
+	modTime := time.Date(2024, 3, 9, 15, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		template    string
+		frontmatter map[string]any
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "author from front matter",
+			template:    defaultSectionMetaTemplate,
+			frontmatter: map[string]any{"author": " Ada Lovelace "},
+			want:        "*Source: guide/setup.md · Modified: 2024-03-09 · Author: Ada Lovelace*",
+		},
+		{
+			name:        "list of authors",
+			template:    defaultSectionMetaTemplate,
+			frontmatter: map[string]any{"author": []any{"Ada", "Grace"}},
+			want:        "*Source: guide/setup.md · Modified: 2024-03-09 · Author: Ada, Grace*",
+		},
+		{
+			name:     "no author",
+			template: defaultSectionMetaTemplate,
+			want:     "*Source: guide/setup.md · Modified: 2024-03-09*",
+		},
+		{
+			name:     "custom template",
+			template: "\n  {{.Title}} from {{.Filename}} ({{.Path}})\n",
+			want:     "Setup from setup (guide/setup.md)",
+		},
+		{
+			name:     "execution error",
+			template: "{{.Missing}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Outside a git repository, so git knows no author
+			path := filepath.Join(t.TempDir(), "guide", "setup.md")
+			file := &sourceFile{path: path, displayPath: "guide/setup.md", frontmatter: tt.frontmatter, modTime: modTime}
+			tmpl := template.Must(template.New("section-meta").Parse(tt.template))
+
+			got, err := renderSectionMeta(tmpl, newSectionData(file, "Setup"))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("renderSectionMeta() = %q, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderSectionMeta() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderSectionMeta() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitAuthor(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"tracked.md": "Tracked", "untracked.md": "Untracked"})
+	runGit(t, dir, "", "init", "-q")
+	runGit(t, dir, "", "add", "tracked.md")
+	runGit(t, dir, "", "commit", "-q", "-m", "Add tracked")
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "tracked.md", want: "Grace Hopper"},
+		{file: "untracked.md", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := gitAuthor(filepath.Join(dir, tt.file)); got != tt.want {
+				t.Errorf("gitAuthor(%s) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+
+	// The front matter author wins over git
+	file := &sourceFile{path: filepath.Join(dir, "tracked.md"), frontmatter: map[string]any{"author": "Ada"}}
+	if got := newSectionData(file, "Tracked").Author(); got != "Ada" {
+		t.Errorf("Author() = %q, want %q", got, "Ada")
+	}
+	file.frontmatter = nil
+	if got := newSectionData(file, "Tracked").Author(); got != "Grace Hopper" {
+		t.Errorf("Author() = %q, want %q", got, "Grace Hopper")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// workers returns the number of files to process at once for -jobs.
+func (mc *MarkdownCombiner) workers() int {
+	if mc.jobs > 0 {
+		return mc.jobs
+	}
+
+	return runtime.NumCPU()
+}
+
+// parallelMap calls fn for each index below n on up to jobs goroutines and
+// returns the results in index order.
+func parallelMap[T any](n, jobs int, fn func(i int) T) []T {
+	results := make([]T, n)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(jobs, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
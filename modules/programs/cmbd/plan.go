@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writePlan describes the document that would be written, for -dry-run:
+// each header in order with the file behind it and its front matter,
+// followed by the size of the result.
+func (mc *MarkdownCombiner) writePlan(w io.Writer, sections []*section, combinedContent string) {
+	// Align the source paths after the longest header
+	width := 0
+	headerLine := func(s *section) string {
+		if s.headless {
+			return "(no header)"
+		}
+
+		return s.header.markdown()
+	}
+	for _, s := range sections {
+		width = max(width, len(headerLine(s)))
+	}
+
+	for _, s := range sections {
+		for _, folder := range s.folders {
+			fmt.Fprintln(w, folder.markdown())
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", width, headerLine(s), s.file.displayPath)
+
+		if len(s.file.frontmatter) > 0 {
+			fmt.Fprintf(w, "%*s  frontmatter: %s\n", width, "", formatFrontmatter(s.file.frontmatter))
+		}
+	}
+
+	destination := "'" + mc.outputFile + "'"
+	if mc.outputFile == stdoutName {
+		destination = "standard output"
+	}
+	fmt.Fprintf(w, "\n%d files, %s, would be written to %s\n",
+		len(sections), formatSize(len(combinedContent)), destination)
+}
+
+// formatFrontmatter lists front matter fields as key=value pairs sorted by
+// key.
+func formatFrontmatter(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", key, fields[key])
+	}
+
+	return strings.Join(pairs, ", ")
+}
+
+// formatSize formats a byte count for people.
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what fn writes to standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	fn()
+
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+func TestDryRun(t *testing.T) {
+	inputDir := t.TempDir()
+	writeFiles(t, inputDir, map[string]string{
+		"a.md":             "---\ntitle: Alpha\norder: 1\n---\nText ![logo](img/logo.png)\n",
+		"b.md":             "## Part\n",
+		"guide/setup.md":   "Setup\n",
+		"img/logo.png":     "png",
+		"guide/ignore.txt": "not markdown",
+	})
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	mc := &MarkdownCombiner{
+		inputDir:     inputDir,
+		outputFile:   filepath.Join(outputDir, "combined.md"),
+		recursive:    true,
+		unlisted:     unlistedAppend,
+		titleSource:  titleFrontmatter,
+		assets:       assetsCopy,
+		dryRun:       true,
+		limit:        levelLimit{max: 6, overflow: overflowClamp},
+		headerOffset: 1,
+		sortBy:       sortName,
+		manifest:     filepath.Join(outputDir, "combined.json"),
+		log:          io.Discard,
+	}
+
+	var err error
+	plan := captureStdout(t, func() {
+		err = mc.combineMarkdownFiles()
+	})
+	if err != nil {
+		t.Fatalf("combineMarkdownFiles() unexpected error: %v", err)
+	}
+
+	want := "# Alpha   a.md\n" +
+		"          frontmatter: order=1, title=Alpha\n" +
+		"# b       b.md\n" +
+		"# guide\n" +
+		"## setup  guide/setup.md\n" +
+		"\n3 files, 78 bytes, would be written to '" + mc.outputFile + "'\n"
+	if plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+
+	// Neither the output, the manifest, nor copied images are written
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("output directory exists after a dry run: %v", err)
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputFile string
+		sections   []*section
+		content    string
+		want       string
+	}{
+		{
+			name:       "headless sections",
+			outputFile: "out.md",
+			sections: []*section{
+				{file: &sourceFile{displayPath: "a.md"}, headless: true},
+				{file: &sourceFile{displayPath: "b.md"}, header: heading{level: 7, text: "Deep", bold: true}},
+			},
+			content: "x",
+			want:    "(no header)  a.md\n**Deep**     b.md\n\n2 files, 1 bytes, would be written to 'out.md'\n",
+		},
+		{
+			name:       "standard output",
+			outputFile: stdoutName,
+			sections: []*section{
+				{file: &sourceFile{displayPath: "a.md"}, header: heading{level: 1, text: "A"}},
+			},
+			content: string(make([]byte, 3<<10)),
+			want:    "# A  a.md\n\n1 files, 3.0 KB, would be written to standard output\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plan strings.Builder
+			(&MarkdownCombiner{outputFile: tt.outputFile}).writePlan(&plan, tt.sections, tt.content)
+			if plan.String() != tt.want {
+				t.Errorf("writePlan() = %q, want %q", plan.String(), tt.want)
+			}
+		})
+	}
+}
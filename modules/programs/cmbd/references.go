@@ -0,0 +1,187 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// footnotePattern matches a footnote reference or the start of its
+	// definition, capturing the identifier.
+	footnotePattern = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+
+	// footnoteDefPattern matches a footnote definition.
+	footnoteDefPattern = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:`)
+
+	// referenceDefPattern matches a link reference definition, capturing the
+	// label.
+	referenceDefPattern = regexp.MustCompile(`^( {0,3}\[)([^\]^][^\]]*)(\]:)`)
+
+	// fullReferencePattern matches a full or collapsed reference link,
+	// capturing the text and the label.
+	fullReferencePattern = regexp.MustCompile(`(\[[^\]]*\])\[([^\]]*)\]`)
+
+	// bracketPattern matches bracketed text that may be a shortcut
+	// reference link.
+	bracketPattern = regexp.MustCompile(`\[([^\]^][^\]]*)\]`)
+)
+
+// referenceLabel normalizes a link reference label, which matches case
+// insensitively and ignoring runs of whitespace.
+func referenceLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// sectionReferences holds the footnotes and link references a section
+// defines.
+type sectionReferences struct {
+	footnotes map[string]bool
+	links     map[string]bool
+}
+
+// findReferences returns the footnote identifiers and normalized link
+// reference labels defined in content, outside code blocks.
+func findReferences(content string) sectionReferences {
+	refs := sectionReferences{footnotes: map[string]bool{}, links: map[string]bool{}}
+	inFence := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if match := footnoteDefPattern.FindStringSubmatch(line); match != nil {
+			refs.footnotes[match[1]] = true
+		} else if match := referenceDefPattern.FindStringSubmatch(line); match != nil {
+			refs.links[referenceLabel(match[2])] = true
+		}
+	}
+
+	return refs
+}
+
+// namespaceReferences renames the footnotes and link references that more
+// than one file defines, prefixing them with their section's anchor, so
+// each file's references keep pointing at its own definitions in the
+// combined document.
+func namespaceReferences(sections []*section) {
+	refs := make([]sectionReferences, len(sections))
+	footnoteCount := map[string]int{}
+	linkCount := map[string]int{}
+	for i, s := range sections {
+		refs[i] = findReferences(s.content)
+		for id := range refs[i].footnotes {
+			footnoteCount[id]++
+		}
+		for label := range refs[i].links {
+			linkCount[label]++
+		}
+	}
+
+	for i, s := range sections {
+		footnotes := map[string]bool{}
+		for id := range refs[i].footnotes {
+			if footnoteCount[id] > 1 {
+				footnotes[id] = true
+			}
+		}
+		links := map[string]bool{}
+		for label := range refs[i].links {
+			if linkCount[label] > 1 {
+				links[label] = true
+			}
+		}
+
+		if len(footnotes)+len(links) > 0 {
+			namespace := s.header.anchor
+			if s.headless || s.header.bold {
+				namespace = slugify(s.header.text)
+			}
+			s.content = renameReferences(s.content, namespace, footnotes, links)
+		}
+	}
+}
+
+// renameReferences prefixes the given footnote identifiers and link labels
+// in content with namespace, in both references and definitions.
+func renameReferences(content, namespace string, footnotes, links map[string]bool) string {
+	rename := func(label string) string {
+		return namespace + "-" + label
+	}
+
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		line = footnotePattern.ReplaceAllStringFunc(line, func(ref string) string {
+			id := footnotePattern.FindStringSubmatch(ref)[1]
+			if !footnotes[id] {
+				return ref
+			}
+
+			return "[^" + rename(id) + "]"
+		})
+
+		// A definition has nothing else to rename on its line
+		if match := referenceDefPattern.FindStringSubmatchIndex(line); match != nil {
+			if label := line[match[4]:match[5]]; links[referenceLabel(label)] {
+				line = line[:match[4]] + rename(label) + line[match[5]:]
+			}
+			lines[i] = line
+
+			continue
+		}
+
+		// [text][label] and [label][]
+		line = fullReferencePattern.ReplaceAllStringFunc(line, func(ref string) string {
+			match := fullReferencePattern.FindStringSubmatch(ref)
+			label := match[2]
+			if label == "" {
+				label = match[1][1 : len(match[1])-1]
+			}
+			if !links[referenceLabel(label)] {
+				return ref
+			}
+
+			return match[1] + "[" + rename(label) + "]"
+		})
+
+		// [label] on its own, keeping its text
+		var renamed strings.Builder
+		last := 0
+		for _, match := range bracketPattern.FindAllStringSubmatchIndex(line, -1) {
+			start, end := match[0], match[1]
+			label := line[match[2]:match[3]]
+			if start > 0 && (line[start-1] == ']' || line[start-1] == '\\') {
+				continue
+			}
+			if end < len(line) && strings.ContainsRune("([:", rune(line[end])) {
+				continue
+			}
+			if !links[referenceLabel(label)] {
+				continue
+			}
+
+			renamed.WriteString(line[last:end])
+			renamed.WriteString("[" + rename(label) + "]")
+			last = end
+		}
+		renamed.WriteString(line[last:])
+		lines[i] = renamed.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func TestRenameReferences(t *testing.T) {
+	footnotes := map[string]bool{"1": true}
+	links := map[string]bool{"docs": true}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "footnote reference and definition",
+			content: "Text[^1] and more[^2].\n\n[^1]: One.\n[^2]: Two.",
+			want:    "Text[^a-1] and more[^2].\n\n[^a-1]: One.\n[^2]: Two.",
+		},
+		{
+			name:    "definition",
+			content: "[Docs]: https://example.com\n[other]: https://example.org",
+			want:    "[a-Docs]: https://example.com\n[other]: https://example.org",
+		},
+		{
+			name:    "full and collapsed references",
+			content: "[the docs][docs], [docs][], and [x][other]",
+			want:    "[the docs][a-docs], [docs][a-docs], and [x][other]",
+		},
+		{
+			name:    "shortcut reference",
+			content: "Read [Docs] first.",
+			want:    "Read [Docs][a-Docs] first.",
+		},
+		{
+			name:    "inline links and escaped brackets are left alone",
+			content: "[docs](docs.md) and \\[docs]",
+			want:    "[docs](docs.md) and \\[docs]",
+		},
+		{
+			name:    "code blocks are left alone",
+			content: "```\n[docs] [^1]\n```",
+			want:    "```\n[docs] [^1]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renameReferences(tt.content, "a", footnotes, links); got != tt.want {
+				t.Errorf("renameReferences() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []*section
+		want     []string
+	}{
+		{
+			name: "shared footnote",
+			sections: []*section{
+				{header: heading{text: "A", anchor: "a"}, content: "A[^1]\n\n[^1]: From a."},
+				{header: heading{text: "B", anchor: "b"}, content: "B[^1]\n\n[^1]: From b."},
+			},
+			want: []string{
+				"A[^a-1]\n\n[^a-1]: From a.",
+				"B[^b-1]\n\n[^b-1]: From b.",
+			},
+		},
+		{
+			name: "shared link label, matched case insensitively",
+			sections: []*section{
+				{header: heading{text: "A", anchor: "a"}, content: "[Home]\n\n[home]: a.html"},
+				{header: heading{text: "B", anchor: "b"}, content: "[HOME]\n\n[HOME]: b.html"},
+			},
+			want: []string{
+				"[Home][a-Home]\n\n[a-home]: a.html",
+				"[HOME][b-HOME]\n\n[b-HOME]: b.html",
+			},
+		},
+		{
+			name: "unique references are left alone",
+			sections: []*section{
+				{header: heading{text: "A", anchor: "a"}, content: "A[^1]\n\n[^1]: From a."},
+				{header: heading{text: "B", anchor: "b"}, content: "B[^2]\n\n[^2]: From b."},
+			},
+			want: []string{
+				"A[^1]\n\n[^1]: From a.",
+				"B[^2]\n\n[^2]: From b.",
+			},
+		},
+		{
+			name: "headless and bold sections use their title",
+			sections: []*section{
+				{header: heading{text: "Part A", anchor: "intro"}, headless: true, content: "A[^1]\n\n[^1]: a"},
+				{header: heading{text: "Part B", bold: true}, content: "B[^1]\n\n[^1]: b"},
+			},
+			want: []string{
+				"A[^part-a-1]\n\n[^part-a-1]: a",
+				"B[^part-b-1]\n\n[^part-b-1]: b",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaceReferences(tt.sections)
+			for i, s := range tt.sections {
+				if s.content != tt.want[i] {
+					t.Errorf("section %d content = %q, want %q", i, s.content, tt.want[i])
+				}
+			}
+		})
+	}
+}
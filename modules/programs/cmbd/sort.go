@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Orders files are combined in within a directory, after any order or
+// weight front matter field.
+const (
+	sortName            = "name"             // Alphabetically by filename
+	sortNatural         = "natural"          // By filename, with numbers compared by value
+	sortMtime           = "mtime"            // Oldest modification time first
+	sortFrontmatterDate = "frontmatter-date" // Oldest front matter date first
+	sortGitDate         = "git-date"         // Oldest last commit first
+)
+
+// frontmatterDateLayouts are the date formats accepted in a date front
+// matter field.
+var frontmatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// sortTime returns the time a file is ordered by under sortBy, and whether
+// it has one.
+func sortTime(file *sourceFile, sortBy string) (time.Time, bool) {
+	switch sortBy {
+	case sortMtime:
+		return file.modTime, !file.modTime.IsZero()
+	case sortFrontmatterDate:
+		return frontmatterDate(file.frontmatter)
+	case sortGitDate:
+		if file.commit != nil {
+			return file.commit.date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// frontmatterDate returns the date front matter field, whether YAML decoded
+// it as a timestamp or left it a string.
+func frontmatterDate(fields map[string]any) (time.Time, bool) {
+	switch value := fields["date"].(type) {
+	case time.Time:
+		return value, true
+	case string:
+		for _, layout := range frontmatterDateLayouts {
+			if date, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+				return date, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// naturalLess compares strings so that runs of digits are ordered by their
+// numeric value, putting 2-intro before 10-advanced.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aChunk, aDigits := leadingChunk(a)
+		bChunk, bDigits := leadingChunk(b)
+		a, b = a[len(aChunk):], b[len(bChunk):]
+
+		if aDigits && bDigits {
+			aNum := strings.TrimLeft(aChunk, "0")
+			bNum := strings.TrimLeft(bChunk, "0")
+			if len(aNum) != len(bNum) {
+				return len(aNum) < len(bNum)
+			}
+			if aNum != bNum {
+				return aNum < bNum
+			}
+		}
+		if aChunk != bChunk {
+			return aChunk < bChunk
+		}
+	}
+
+	return len(a) < len(b)
+}
+
+// leadingChunk returns the leading run of digits or of non-digits in s, and
+// whether it is digits.
+func leadingChunk(s string) (string, bool) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	digits := isDigit(s[0])
+	end := 1
+	for end < len(s) && isDigit(s[end]) == digits {
+		end++
+	}
+
+	return s[:end], digits
+}
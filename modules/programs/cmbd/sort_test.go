@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "2-intro.md", b: "10-advanced.md", want: true},
+		{a: "10-advanced.md", b: "2-intro.md", want: false},
+		{a: "chapter2.md", b: "chapter10.md", want: true},
+		{a: "chapter02.md", b: "chapter10.md", want: true},
+		{a: "a.md", b: "b.md", want: true},
+		{a: "b.md", b: "a.md", want: false},
+		{a: "file", b: "file1", want: true},
+		{a: "file1", b: "file", want: false},
+		{a: "same.md", b: "same.md", want: false},
+		{a: "v1.2.md", b: "v1.10.md", want: true},
+		{a: "", b: "a", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"<"+tt.b, func(t *testing.T) {
+			if got := naturalLess(tt.a, tt.b); got != tt.want {
+				t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
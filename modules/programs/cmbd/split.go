@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// emptySectionText is the placeholder written for a file with no content.
+const emptySectionText = "*This file was empty or contained only YAML front matter.*"
+
+// splitSection is a file recovered from a combined document.
+type splitSection struct {
+	title   string
+	content string
+}
+
+// splitDocument splits a combined document at its H1 headers, undoing the
+// header shift applied when combining. It also returns the number of
+// non-blank lines before the first H1, which belong to no section.
+func splitDocument(content string) ([]splitSection, int) {
+	var sections []splitSection
+	var lines []string
+	preamble := 0
+	inFence := false
+
+	flush := func() {
+		defer func() { lines = nil }()
+		if len(sections) == 0 {
+			return
+		}
+
+		body := strings.TrimSpace(strings.Join(lines, "\n"))
+		if body == emptySectionText {
+			body = ""
+		}
+		sections[len(sections)-1].content = body
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+		}
+
+		level, text, ok := parseHeading(line)
+		switch {
+		case inFence || !ok:
+			if len(sections) == 0 && strings.TrimSpace(line) != "" {
+				preamble++
+			}
+			lines = append(lines, line)
+		case level == 1:
+			flush()
+			sections = append(sections, splitSection{title: text})
+		default:
+			lines = append(lines, strings.Repeat("#", level-1)+" "+text)
+		}
+	}
+	flush()
+
+	return sections, preamble
+}
+
+// sectionFilename turns a section title back into a markdown filename,
+// replacing characters that cannot appear in one.
+func sectionFilename(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '-'
+		}
+
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" || name == "." || name == ".." {
+		name = "section"
+	}
+
+	return name + ".md"
+}
+
+// printSplitUsage prints usage information for the split command.
+func printSplitUsage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  %s split [options] <combined_file>
+
+Splits a combined document back into one file per H1 section, named after
+its header, and decreases the remaining header levels by one. Use - to read
+the document from standard input.
+
+Options:
+  -out-dir string    Directory to write the files to (default: .)
+  -h, -help          Show this help message
+
+Examples:
+  %s split combined.md -out-dir docs/
+
+`, os.Args[0], os.Args[0])
+}
+
+// runSplit runs the split command with its arguments.
+func runSplit(args []string) error {
+	flags := flag.NewFlagSet("split", flag.ExitOnError)
+	flags.Usage = printSplitUsage
+
+	var outDir string
+	var showHelp bool
+	flags.StringVar(&outDir, "out-dir", ".", "Directory to write the files to")
+	flags.BoolVar(&showHelp, "help", false, "Show help message")
+	flags.BoolVar(&showHelp, "h", false, "Show help message (shorthand)")
+
+	// Allow options after the combined file as well as before it
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var positional []string
+	for flags.NArg() > 0 {
+		positional = append(positional, flags.Arg(0))
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	if showHelp {
+		printSplitUsage()
+		os.Exit(0)
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("split takes one combined file, got %d arguments", len(positional))
+	}
+
+	var content []byte
+	var err error
+	if positional[0] == stdoutName {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(positional[0])
+	}
+	if err != nil {
+		return fmt.Errorf("error reading combined file: %v", err)
+	}
+
+	sections, preamble := splitDocument(string(content))
+	if len(sections) == 0 {
+		return fmt.Errorf("no H1 sections found in '%s'", positional[0])
+	}
+	if preamble > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %d lines before the first H1 header\n", preamble)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	// Number repeated titles so no section overwrites another
+	used := map[string]int{}
+	for _, s := range sections {
+		name := sectionFilename(s.title)
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s-%d.md", strings.TrimSuffix(name, ".md"), used[name])
+		}
+
+		content := s.content
+		if content != "" {
+			content += "\n"
+		}
+
+		//nolint:gosec
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %v", name, err)
+		}
+		fmt.Printf("Wrote: %s\n", filepath.Join(outDir, name))
+	}
+
+	fmt.Printf("Successfully split '%s' into %d files in '%s'\n", positional[0], len(sections), outDir)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		want         []splitSection
+		wantPreamble int
+	}{
+		{
+			name:    "sections",
+			content: "# a\n\nText\n\n## Part\n\n# b\n\n### Deep",
+			want: []splitSection{
+				{title: "a", content: "Text\n\n# Part"},
+				{title: "b", content: "## Deep"},
+			},
+		},
+		{
+			name:    "code blocks are left alone",
+			content: "# a\n\n```\n# comment\n## comment\n```",
+			want:    []splitSection{{title: "a", content: "```\n# comment\n## comment\n```"}},
+		},
+		{
+			name:    "empty section",
+			content: "# a\n\n" + emptySectionText + "\n\n# b\n",
+			want:    []splitSection{{title: "a"}, {title: "b"}},
+		},
+		{
+			name:         "preamble",
+			content:      "Generated\n\nby cmbd\n\n# a\n\nText",
+			want:         []splitSection{{title: "a", content: "Text"}},
+			wantPreamble: 2,
+		},
+		{
+			name:         "no sections",
+			content:      "Just text",
+			wantPreamble: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, preamble := splitDocument(tt.content)
+			if !reflect.DeepEqual(got, tt.want) || preamble != tt.wantPreamble {
+				t.Errorf("splitDocument() = %+v, %d, want %+v, %d", got, preamble, tt.want, tt.wantPreamble)
+			}
+		})
+	}
+}
+
+func TestSectionFilename(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "intro", want: "intro.md"},
+		{title: " Q&A: what? ", want: "Q&A- what-.md"},
+		{title: "a/b\\c", want: "a-b-c.md"},
+		{title: "", want: "section.md"},
+		{title: "..", want: "section.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := sectionFilename(tt.title); got != tt.want {
+				t.Errorf("sectionFilename(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombineSplitRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"intro.md": "Welcome.\n\n## Details\n\nMore text.\n\n```sh\n# not a header\n```\n",
+		"usage.md": "# Usage\n\nRun it.\n\n### Options\n\n- `-o`: output\n",
+		"empty.md": "",
+	}
+
+	src := t.TempDir()
+	writeFiles(t, src, files)
+	combined := filepath.Join(t.TempDir(), "combined.md")
+
+	mc := &MarkdownCombiner{
+		inputDir:     src,
+		outputFile:   combined,
+		unlisted:     unlistedAppend,
+		titleSource:  titleFilename,
+		limit:        levelLimit{max: 6, overflow: overflowClamp},
+		headerOffset: 1,
+		sortBy:       sortName,
+		log:          io.Discard,
+	}
+	if err := mc.combineMarkdownFiles(); err != nil {
+		t.Fatalf("combineMarkdownFiles() unexpected error: %v", err)
+	}
+
+	out := t.TempDir()
+	if err := runSplit([]string{combined, "-out-dir", out}); err != nil {
+		t.Fatalf("runSplit() unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(files) {
+		t.Errorf("split wrote %d files, want %d", len(entries), len(files))
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Errorf("split file %s: %v", name, err)
+
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("split file %s = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// wordsPerMinute is the reading speed behind -stats reading times.
+const wordsPerMinute = 200
+
+// textStats sizes up a section or the whole document for -stats.
+type textStats struct {
+	Words          int `json:"words"`
+	Lines          int `json:"lines"`
+	Headings       int `json:"headings"`
+	ReadingMinutes int `json:"reading_minutes"`
+}
+
+// add counts other in with s, working the reading time out again from the
+// combined word count.
+func (s textStats) add(other textStats) textStats {
+	s.Words += other.Words
+	s.Lines += other.Lines
+	s.Headings += other.Headings
+	s.ReadingMinutes = readingMinutes(s.Words)
+
+	return s
+}
+
+// readingMinutes estimates how long words take to read, rounded up to
+// whole minutes.
+func readingMinutes(words int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// sectionStats counts the words, lines, and headings of a file's section.
+// Words in code blocks are left out, as they are skimmed rather than read,
+// but their lines count toward the section's size.
+func sectionStats(s *section) textStats {
+	words, lines := 0, 0
+	if s.content != "" {
+		lines = strings.Count(s.content, "\n") + 1
+	}
+	inFence := false
+	for _, line := range strings.Split(s.content, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		// The hashes of a heading are not words
+		if _, text, ok := parseHeading(line); ok {
+			line = text
+		}
+		words += len(strings.Fields(line))
+	}
+
+	return textStats{
+		Words:          words,
+		Lines:          lines,
+		Headings:       len(s.headings),
+		ReadingMinutes: readingMinutes(words),
+	}
+}
+
+// writeStats prints a table of each file's word, line, and heading counts
+// and reading time, followed by the totals.
+func writeStats(w io.Writer, sections []*section) {
+	var total textStats
+	fmt.Fprintf(w, "\n%8s  %8s  %8s  %8s  %s\n", "Words", "Lines", "Headings", "Reading", "File")
+	for _, s := range sections {
+		stats := sectionStats(s)
+		total = total.add(stats)
+		fmt.Fprintf(w, "%8d  %8d  %8d  %4d min  %s\n", stats.Words, stats.Lines, stats.Headings, stats.ReadingMinutes, s.file.displayPath)
+	}
+	fmt.Fprintf(w, "%8d  %8d  %8d  %4d min  total\n", total.Words, total.Lines, total.Headings, total.ReadingMinutes)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// statsSections returns sections with known counts, with their headings
+// found as assignAnchors finds them.
+func statsSections() []*section {
+	sections := []*section{
+		{
+			file:    &sourceFile{displayPath: "intro.md"},
+			header:  heading{level: 1, text: "Intro"},
+			content: "One two three\n\n## Part one\n\nfour five\n\n```\nskipped code words\n## not a heading\n```",
+		},
+		{
+			file:   &sourceFile{displayPath: "empty.md"},
+			header: heading{level: 1, text: "Empty"},
+		},
+		{
+			file:    &sourceFile{displayPath: "guide/long.md"},
+			header:  heading{level: 2, text: "Long"},
+			content: "## Usage\n\n" + strings.Repeat("word ", 400) + "\n\n### Flags\n\nend",
+		},
+	}
+	assignAnchors(sections)
+
+	return sections
+}
+
+func TestSectionStats(t *testing.T) {
+	sections := statsSections()
+	tests := []struct {
+		name    string
+		section *section
+		want    textStats
+	}{
+		{
+			name:    "code blocks count lines but not words",
+			section: sections[0],
+			want:    textStats{Words: 7, Lines: 10, Headings: 1, ReadingMinutes: 1},
+		},
+		{
+			name:    "empty",
+			section: sections[1],
+			want:    textStats{},
+		},
+		{
+			name:    "reading time rounds up",
+			section: sections[2],
+			want:    textStats{Words: 403, Lines: 7, Headings: 2, ReadingMinutes: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sectionStats(tt.section); got != tt.want {
+				t.Errorf("sectionStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextStatsAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b textStats
+		want textStats
+	}{
+		{
+			name: "reading time from the combined words",
+			a:    textStats{Words: 150, Lines: 10, Headings: 1, ReadingMinutes: 1},
+			b:    textStats{Words: 150, Lines: 5, Headings: 2, ReadingMinutes: 1},
+			want: textStats{Words: 300, Lines: 15, Headings: 3, ReadingMinutes: 2},
+		},
+		{
+			name: "nothing",
+			want: textStats{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.add(tt.b); got != tt.want {
+				t.Errorf("add() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	var out strings.Builder
+	writeStats(&out, statsSections())
+
+	want := "\n" +
+		"   Words     Lines  Headings   Reading  File\n" +
+		"       7        10         1     1 min  intro.md\n" +
+		"       0         0         0     0 min  empty.md\n" +
+		"     403         7         2     3 min  guide/long.md\n" +
+		"     410        17         3     3 min  total\n"
+	if out.String() != want {
+		t.Errorf("writeStats() = %q, want %q", out.String(), want)
+	}
+
+	// One row per section, plus the header and the totals
+	if rows := strings.Count(strings.TrimSpace(out.String()), "\n") + 1; rows != 5 {
+		t.Errorf("writeStats() wrote %d rows, want 5", rows)
+	}
+}
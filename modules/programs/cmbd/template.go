@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is what a -template wrapper is rendered with.
+type templateData struct {
+	Title   string         // Document title, from -title or the input directory name
+	Date    string         // Date combined, as 2006-01-02
+	Time    time.Time      // Time combined
+	Files   []templateFile // Combined files in document order
+	TOC     string         // Markdown list linking to each section
+	Content string         // The combined document
+}
+
+// templateFile describes a combined file to a -template wrapper.
+type templateFile struct {
+	Path        string // Path relative to the input directory
+	Title       string // Section title
+	Anchor      string // Anchor of the section header
+	Frontmatter map[string]any
+}
+
+// applyTemplate renders the combined document into the -template wrapper.
+func (mc *MarkdownCombiner) applyTemplate(sections []*section, combinedContent string) (string, error) {
+	text, err := os.ReadFile(mc.template)
+	if err != nil {
+		return "", fmt.Errorf("error reading template: %v", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(mc.template)).Parse(string(text))
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %v", err)
+	}
+
+	now := time.Now()
+	data := templateData{
+		Title:   mc.documentTitle(),
+		Date:    now.Format(time.DateOnly),
+		Time:    now,
+		TOC:     tableOfContents(sections),
+		Content: combinedContent,
+	}
+	for _, s := range sections {
+		data.Files = append(data.Files, templateFile{
+			Path:        s.file.displayPath,
+			Title:       s.header.text,
+			Anchor:      s.header.anchor,
+			Frontmatter: s.file.frontmatter,
+		})
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("error rendering template: %v", err)
+	}
+
+	return rendered.String(), nil
+}
+
+// documentTitle returns the -title, or else the input directory's name.
+func (mc *MarkdownCombiner) documentTitle() string {
+	if mc.title != "" {
+		return mc.title
+	}
+	if abs, err := filepath.Abs(mc.inputDir); err == nil {
+		return filepath.Base(abs)
+	}
+
+	return filepath.Base(mc.inputDir)
+}
+
+// tableOfContents returns a nested markdown list linking to each folder and
+// file section.
+func tableOfContents(sections []*section) string {
+	var toc strings.Builder
+
+	entry := func(h heading) {
+		if h.bold {
+			return
+		}
+		fmt.Fprintf(&toc, "%s- [%s](#%s)\n", strings.Repeat("  ", h.level-1), h.text, h.anchor)
+	}
+	for _, s := range sections {
+		for _, folder := range s.folders {
+			entry(folder)
+		}
+		if !s.headless {
+			entry(s.header)
+		}
+	}
+
+	return toc.String()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	sections := []*section{
+		{
+			file:   &sourceFile{displayPath: "intro.md", frontmatter: map[string]any{"author": "Ada"}},
+			header: heading{level: 1, text: "Intro", anchor: "intro"},
+		},
+		{
+			file:    &sourceFile{displayPath: "guide/setup.md"},
+			folders: []heading{{level: 1, text: "guide", anchor: "guide"}},
+			header:  heading{level: 2, text: "Setup", anchor: "setup"},
+		},
+	}
+	tmpl := "# {{.Title}}\n\n{{.TOC}}\n{{.Content}}" +
+		"{{range .Files}}{{.Path}} {{.Title}} #{{.Anchor}} {{index .Frontmatter \"author\"}}\n{{end}}" +
+		"{{.Date}}"
+
+	tests := []struct {
+		name     string
+		title    string
+		inputDir string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "title",
+			title:    "Handbook",
+			template: tmpl,
+			want: "# Handbook\n\n" +
+				"- [Intro](#intro)\n- [guide](#guide)\n  - [Setup](#setup)\n\n" +
+				"BODY\n" +
+				"intro.md Intro #intro Ada\nguide/setup.md Setup #setup <no value>\n",
+		},
+		{
+			name:     "title from the input directory",
+			inputDir: filepath.Join("docs", "handbook"),
+			template: "{{.Title}}",
+			want:     "handbook",
+		},
+		{
+			name:     "parse error",
+			template: "{{.Title",
+			wantErr:  true,
+		},
+		{
+			name:     "execution error",
+			template: "{{.Missing}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"wrap.md.tmpl": tt.template})
+			mc := &MarkdownCombiner{
+				inputDir: tt.inputDir,
+				title:    tt.title,
+				template: filepath.Join(dir, "wrap.md.tmpl"),
+			}
+
+			got, err := mc.applyTemplate(sections, "BODY\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("applyTemplate() = %q, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyTemplate() unexpected error: %v", err)
+			}
+
+			// The date changes from run to run
+			date := regexp.MustCompile(`\d{4}-\d{2}-\d{2}$`)
+			if tt.template == tmpl {
+				if !date.MatchString(got) {
+					t.Errorf("applyTemplate() = %q, want it to end in the date", got)
+				}
+				got = date.ReplaceAllString(got, "")
+			}
+			if got != tt.want {
+				t.Errorf("applyTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTemplateMissingFile(t *testing.T) {
+	mc := &MarkdownCombiner{template: filepath.Join(t.TempDir(), "missing.tmpl")}
+	if _, err := mc.applyTemplate(nil, ""); err == nil {
+		t.Error("applyTemplate() expected an error for a missing template")
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []*section
+		want     string
+	}{
+		{
+			name: "nested folders",
+			sections: []*section{
+				{
+					folders: []heading{{level: 1, text: "a", anchor: "a"}, {level: 2, text: "b", anchor: "b"}},
+					header:  heading{level: 3, text: "File", anchor: "file"},
+				},
+				{header: heading{level: 1, text: "Top", anchor: "top"}},
+			},
+			want: "- [a](#a)\n  - [b](#b)\n    - [File](#file)\n- [Top](#top)\n",
+		},
+		{
+			name: "headless and bold headers are left out",
+			sections: []*section{
+				{header: heading{level: 1, text: "Hidden", anchor: "usage"}, headless: true},
+				{
+					folders: []heading{{level: 3, text: "deep", bold: true}},
+					header:  heading{level: 4, text: "Deeper", bold: true},
+				},
+				{header: heading{level: 1, text: "Shown", anchor: "shown"}},
+			},
+			want: "- [Shown](#shown)\n",
+		},
+		{
+			name: "no sections",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tableOfContents(tt.sections); got != tt.want {
+				t.Errorf("tableOfContents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
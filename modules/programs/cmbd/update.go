@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Markers around the part of the output that -update replaces. Anything
+// written before or after them is kept.
+const (
+	updateStartMarker = "<!-- cmbd:start -->"
+	updateEndMarker   = "<!-- cmbd:end -->"
+)
+
+// updateOutput returns the output file with the generated content put
+// between its update markers. An output file that does not exist yet
+// starts out holding only the markers.
+func (mc *MarkdownCombiner) updateOutput(generated string) (string, error) {
+	existing, err := os.ReadFile(mc.outputFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		existing = []byte(updateStartMarker + "\n" + updateEndMarker + "\n")
+	} else if err != nil {
+		return "", fmt.Errorf("error reading output file: %v", err)
+	}
+
+	document, err := replaceBetweenMarkers(string(existing), generated)
+	if err != nil {
+		return "", fmt.Errorf("cannot update %s: %v", mc.outputFile, err)
+	}
+
+	return document, nil
+}
+
+// replaceBetweenMarkers replaces whatever sits between the first start
+// marker in document and the end marker after it.
+func replaceBetweenMarkers(document, generated string) (string, error) {
+	start := strings.Index(document, updateStartMarker)
+	if start < 0 {
+		return "", fmt.Errorf("no %s marker", updateStartMarker)
+	}
+	start += len(updateStartMarker)
+
+	end := strings.Index(document[start:], updateEndMarker)
+	if end < 0 {
+		return "", fmt.Errorf("no %s marker after %s", updateEndMarker, updateStartMarker)
+	}
+	end += start
+
+	return document[:start] + "\n\n" + strings.TrimRight(generated, "\n") + "\n\n" + document[end:], nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestReplaceBetweenMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "keeps the text around the markers",
+			document: "# Readme\n\n<!-- cmbd:start -->\nold\n<!-- cmbd:end -->\n\nFooter\n",
+			want:     "# Readme\n\n<!-- cmbd:start -->\n\nnew\n\n<!-- cmbd:end -->\n\nFooter\n",
+		},
+		{
+			name:     "empty markers",
+			document: "<!-- cmbd:start --><!-- cmbd:end -->",
+			want:     "<!-- cmbd:start -->\n\nnew\n\n<!-- cmbd:end -->",
+		},
+		{
+			name:     "only the first pair is replaced",
+			document: "<!-- cmbd:start -->a<!-- cmbd:end --> <!-- cmbd:start -->b<!-- cmbd:end -->",
+			want:     "<!-- cmbd:start -->\n\nnew\n\n<!-- cmbd:end --> <!-- cmbd:start -->b<!-- cmbd:end -->",
+		},
+		{
+			name:     "no start marker",
+			document: "text\n<!-- cmbd:end -->",
+			wantErr:  true,
+		},
+		{
+			name:     "no end marker",
+			document: "text\n<!-- cmbd:start -->",
+			wantErr:  true,
+		},
+		{
+			name:     "end marker before the start marker",
+			document: "<!-- cmbd:end -->\n<!-- cmbd:start -->",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replaceBetweenMarkers(tt.document, "new\n\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("replaceBetweenMarkers() = %q, want an error", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("replaceBetweenMarkers() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("replaceBetweenMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}